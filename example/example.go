@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/wanglihui/pptx-go/pptx"
 )
@@ -99,7 +100,7 @@ func example2() error {
 
 	// 添加图片
 	if pic, err := slide.GetPlaceholder(pptx.PlaceholderImage); err == nil {
-		pic.SetImage("example.jpg")
+		pic.SetImage("example.jpg", pptx.WithImageSource(pptx.NewImageSource(nil, pptx.WithImageFiles(os.DirFS(".")))))
 	}
 
 	// 添加表格
@@ -138,7 +139,7 @@ func example3() error {
 	if title, err := titleSlide.GetPlaceholder(pptx.PlaceholderTitle); err == nil {
 		title.SetText("Annual Report 2023")
 	}
-	if subtitle, err := titleSlide.GetPlaceholder("Subtitle"); err == nil {
+	if subtitle, err := titleSlide.GetPlaceholder(pptx.PlaceholderSubTitle); err == nil {
 		subtitle.SetText("Company Performance Overview")
 	}
 
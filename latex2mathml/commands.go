@@ -1,5 +1,7 @@
 package latex2mathml
 
+import "sync"
+
 type Style struct {
 	Tag       string
 	Modifiers map[string]string
@@ -125,6 +127,23 @@ const (
 	SPLIT        = `\split`
 	ALIGN        = `\align*`
 
+	ALIGN_NUMBERED = `\align`
+	ALIGNED        = `\aligned`
+	GATHER         = `\gather`
+	GATHER_STAR    = `\gather*`
+	GATHERED       = `\gathered`
+	MULTLINE       = `\multline`
+	MULTLINE_STAR  = `\multline*`
+	EQUATION       = `\equation`
+	EQUATION_STAR  = `\equation*`
+	TAG            = `\tag`
+	TAG_STAR       = `\tag*`
+	LABEL          = `\label`
+	EQREF          = `\eqref`
+	REF            = `\ref`
+	NONUMBER       = `\nonumber`
+	NOTAG          = `\notag`
+
 	BACKSLASH       = `\`
 	CARRIAGE_RETURN = `\cr`
 
@@ -188,13 +207,15 @@ const (
 	LATEX    = `\LaTeX`
 	TEX      = `\TeX`
 
-	SIDESET = `\sideset`
+	SIDESET   = `\sideset`
+	PRESCRIPT = `\prescript`
 
 	SKEW = `\skew`
 	NOT  = `\not`
 )
 
 var (
+	// Deprecated: 命令表由 InitializeCommands 通过 sync.Once 构建，该变量不能用来判断或控制初始化，不应再读取或修改
 	COMMANDS_INITILIAZED = false
 
 	LIMIT = []string{`\lim`, `\sup`, `\inf`, `\max`, `\min`}
@@ -243,8 +264,27 @@ var (
 		SMALLMATRIX,
 		SPLIT,
 		ALIGN,
+		ALIGN_NUMBERED,
+		ALIGNED,
+		GATHER,
+		GATHER_STAR,
+		GATHERED,
+		MULTLINE,
+		MULTLINE_STAR,
 	}
 
+	// ALIGN_LIST 按 rl 交替对齐列的环境
+	ALIGN_LIST = []string{SPLIT, ALIGN, ALIGN_NUMBERED, ALIGNED}
+
+	// NUMBERED_ENVIRONMENTS 自动编号的环境，align 和 gather 每行一个编号，multline 和 equation 整体一个编号
+	NUMBERED_ENVIRONMENTS = []string{ALIGN_NUMBERED, GATHER, MULTLINE, EQUATION}
+
+	// EQUATION_LIST 单个公式的环境
+	EQUATION_LIST = []string{EQUATION, EQUATION_STAR}
+
+	// TAG_LIST 公式编号、标签和引用，参数按原样读取
+	TAG_LIST = []string{TAG, TAG_STAR, LABEL, EQREF, REF}
+
 	LOCAL_FONTS = map[string]map[string]string{
 		BLACKBOARD_BOLD: {"default": "double-struck", "fence": "none"},
 		BOLD_SYMBOL:     {"default": "bold", "mi": "bold-italic", "mtext": "none"},
@@ -405,6 +445,13 @@ var (
 		SMALLMATRIX:         {Tag: "mtable", Modifiers: map[string]string{"rowspacing": "0.1em", "columnspacing": "0.2778em"}},
 		SPLIT:               {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "columnspacing": "0em", "rowspacing": "3pt"}},
 		ALIGN:               {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt"}},
+		ALIGN_NUMBERED:      {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt"}},
+		ALIGNED:             {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt"}},
+		GATHER:              {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt"}},
+		GATHER_STAR:         {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt"}},
+		GATHERED:            {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt"}},
+		MULTLINE:            {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt", "width": "100%"}},
+		MULTLINE_STAR:       {Tag: "mtable", Modifiers: map[string]string{"displaystyle": "true", "rowspacing": "3pt", "width": "100%"}},
 		SUBSCRIPT:           {Tag: "msub", Modifiers: map[string]string{}},
 		SUPERSCRIPT:         {Tag: "msup", Modifiers: map[string]string{}},
 		SUBSUP:              {Tag: "msubsup", Modifiers: map[string]string{}},
@@ -486,6 +533,7 @@ var (
 		PHANTOM:             {Tag: "mphantom", Modifiers: map[string]string{}},
 		VPHANTOM:            {Tag: "mphantom", Modifiers: map[string]string{}},
 		SIDESET:             {Tag: "mrow", Modifiers: map[string]string{}},
+		PRESCRIPT:           {Tag: "mmultiscripts", Modifiers: map[string]string{}},
 		SKEW:                {Tag: "mrow", Modifiers: map[string]string{}},
 		MOD:                 {Tag: "mi", Modifiers: map[string]string{}},
 		PMOD:                {Tag: "mi", Modifiers: map[string]string{}},
@@ -495,37 +543,45 @@ var (
 	}
 )
 
+// InitializeCommands 构建命令表，只在第一次调用时执行，可以并发调用
 func InitializeCommands() {
-	if !COMMANDS_INITILIAZED {
-		for _, postfix := range "lmr" {
-			for command, style := range BIG {
-				BIG_OPEN_CLOSE[command+string(postfix)] = Style{
-					Tag: style.Tag,
-					Modifiers: map[string]string{
-						"stretchy": "true",
-						"fence":    "true",
-						"minsize":  style.Modifiers["minsize"],
-						"maxsize":  style.Modifiers["maxsize"],
-					},
-				}
+	commandsOnce.Do(initializeCommands)
+}
 
+var commandsOnce sync.Once
+
+func initializeCommands() {
+	for _, postfix := range "lmr" {
+		for command, style := range BIG {
+			BIG_OPEN_CLOSE[command+string(postfix)] = Style{
+				Tag: style.Tag,
+				Modifiers: map[string]string{
+					"stretchy": "true",
+					"fence":    "true",
+					"minsize":  style.Modifiers["minsize"],
+					"maxsize":  style.Modifiers["maxsize"],
+				},
 			}
+
 		}
+	}
 
-		for _, matrix := range MATRICES {
+	for _, matrix := range MATRICES {
+		// split、align 等环境已经定义了 mtable 的属性
+		if !containsKey(CONVERSION_MAP, matrix) {
 			CONVERSION_MAP[matrix] = Style{Tag: "mtable", Modifiers: map[string]string{}}
 		}
+	}
 
-		for _, limit := range LIMIT {
-			CONVERSION_MAP[limit] = Style{Tag: "mo", Modifiers: map[string]string{}}
-		}
+	for _, limit := range LIMIT {
+		CONVERSION_MAP[limit] = Style{Tag: "mo", Modifiers: map[string]string{}}
+	}
 
-		for _, overload := range []map[string]Style{BIG, BIG_OPEN_CLOSE, MSTYLE_SIZES, STYLES} {
-			for command, style := range overload {
-				CONVERSION_MAP[command] = style
-			}
+	for _, overload := range []map[string]Style{BIG, BIG_OPEN_CLOSE, MSTYLE_SIZES, STYLES} {
+		for command, style := range overload {
+			CONVERSION_MAP[command] = style
 		}
-
-		COMMANDS_INITILIAZED = true
 	}
+
+	COMMANDS_INITILIAZED = true
 }
@@ -1,7 +1,9 @@
 package latex2mathml
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -72,18 +74,99 @@ type Moded struct {
 var MATH_MODE_PATTERN = regexp.MustCompile(`\\\$|\$|\\?[^\\$]+`)
 var NUMBER_PATTERN = regexp.MustCompile(`\d+(.\d+)?`)
 
+// MathMLNamespace MathML 的命名空间
+const MathMLNamespace = "http://www.w3.org/1998/Math/MathML"
+
+// Converter 将 LaTeX 转换为 MathML，选项只作用于当前实例
+// 零值可以直接使用，同一个 Converter 可以被多个 goroutine 并发使用
+type Converter struct {
+	Xmlns   string // math 元素的命名空间，为空时使用 MathMLNamespace
+	Display string // "inline" 或 "block"，为空时使用 "inline"
+	Indent  int    // 缩进的空格数，为 0 时不缩进
+	Lenient bool   // 为 true 时忽略语法错误，尽可能输出转换结果
+	Macros  Macros // 预先定义的宏，公式中的 \newcommand 等定义只在当前公式内有效
+	// Equations 非空时为 align、gather、multline 和 equation 环境中的公式自动编号，多个公式共用时编号连续
+	Equations *Equations
+
+	verbatim bool // 由包级 Convert 和 ConvertE 使用，Xmlns 和 Display 为空时仍输出空属性，与旧版本的输出一致
+}
+
+// Convert 将 LaTeX 转换为 MathML，遇到语法错误时返回 *SyntaxError，包含出错记号的序号、行列位置和命令
+func (c Converter) Convert(latex string) (string, error) {
+	return c.ConvertCommit(latex, nil)
+}
+
+// ConvertCommit 与 Convert 相同，转换成功后调用 commit，commit 返回错误时撤销本次分配的公式编号和记录的标签并返回该错误
+// 用于公式还需要进一步转换（如转换为 OMML）的情形，进一步转换失败的公式不占用编号
+// commit 调用期间持有 Equations 的锁，不能在其中使用同一个 Equations
+func (c Converter) ConvertCommit(latex string, commit func(mathml string) error) (mathml string, err error) {
+	tokens := newTokenIterator(latex, c.Macros)
+	nodes, err := walk(tokens)
+	if err != nil && !c.Lenient {
+		return "", err
+	}
+
+	equations := c.Equations
+	if equations == nil {
+		equations = &Equations{}
+	}
+	equations.mu.Lock()
+	defer equations.mu.Unlock()
+	state := equations.save()
+
+	defer func() {
+		if r := recover(); r != nil {
+			mathml, err = "", &SyntaxError{Message: fmt.Sprintf("Malformed input: %v", r), Token: len(tokens.tokens), Position: tokens.end}
+		}
+		if err != nil {
+			equations.restore(state)
+		}
+	}()
+	mathml = c.convertNodes(numberEquations(nodes, equations, c.Equations != nil))
+	if commit != nil {
+		if err := commit(mathml); err != nil {
+			return "", err
+		}
+	}
+	return mathml, nil
+}
+
+// Convert 将 LaTeX 转换为 MathML，忽略语法错误
+// xmlns 和 display 按原样输出，为空时输出 xmlns="" 和 display=""，需要默认值时使用 Converter
 func Convert(latex string, xmlns string, display string, indent int) string {
-	InitializeCommands()
-	ParseSymbol()
+	mathml, _ := Converter{Xmlns: xmlns, Display: display, Indent: indent, Lenient: true, verbatim: true}.Convert(latex)
+	return mathml
+}
+
+// ConvertE 与 Convert 相同，但遇到语法错误时返回 *SyntaxError
+func ConvertE(latex string, xmlns string, display string, indent int) (string, error) {
+	return Converter{Xmlns: xmlns, Display: display, Indent: indent, verbatim: true}.Convert(latex)
+}
+
+func (c Converter) convertNodes(nodes []Node) string {
+	xmlns := c.Xmlns
+	if xmlns == "" && !c.verbatim {
+		xmlns = MathMLNamespace
+	}
+	display := c.Display
+	if display == "" && !c.verbatim {
+		display = "inline"
+	}
+
 	doc := etree.NewDocument()
 	math := doc.CreateElement("math")
 	math.CreateAttr("xmlns", xmlns)
 	math.CreateAttr("display", display)
 	row := math.CreateElement("mrow")
-	nodes, _ := Walk(latex)
+	if tag, ok := findTag(nodes); ok {
+		// 带编号的公式放在只有一行的表格中，第一个单元格为编号
+		labeled := row.CreateElement("mtable").CreateElement("mlabeledtr")
+		labeled.CreateElement("mtd").CreateElement("mtext").SetText(tagText(tag))
+		row = labeled.CreateElement("mtd")
+	}
 	convertGroup(nodes, row, map[string]string{})
-	if indent != 0 {
-		doc.Indent(indent)
+	if c.Indent != 0 {
+		doc.Indent(c.Indent)
 	}
 	doc.WriteSettings.NoEscape = true
 	mathml, _ := doc.WriteToString()
@@ -102,6 +185,7 @@ func convertMatrix(nodes []Node, parent *etree.Element, command string, alignmen
 	var rowLines = []string{}
 
 	var indexes = []bool{}
+	var tag Node
 
 	for _, node := range nodes {
 
@@ -121,12 +205,18 @@ func convertMatrix(nodes []Node, parent *etree.Element, command string, alignmen
 			indexes = []bool{}
 			colAlignment, colIndex = getColumnAlignment(&alignment, colAlignment, colIndex)
 			cell = makeMatrixCell(row, colAlignment)
-			if (command == SPLIT || command == ALIGN) && colIndex%2 == 0 {
+			if slices.Contains(ALIGN_LIST, command) && colIndex%2 == 0 {
 				cell.CreateElement("mi")
 			}
+		} else if node.Token == TAG || node.Token == TAG_STAR {
+			tag = node
 		} else if node.Token == DOUBLEBACKSLASH || node.Token == CARRIAGE_RETURN {
 			setCellAlignment(cell, indexes)
 			indexes = []bool{}
+			if tag.Token != "" {
+				labelRow(row, tag)
+				tag = Node{}
+			}
 			rowIndex = rowIndex + 1
 			if colIndex > maxColSize {
 				maxColSize = colIndex
@@ -154,16 +244,29 @@ func convertMatrix(nodes []Node, parent *etree.Element, command string, alignmen
 		maxColSize = colIndex
 	}
 
+	if tag.Token != "" {
+		labelRow(row, tag)
+	}
+
 	if slices.Contains(rowLines, "solid") {
 		parent.CreateAttr("rowlines", strings.Join(rowLines, " "))
 	}
 
-	if row != nil && cell != nil && len(cell.ChildElements()) == 0 && cell.Text() == "" {
+	if row != nil && cell != nil && len(cell.ChildElements()) == 0 && cell.Text() == "" && row.Tag == "mtr" {
 		children := parent.ChildElements()
 		parent.RemoveChildAt(len(children) - 1)
 	}
 
-	if maxColSize > 0 && command == ALIGN {
+	if command == MULTLINE || command == MULTLINE_STAR {
+		// multline 的第一行左对齐，最后一行右对齐，其余各行居中
+		rows := parent.ChildElements()
+		if len(rows) > 1 {
+			alignCells(rows[0], "left")
+			alignCells(rows[len(rows)-1], "right")
+		}
+	}
+
+	if maxColSize > 0 && slices.Contains(ALIGN_LIST, command) && command != SPLIT {
 		multiplier := maxColSize / 2
 		spacing := "0em 2em"
 		for i := 0; i < multiplier-1; i++ {
@@ -173,6 +276,25 @@ func convertMatrix(nodes []Node, parent *etree.Element, command string, alignmen
 	}
 }
 
+// alignCells 设置一行中各单元格的对齐方式，mlabeledtr 的第一个单元格是编号，不做处理
+func alignCells(row *etree.Element, align string) {
+	cells := row.SelectElements("mtd")
+	if row.Tag == "mlabeledtr" && len(cells) > 0 {
+		cells = cells[1:]
+	}
+	for _, cell := range cells {
+		cell.CreateAttr("columnalign", align)
+	}
+}
+
+// labelRow 将带有 \tag 的行转换为 mlabeledtr，第一个单元格为编号
+func labelRow(row *etree.Element, tag Node) {
+	row.Tag = "mlabeledtr"
+	label := etree.NewElement("mtd")
+	label.CreateElement("mtext").SetText(tagText(tag))
+	row.InsertChildAt(0, label)
+}
+
 func setCellAlignment(cell *etree.Element, indexes []bool) {
 	if slices.Contains(indexes, true) {
 		if indexes[0] && !indexes[len(indexes)-1] {
@@ -211,15 +333,20 @@ func makeMatrixCell(row *etree.Element, columnAlignment *string) *etree.Element
 }
 
 func convertGroup(nodes []Node, parent *etree.Element, font map[string]string) {
-	for _, node := range nodes {
+	for index, node := range nodes {
 		token := node.Token
 
-		if _, exist := MSTYLE_SIZES[token]; exist {
-			node := Node{Token: token, Children: nodes}
+		if token == TAG || token == TAG_STAR {
+			// 编号由 convertMatrix 和 convertNodes 输出
+			continue
+		} else if _, exist := MSTYLE_SIZES[token]; exist {
+			node := Node{Token: token, Children: nodes[index+1:]}
 			convertCommand(node, parent, font)
+			break
 		} else if _, exist := STYLES[token]; exist {
-			node := Node{Token: token, Children: nodes}
+			node := Node{Token: token, Children: nodes[index+1:]}
 			convertCommand(node, parent, font)
+			break
 		} else if _, exist := CONVERSION_MAP[token]; exist || token == MOD || token == PMOD {
 			convertCommand(node, parent, font)
 		} else if _, exist := LOCAL_FONTS[token]; exist && node.Children != nil {
@@ -335,6 +462,12 @@ func convertCommand(node Node, parent *etree.Element, font map[string]string) {
 	}
 
 	style, _ := CONVERSION_MAP[command]
+	// 复制修饰属性，避免修改全局命令表
+	modifiers := make(map[string]string, len(style.Modifiers))
+	for key, value := range style.Modifiers {
+		modifiers[key] = value
+	}
+	style.Modifiers = modifiers
 
 	if len(node.Attributes) > 0 && node.Token != SKEW {
 		for key, value := range node.Attributes {
@@ -439,8 +572,10 @@ func convertCommand(node Node, parent *etree.Element, font map[string]string) {
 
 			if command == CASES {
 				align = "l"
-			} else if command == SPLIT || command == ALIGN {
+			} else if slices.Contains(ALIGN_LIST, command) {
 				align = "rl"
+			} else if command == GATHER || command == GATHER_STAR || command == GATHERED || command == MULTLINE || command == MULTLINE_STAR {
+				align = "c"
 			}
 			convertMatrix(node.Children, localParent, command, align)
 
@@ -462,6 +597,19 @@ func convertCommand(node Node, parent *etree.Element, font map[string]string) {
 			space.CreateAttr("width", "-0.167em")
 			convertGroup(node.Children[1:2], localParent, font)
 
+		} else if command == PRESCRIPT {
+
+			// mmultiscripts 中依次为底数、mprescripts 和前置的下标、上标，空的上下标为 none
+			convertGroup(node.Children[2:3], localParent, font)
+			localParent.CreateElement("mprescripts")
+			for _, script := range []Node{node.Children[1], node.Children[0]} {
+				if script.Token == BRACES && len(script.Children) == 0 {
+					localParent.CreateElement("none")
+					continue
+				}
+				convertGroup([]Node{script}, localParent, font)
+			}
+
 		} else if command == SKEW {
 
 			child := node.Children[0]
@@ -506,16 +654,20 @@ func addDiacritic(command string, parent *etree.Element) {
 	if exist {
 		element := etree.NewElement("mo")
 		element.SetText(style.Tag)
-		for key, value := range style.Modifiers {
-			element.CreateAttr(key, value)
-		}
+		addAttributes(element, style.Modifiers)
 		parent.AddChild(element)
 	}
 }
 
+// addAttributes 按属性名排序添加属性，使输出稳定
 func addAttributes(element *etree.Element, attributes map[string]string) {
-	for key, value := range attributes {
-		element.CreateAttr(key, value)
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		element.CreateAttr(key, attributes[key])
 	}
 }
 
@@ -562,9 +714,9 @@ func appendPostfixElement(node Node, parent *etree.Element) {
 	}
 
 	if node.Token == `\pmatrix` || node.Token == PMOD {
-		convertAndAppendCommand(`\lparen`, parent, map[string]string{})
+		convertAndAppendCommand(`\rparen`, parent, map[string]string{})
 	} else if node.Token == BINOM || node.Token == DBINOM || node.Token == TBINOM {
-		convertAndAppendCommand(`\lparen`, parent, map[string]string{"minsize": size, "maxsize": size})
+		convertAndAppendCommand(`\rparen`, parent, map[string]string{"minsize": size, "maxsize": size})
 	} else if node.Token == `\bmatrix` {
 		convertAndAppendCommand(`\rbrack`, parent, map[string]string{})
 	} else if node.Token == `\Bmatrix` {
@@ -0,0 +1,104 @@
+package latex2mathml
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConvertStyleCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		latex string
+		want  []string
+	}{
+		{"displaystyle", `\displaystyle x`, []string{`displaystyle="true"`, "<mi>x</mi></mstyle>"}},
+		{"textstyle after content", `a \textstyle b`, []string{"<mi>a</mi><mstyle", "<mi>b</mi></mstyle>"}},
+		{"size command", `\scriptsize y`, []string{"<mstyle", "<mi>y</mi></mstyle>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Convert(tt.latex, "", "inline", 0)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Convert(%q) = %s, want it to contain %s", tt.latex, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertLimits(t *testing.T) {
+	got := Convert(`\sum\limits_{i=1}^{n} i`, "", "inline", 0)
+	for _, want := range []string{"<mi>&#x02211;</mi>", "<mn>1</mn>", "<mi>n</mi>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Convert = %s, want it to contain %s", got, want)
+		}
+	}
+}
+
+func TestConvertNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		got  func() string
+		want string
+	}{
+		{"legacy empty attributes", func() string { return Convert("x", "", "", 0) }, `<math xmlns="" display="">`},
+		{"legacy namespace", func() string { return Convert("x", MathMLNamespace, "block", 0) }, `<math xmlns="` + MathMLNamespace + `" display="block">`},
+		{"converter defaults", func() string {
+			mathml, _ := Converter{}.Convert("x")
+			return mathml
+		}, `<math xmlns="` + MathMLNamespace + `" display="inline">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("got %s, want prefix %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverterConcurrent(t *testing.T) {
+	formulas := []struct{ latex, want string }{
+		{`\frac{a}{b}`, "<mfrac><mrow><mi>a</mi></mrow><mrow><mi>b</mi></mrow></mfrac>"},
+		{`\sqrt{x}`, "<msqrt><mrow><mi>x</mi></mrow></msqrt>"},
+		{`\alpha + \beta`, "<mi>&#x003B1;</mi><mo>&#x0002B;</mo><mi>&#x003B2;</mi>"},
+		{`\sum_{i=1}^{n} i`, "<mi>&#x02211;</mi>"},
+		{`\begin{matrix}1&2\end{matrix}`, "<mtr><mtd><mn>1</mn></mtd><mtd><mn>2</mn></mtd></mtr>"},
+	}
+	const goroutines = 64
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			formula := formulas[i%len(formulas)]
+			converter := Converter{Display: "inline"}
+			if i%2 == 1 {
+				converter.Display = "block"
+			}
+			var got string
+			var err error
+			if i%3 == 0 {
+				// 包级函数与 Converter 共用同一组表
+				got, err = ConvertE(formula.latex, "", converter.Display, 0)
+			} else {
+				got, err = converter.Convert(formula.latex)
+			}
+			switch {
+			case err != nil:
+				errs <- fmt.Errorf("Convert(%q): %w", formula.latex, err)
+			case !strings.Contains(got, formula.want) || !strings.Contains(got, `display="`+converter.Display+`"`):
+				errs <- fmt.Errorf("Convert(%q) with display %s = %s, want it to contain %s", formula.latex, converter.Display, got, formula.want)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,195 @@
+package latex2mathml
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/wanglihui/pptx-go/latex2mathml/slices"
+)
+
+// Equations 记录公式自动编号的状态
+// align、gather、multline 和 equation 环境（不带 * 的形式）中的公式按顺序编号，\label 记录编号，\eqref 和 \ref 引用编号
+// 多个公式（如同一张幻灯片或整个演示文稿中的公式）使用同一个 Equations 时编号连续，只能引用之前已经出现的标签
+// 零值可以直接使用，同一个 Equations 可以被多个 goroutine 并发使用
+type Equations struct {
+	mu     sync.Mutex
+	count  int
+	labels map[string]string
+}
+
+// Count 返回已经分配的编号数量
+func (e *Equations) Count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.count
+}
+
+// Label 返回 \label 对应的编号
+func (e *Equations) Label(name string) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	tag, ok := e.labels[name]
+	return tag, ok
+}
+
+// next 分配下一个编号，调用方需要持有锁
+func (e *Equations) next() string {
+	e.count++
+	return strconv.Itoa(e.count)
+}
+
+// setLabel 记录标签对应的编号，调用方需要持有锁
+func (e *Equations) setLabel(name, tag string) {
+	if e.labels == nil {
+		e.labels = map[string]string{}
+	}
+	e.labels[name] = tag
+}
+
+// equationsState 编号的状态，用于撤销一次转换分配的编号和记录的标签
+type equationsState struct {
+	count  int
+	labels map[string]string
+}
+
+// save 保存当前的编号状态，调用方需要持有锁
+func (e *Equations) save() equationsState {
+	labels := make(map[string]string, len(e.labels))
+	for name, tag := range e.labels {
+		labels[name] = tag
+	}
+	return equationsState{count: e.count, labels: labels}
+}
+
+// restore 恢复到 save 保存的状态，调用方需要持有锁
+func (e *Equations) restore(state equationsState) {
+	e.count = state.count
+	e.labels = state.labels
+}
+
+// numberEquations 为公式编号并解析引用，auto 为 false 时不自动编号，调用方需要持有 equations 的锁
+// equation 环境展开到所在的节点序列中，其编号由 convertNodes 输出
+func numberEquations(nodes []Node, equations *Equations, auto bool) []Node {
+	nodes = numberRows(nodes, equations, auto)
+	// 不在环境中的 \tag 和 \label
+	nodes = numberRow(nodes, equations, false)
+	return resolveReferences(nodes, equations)
+}
+
+// numberRows 为环境中的每一行分配编号，并记录各行的标签
+func numberRows(nodes []Node, equations *Equations, auto bool) []Node {
+	result := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Children != nil {
+			node.Children = numberRows(node.Children, equations, auto)
+		}
+
+		numbered := auto && slices.Contains(NUMBERED_ENVIRONMENTS, node.Token)
+		switch {
+		case node.Token == MULTLINE || node.Token == MULTLINE_STAR || slices.Contains(EQUATION_LIST, node.Token):
+			node.Children = numberRow(node.Children, equations, numbered)
+		case slices.Contains(MATRICES, node.Token):
+			children := make([]Node, 0, len(node.Children))
+			start := 0
+			for i := 0; i <= len(node.Children); i++ {
+				if i < len(node.Children) && node.Children[i].Token != DOUBLEBACKSLASH && node.Children[i].Token != CARRIAGE_RETURN {
+					continue
+				}
+				row := numberRow(node.Children[start:i], equations, numbered && i > start)
+				children = append(children, row...)
+				if i < len(node.Children) {
+					children = append(children, node.Children[i])
+				}
+				start = i + 1
+			}
+			node.Children = children
+		}
+
+		if slices.Contains(EQUATION_LIST, node.Token) {
+			result = append(result, node.Children...)
+			continue
+		}
+		result = append(result, node)
+	}
+	return result
+}
+
+// numberRow 为一行公式分配编号，已经有 \tag 或者使用了 \nonumber、\notag 的行不分配编号
+func numberRow(row []Node, equations *Equations, numbered bool) []Node {
+	tag, ok := findTag(row)
+	if !ok && numbered && !hasToken(row, NONUMBER) && !hasToken(row, NOTAG) {
+		tag = Node{Token: TAG, Text: equations.next()}
+		row = append(row[:len(row):len(row)], tag)
+		ok = true
+	}
+	if ok {
+		for _, label := range findLabels(row) {
+			equations.setLabel(label, tag.Text)
+		}
+	}
+	return row
+}
+
+// findTag 查找节点序列中的 \tag
+func findTag(nodes []Node) (Node, bool) {
+	for _, node := range nodes {
+		if node.Token == TAG || node.Token == TAG_STAR {
+			return node, true
+		}
+	}
+	return Node{}, false
+}
+
+// hasToken 判断节点序列中是否有指定的记号
+func hasToken(nodes []Node, token string) bool {
+	for _, node := range nodes {
+		if node.Token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// findLabels 查找节点序列中的 \label
+func findLabels(nodes []Node) []string {
+	var labels []string
+	for _, node := range nodes {
+		if node.Token == LABEL {
+			labels = append(labels, node.Text)
+		}
+	}
+	return labels
+}
+
+// resolveReferences 将 \eqref 和 \ref 替换为编号，删除 \label、\nonumber 和 \notag，未定义的标签显示为 ??
+func resolveReferences(nodes []Node, equations *Equations) []Node {
+	result := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch node.Token {
+		case LABEL, NONUMBER, NOTAG:
+			continue
+		case EQREF, REF:
+			tag, ok := equations.labels[node.Text]
+			if !ok {
+				tag = "??"
+			}
+			if node.Token == EQREF {
+				tag = "(" + tag + ")"
+			}
+			node = Node{Token: TEXT, Text: tag}
+		}
+		if node.Children != nil {
+			node.Children = resolveReferences(node.Children, equations)
+		}
+		result = append(result, node)
+	}
+	return result
+}
+
+// tagText 返回编号显示的文本，\tag*{…} 不加括号
+func tagText(tag Node) string {
+	if tag.Token == TAG_STAR {
+		return tag.Text
+	}
+	return "(" + tag.Text + ")"
+}
@@ -0,0 +1,75 @@
+package latex2mathml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEquationNumbering(t *testing.T) {
+	tests := []struct {
+		name  string
+		latex []string
+		want  []string
+		count int
+	}{
+		{"sequential", []string{`\begin{equation} a \end{equation}`, `\begin{equation} b \end{equation}`}, []string{"(1)", "(2)"}, 2},
+		{"starred not numbered", []string{`\begin{equation*} a \end{equation*}`, `\begin{equation} b \end{equation}`}, []string{"<mi>a</mi>", "(1)"}, 1},
+		{"align rows", []string{`\begin{align} a \\ b \nonumber \\ c \end{align}`}, []string{"(1)", "(2)"}, 2},
+		{"explicit tag", []string{`\begin{equation} a \tag{A} \end{equation}`}, []string{"(A)"}, 0},
+		{"reference earlier label", []string{`\begin{equation} a \label{first} \end{equation}`, `\eqref{first}`}, []string{"(1)", "(1)"}, 1},
+		{"undefined label", []string{`\eqref{missing}`}, []string{"??"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := Converter{Equations: &Equations{}}
+			for i, latex := range tt.latex {
+				mathml, err := converter.Convert(latex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(mathml, tt.want[i]) {
+					t.Errorf("Convert(%q) = %s, want it to contain %s", latex, mathml, tt.want[i])
+				}
+			}
+			if got := converter.Equations.Count(); got != tt.count {
+				t.Errorf("Count() = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
+
+func TestMultlineLabelAlignment(t *testing.T) {
+	converter := Converter{Equations: &Equations{}}
+	mathml, err := converter.Convert(`\begin{multline} a \\ b \end{multline}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<mtd columnalign="left"><mi>a</mi>`, `<mlabeledtr><mtd><mtext>(1)</mtext></mtd><mtd columnalign="right"><mi>b</mi>`} {
+		if !strings.Contains(mathml, want) {
+			t.Errorf("Convert() = %s, want it to contain %s", mathml, want)
+		}
+	}
+}
+
+func TestConvertCommitRollback(t *testing.T) {
+	equations := &Equations{}
+	converter := Converter{Equations: equations}
+	failed := errors.New("conversion failed")
+
+	_, err := converter.ConvertCommit(`\begin{equation} a \label{a} \end{equation}`, func(string) error { return failed })
+	if !errors.Is(err, failed) {
+		t.Fatalf("ConvertCommit() error = %v, want %v", err, failed)
+	}
+	if _, ok := equations.Label("a"); ok || equations.Count() != 0 {
+		t.Fatalf("failed commit kept its number: count %d", equations.Count())
+	}
+
+	mathml, err := converter.ConvertCommit(`\begin{equation} b \label{b} \end{equation}`, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag, _ := equations.Label("b"); tag != "1" || !strings.Contains(mathml, "(1)") {
+		t.Errorf("label b = %q in %s, want 1", tag, mathml)
+	}
+}
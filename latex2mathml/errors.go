@@ -0,0 +1,89 @@
+package latex2mathml
+
+import "fmt"
+
+// Position 表示记号在 LaTeX 输入中的位置
+type Position struct {
+	Offset int // 字节偏移，从 0 开始
+	Line   int // 行号，从 1 开始
+	Column int // 列号（按字符计），从 1 开始
+}
+
+// SyntaxError 表示 LaTeX 语法错误
+type SyntaxError struct {
+	Message string // 错误描述
+	Command string // 出错的命令或记号，在输入末尾出错时为空
+	Token   int    // 出错记号的序号，从 0 开始，在输入末尾出错时等于记号数量
+	Position
+}
+
+// Error 实现 error 接口
+func (e *SyntaxError) Error() string {
+	if e.Command == "" {
+		return fmt.Sprintf("%s at end of input (line %d, column %d)", e.Message, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s at line %d, column %d (token %d %q)", e.Message, e.Line, e.Column, e.Token, e.Command)
+}
+
+// tokenIterator 按顺序读取记号，并记录遇到的第一个语法错误
+type tokenIterator struct {
+	tokens    []string
+	positions []Position
+	end       Position // 输入末尾的位置
+	index     int
+	macros    Macros // 展开后的宏表，包括公式中定义的宏
+	err       *SyntaxError
+}
+
+// newTokenIterator 将 LaTeX 拆分为记号并展开宏，宏定义出错时记录在 err 中
+func newTokenIterator(latex string, macros Macros) *tokenIterator {
+	InitializeCommands()
+	ParseSymbol()
+	tokens, positions := tokenize(latex)
+	tokens, positions, macros, err := expandMacros(latex, tokens, positions, macros)
+	return &tokenIterator{tokens: tokens, positions: positions, end: endPosition(latex), macros: macros, err: err}
+}
+
+// endPosition 返回输入末尾的位置
+func endPosition(latex string) Position {
+	end := Position{Offset: len(latex), Line: 1, Column: 1}
+	for _, r := range latex {
+		if r == '\n' {
+			end.Line++
+			end.Column = 1
+		} else {
+			end.Column++
+		}
+	}
+	return end
+}
+
+// next 返回下一个记号，没有更多记号时返回空字符串
+func (it *tokenIterator) next() string {
+	var token string
+	if it.index < len(it.tokens) {
+		token = it.tokens[it.index]
+	}
+	it.index = it.index + 1
+	return token
+}
+
+// current 返回最近读取的记号的序号
+func (it *tokenIterator) current() int {
+	return it.index - 1
+}
+
+// fail 记录第 index 个记号处的语法错误并返回该错误，只保留第一个错误
+func (it *tokenIterator) fail(index int, message string) error {
+	err := &SyntaxError{Message: message, Token: index, Position: it.end}
+	if index >= 0 && index < len(it.tokens) {
+		err.Command = it.tokens[index]
+		err.Position = it.positions[index]
+	} else {
+		err.Token = len(it.tokens)
+	}
+	if it.err == nil {
+		it.err = err
+	}
+	return err
+}
@@ -0,0 +1,393 @@
+package latex2mathml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wanglihui/pptx-go/latex2mathml/slices"
+)
+
+const (
+	NEWCOMMAND          = `\newcommand`
+	RENEWCOMMAND        = `\renewcommand`
+	PROVIDECOMMAND      = `\providecommand`
+	DEF                 = `\def`
+	DECLAREMATHOPERATOR = `\DeclareMathOperator`
+)
+
+// maxMacroExpansions 单个公式中宏展开的最大次数，用于发现递归定义
+const maxMacroExpansions = 10000
+
+// Macro 表示一个用户定义的宏
+type Macro struct {
+	Params   int    // 参数个数，最多 9 个
+	Optional bool   // 第一个参数是否为可选参数，即 \newcommand{\name}[n][默认值]{...} 的形式
+	Default  string // 可选参数的默认值
+	Body     string // 替换文本，#1 到 #9 表示参数；运算符宏为运算符名称
+	Operator bool   // 是否为 \DeclareMathOperator 定义的运算符
+	Limits   bool   // 是否为 \DeclareMathOperator* 定义的运算符，上下标放在运算符正下方和正上方
+}
+
+// Macros 宏名（包括反斜杠，如 \R）到宏定义的映射
+type Macros map[string]Macro
+
+// ParseMacros 解析导言区中的 \newcommand、\renewcommand、\providecommand、\def 和 \DeclareMathOperator 定义
+// 导言区中除宏定义以外的内容会被忽略
+func ParseMacros(preamble string) (Macros, error) {
+	return Macros(nil).Parse(preamble)
+}
+
+// Parse 在 m 的基础上解析导言区中的宏定义，返回合并后的宏，m 本身不变
+// 导言区中的宏可以使用和重新定义 m 中的宏
+func (m Macros) Parse(preamble string) (Macros, error) {
+	tokens := newTokenIterator(preamble, m)
+	if tokens.err != nil {
+		return nil, tokens.err
+	}
+	return tokens.macros, nil
+}
+
+// clone 复制宏定义，公式中的定义不影响传入的映射
+func (m Macros) clone() Macros {
+	macros := make(Macros, len(m))
+	for name, macro := range m {
+		macros[name] = macro
+	}
+	return macros
+}
+
+// macroExpander 在记号序列上定义和展开宏
+type macroExpander struct {
+	source    string
+	tokens    []string
+	positions []Position
+	index     int
+	macros    Macros
+
+	out          []string
+	outPositions []Position
+	expansions   int
+	err          *SyntaxError
+}
+
+// expandMacros 处理记号序列中的宏定义并展开宏，返回展开后的记号、位置和宏表
+func expandMacros(source string, tokens []string, positions []Position, macros Macros) ([]string, []Position, Macros, *SyntaxError) {
+	e := &macroExpander{source: source, tokens: tokens, positions: positions, macros: macros.clone()}
+	e.run()
+	return e.out, e.outPositions, e.macros, e.err
+}
+
+func (e *macroExpander) run() {
+	for e.index < len(e.tokens) && e.err == nil {
+		start := e.index
+		token := e.next()
+
+		switch token {
+		case NEWCOMMAND, RENEWCOMMAND, PROVIDECOMMAND:
+			e.defineCommand(start, token)
+		case DEF:
+			e.defineDef(start)
+		case DECLAREMATHOPERATOR:
+			e.defineOperator(start)
+		default:
+			macro, ok := e.macros[token]
+			if !ok {
+				e.emit(token, e.positions[start])
+				continue
+			}
+			e.expansions++
+			if e.expansions > maxMacroExpansions {
+				e.fail(start, "Macro expansion limit exceeded")
+				return
+			}
+			e.expand(start, macro)
+		}
+	}
+}
+
+// next 读取下一个记号
+func (e *macroExpander) next() string {
+	if e.index >= len(e.tokens) {
+		e.index++
+		return ""
+	}
+	token := e.tokens[e.index]
+	e.index++
+	return token
+}
+
+// peek 返回下一个记号但不读取
+func (e *macroExpander) peek() string {
+	if e.index >= len(e.tokens) {
+		return ""
+	}
+	return e.tokens[e.index]
+}
+
+func (e *macroExpander) emit(token string, position Position) {
+	e.out = append(e.out, token)
+	e.outPositions = append(e.outPositions, position)
+}
+
+func (e *macroExpander) fail(index int, message string) {
+	if e.err != nil {
+		return
+	}
+	e.err = &SyntaxError{Message: message, Token: index}
+	if index < len(e.tokens) {
+		e.err.Command = e.tokens[index]
+		e.err.Position = e.positions[index]
+	} else {
+		e.err.Token = len(e.tokens)
+		e.err.Position = endPosition(e.source)
+	}
+}
+
+// defineCommand 处理 \newcommand{\name}[n][default]{body}
+func (e *macroExpander) defineCommand(start int, command string) {
+	name, ok := e.readMacroName(start)
+	if !ok {
+		return
+	}
+
+	macro := Macro{}
+	if e.peek() == OPENING_BRACKET {
+		e.next()
+		count := e.next()
+		if len(count) != 1 || count[0] < '0' || count[0] > '9' || e.next() != CLOSING_BRACKET {
+			e.fail(start, "Invalid number of macro parameters")
+			return
+		}
+		macro.Params = int(count[0] - '0')
+		if e.peek() == OPENING_BRACKET {
+			if macro.Params == 0 {
+				e.fail(start, "Optional argument for macro without parameters")
+				return
+			}
+			open := e.index
+			e.next()
+			defaults, ok := e.readUntil(CLOSING_BRACKET)
+			if !ok {
+				e.fail(open, "Missing ] in macro definition")
+				return
+			}
+			macro.Optional = true
+			macro.Default = e.rawText(open, e.index-1, defaults)
+		}
+	}
+
+	body, ok := e.readBody(start)
+	if !ok {
+		return
+	}
+	macro.Body = body
+
+	_, exists := e.macros[name]
+	switch {
+	case command == NEWCOMMAND && exists:
+		e.fail(start, "Command "+name+" already defined")
+	case command == RENEWCOMMAND && !exists && !isKnownCommand(name):
+		e.fail(start, "Command "+name+" undefined")
+	case command == PROVIDECOMMAND && (exists || isKnownCommand(name)):
+		// 已定义时不覆盖
+	default:
+		e.macros[name] = macro
+	}
+}
+
+// defineDef 处理 \def\name#1#2{body}
+func (e *macroExpander) defineDef(start int) {
+	name := e.next()
+	if !strings.HasPrefix(name, BACKSLASH) {
+		e.fail(start, "Missing control sequence in \\def")
+		return
+	}
+
+	macro := Macro{}
+	for e.peek() == "#" {
+		e.next()
+		param := e.next()
+		if param != fmt.Sprint(macro.Params+1) {
+			e.fail(start, "Parameters must be numbered consecutively")
+			return
+		}
+		macro.Params++
+	}
+
+	body, ok := e.readBody(start)
+	if !ok {
+		return
+	}
+	macro.Body = body
+	e.macros[name] = macro
+}
+
+// defineOperator 处理 \DeclareMathOperator{\name}{text} 和 \DeclareMathOperator*{\name}{text}
+func (e *macroExpander) defineOperator(start int) {
+	macro := Macro{Operator: true}
+	if e.peek() == "*" {
+		e.next()
+		macro.Limits = true
+	}
+	name, ok := e.readMacroName(start)
+	if !ok {
+		return
+	}
+	body, ok := e.readBody(start)
+	if !ok {
+		return
+	}
+	macro.Body = operatorName(body)
+	e.macros[name] = macro
+}
+
+// readMacroName 读取 {\name} 或 \name 形式的宏名
+func (e *macroExpander) readMacroName(start int) (string, bool) {
+	token := e.next()
+	if token == OPENING_BRACE {
+		name := e.next()
+		if !strings.HasPrefix(name, BACKSLASH) || e.next() != CLOSING_BRACE {
+			e.fail(start, "Invalid macro name")
+			return "", false
+		}
+		return name, true
+	}
+	if !strings.HasPrefix(token, BACKSLASH) {
+		e.fail(start, "Invalid macro name")
+		return "", false
+	}
+	return token, true
+}
+
+// readBody 读取用大括号包围的宏定义体，返回原始文本
+func (e *macroExpander) readBody(start int) (string, bool) {
+	open := e.index
+	if e.next() != OPENING_BRACE {
+		e.fail(start, "Missing macro body")
+		return "", false
+	}
+	body, ok := e.readUntil(CLOSING_BRACE)
+	if !ok {
+		e.fail(open, "Missing closing brace")
+		return "", false
+	}
+	return e.rawText(open, e.index-1, body), true
+}
+
+// readUntil 读取到与当前层级匹配的结束记号为止（不包括结束记号），大括号内的结束记号不计
+func (e *macroExpander) readUntil(terminator string) ([]string, bool) {
+	var tokens []string
+	depth := 0
+	for e.index < len(e.tokens) {
+		token := e.next()
+		switch {
+		case token == terminator && depth == 0:
+			return tokens, true
+		case token == OPENING_BRACE:
+			depth++
+		case token == CLOSING_BRACE:
+			depth--
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, false
+}
+
+// rawText 获取 open 和 close 两个记号之间的原始文本，记号来自宏展开而没有对应的原文时用空格连接记号
+func (e *macroExpander) rawText(open, close int, tokens []string) string {
+	from, to := e.positions[open].Offset, e.positions[close].Offset
+	if from < to && to <= len(e.source) && e.source[from:from+1] == e.tokens[open][:1] {
+		return e.source[from+len(e.tokens[open]) : to]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// expand 读取宏的参数并展开，展开结果放回输入中以便继续展开嵌套的宏
+func (e *macroExpander) expand(start int, macro Macro) {
+	position := e.positions[start]
+
+	if macro.Operator {
+		e.emit(OPERATORNAME+"{"+macro.Body+"}", position)
+		if macro.Limits && (e.peek() == SUBSCRIPT || e.peek() == SUPERSCRIPT) {
+			e.emit(LIMITS, position)
+		}
+		return
+	}
+
+	args := make([][]string, 0, macro.Params)
+	for i := 0; i < macro.Params; i++ {
+		if i == 0 && macro.Optional {
+			if e.peek() == OPENING_BRACKET {
+				e.next()
+				arg, ok := e.readUntil(CLOSING_BRACKET)
+				if !ok {
+					e.fail(start, "Missing ] in macro argument")
+					return
+				}
+				args = append(args, arg)
+			} else {
+				args = append(args, Tokenize(macro.Default))
+			}
+			continue
+		}
+
+		argStart := e.index
+		switch token := e.next(); token {
+		case "":
+			e.fail(start, "Missing macro argument")
+			return
+		case OPENING_BRACE:
+			arg, ok := e.readUntil(CLOSING_BRACE)
+			if !ok {
+				e.fail(argStart, "Missing closing brace")
+				return
+			}
+			args = append(args, arg)
+		default:
+			args = append(args, []string{token})
+		}
+	}
+
+	var expanded []string
+	body := Tokenize(macro.Body)
+	for i := 0; i < len(body); i++ {
+		token := body[i]
+		if token == "#" && i+1 < len(body) && body[i+1] != "" && body[i+1][0] >= '1' && body[i+1][0] <= '9' {
+			n := int(body[i+1][0] - '1')
+			if n >= len(args) {
+				e.fail(start, "Illegal parameter number in definition of macro")
+				return
+			}
+			// 参数用大括号包围，保持其作为一个整体，如 #1^2
+			expanded = append(expanded, OPENING_BRACE)
+			expanded = append(expanded, args[n]...)
+			expanded = append(expanded, CLOSING_BRACE)
+			if rest := body[i+1][1:]; rest != "" {
+				expanded = append(expanded, rest)
+			}
+			i++
+			continue
+		}
+		expanded = append(expanded, token)
+	}
+
+	// 将展开结果插入到剩余记号之前
+	positions := make([]Position, len(expanded))
+	for i := range positions {
+		positions[i] = position
+	}
+	e.tokens = append(expanded, e.tokens[e.index:]...)
+	e.positions = append(positions, e.positions[e.index:]...)
+	e.index = 0
+}
+
+// operatorName 将 \DeclareMathOperator 的定义体转换为运算符名称，\, 等间距转换为细空格
+func operatorName(body string) string {
+	replacer := strings.NewReplacer(`\,`, " ", `\:`, " ", `\;`, " ", `\ `, " ", `\!`, "", "{", "", "}", "")
+	return strings.TrimSpace(replacer.Replace(body))
+}
+
+// isKnownCommand 判断是否为内置的命令或符号
+func isKnownCommand(name string) bool {
+	return !isUndefinedCommand(name) || slices.Contains(COMMANDS_WITH_ONE_PARAMETER, name) || slices.Contains(COMMANDS_WITH_TWO_PARAMETERS, name)
+}
@@ -0,0 +1,116 @@
+package latex2mathml
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConvertMacros(t *testing.T) {
+	tests := []struct {
+		name    string
+		latex   string
+		want    []string
+		wantErr string
+	}{
+		{"newcommand", `\newcommand{\R}{\mathbb{R}} x \in \R`, []string{"<mi>x</mi><mi>&#x02208;</mi><mrow><mi>R</mi></mrow>"}, ""},
+		{"parameters", `\newcommand{\pair}[2]{(#1,#2)} \pair{a}{b}`, []string{"<mi>a</mi></mrow><mo>&#x0002C;</mo><mrow><mi>b</mi>"}, ""},
+		{"optional parameter", `\newcommand{\opt}[2][n]{#1^#2} \opt{x} \opt[m]{y}`, []string{"<mi>n</mi></mrow><mrow><mi>x</mi>", "<mi>m</mi></mrow><mrow><mi>y</mi>"}, ""},
+		{"def", `\def\sq#1{#1^2} \sq{z}`, []string{"<msup><mrow><mi>z</mi></mrow><mn>2</mn></msup>"}, ""},
+		{"operator", `\DeclareMathOperator{\tr}{tr} \tr A`, []string{"<mo>tr</mo><mi>A</mi>"}, ""},
+		{"operator with limits", `\DeclareMathOperator*{\argmax}{arg\,max}\argmax_x f`, []string{"<munder><mo>arg", "</mo><mi>x</mi></munder>"}, ""},
+		{"recursive", `\newcommand{\x}{\x} \x`, nil, "Macro expansion limit exceeded"},
+		{"already defined", `\newcommand{\a}{1}\newcommand{\a}{2}`, nil, `Command \a already defined`},
+		{"renew undefined", `\renewcommand{\foo}{a}`, nil, `Command \foo undefined`},
+		{"bad parameter count", `\newcommand{\f}[x]{a}`, nil, "Invalid number of macro parameters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Converter{}.Convert(tt.latex)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Convert(%q) error = %v, want %q", tt.latex, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Convert(%q) = %s, want it to contain %s", tt.latex, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMacros(t *testing.T) {
+	base, err := ParseMacros(`\newcommand{\R}{\mathbb{R}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := base.Parse(`\renewcommand{\R}{\mathbb{Q}} \DeclareMathOperator{\rank}{rank}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base[`\R`].Body != `\mathbb{R}` || len(base) != 1 {
+		t.Errorf("Parse modified the original macros: %v", base)
+	}
+	if merged[`\R`].Body != `\mathbb{Q}` || !merged[`\rank`].Operator {
+		t.Errorf("merged macros = %v", merged)
+	}
+
+	// 公式中的定义不影响 Converter 的宏
+	converter := Converter{Macros: base}
+	if _, err := converter.Convert(`\renewcommand{\R}{x} \R`); err != nil {
+		t.Fatal(err)
+	}
+	if base[`\R`].Body != `\mathbb{R}` {
+		t.Errorf("definition in a formula leaked into Converter.Macros: %v", base)
+	}
+}
+
+func TestConvertMacrosConcurrent(t *testing.T) {
+	base, err := ParseMacros(`\newcommand{\R}{\mathbb{R}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const goroutines = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 每个 goroutine 使用自己的 Macros，同时共用 base，公式中的定义不能影响其他 goroutine
+			macros, err := ParseMacros(fmt.Sprintf(`\newcommand{\n}{%d}`, i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			for name, macro := range base {
+				macros[name] = macro
+			}
+			latex := fmt.Sprintf(`\newcommand{\local}{%d} \n + \local \in \R`, i+1000)
+			got, err := Converter{Macros: macros}.Convert(latex)
+			if err != nil {
+				errs <- fmt.Errorf("Convert(%q): %w", latex, err)
+				return
+			}
+			want := fmt.Sprintf("<mn>%d</mn><mo>&#x0002B;</mo><mn>%d</mn>", i, i+1000)
+			if !strings.Contains(got, want) {
+				errs <- fmt.Errorf("Convert(%q) = %s, want it to contain %s", latex, got, want)
+			}
+			if _, err := (Converter{Macros: base}).Convert(`\local`); err == nil {
+				errs <- fmt.Errorf("definition of \\local leaked into the shared Macros")
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
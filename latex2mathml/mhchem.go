@@ -0,0 +1,515 @@
+package latex2mathml
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	CE = `\ce`
+	PU = `\pu`
+)
+
+// chemistryPattern 匹配 mhchem 的 \ce{ 和 \pu{
+var chemistryPattern = regexp.MustCompile(`\\(ce|pu)\s*{`)
+
+// chemArrows mhchem 的反应箭头，较长的箭头需要先于较短的匹配
+var chemArrows = []struct {
+	arrow   string
+	command string
+}{
+	{"<-->", `\rightleftarrows`},
+	{"<=>>", `\underset{\leftharpoondown}{\longrightarrow}`}, // 平衡偏向右侧
+	{"<<=>", `\overset{\rightharpoonup}{\longleftarrow}`},    // 平衡偏向左侧
+	{"<=>", `\rightleftharpoons`},
+	{"<->", `\longleftrightarrow`},
+	{"->", `\longrightarrow`},
+	{"<-", `\longleftarrow`},
+}
+
+// unitNumberPattern 匹配 \pu 开头的数值，如 -1.5、6.02e23、1,5E-3
+var unitNumberPattern = regexp.MustCompile(`^([+\-−]?\d+(?:[.,]\d+)?)(?:[eE]([+\-−]?\d+))?`)
+
+// chemistry 表示输入中的一个 \ce{…} 或 \pu{…}
+type chemistry struct {
+	start, end int    // 在输入中的字节范围，包括命令和大括号
+	latex      string // 转换得到的 LaTeX
+}
+
+// findChemistry 查找输入中的 \ce{…} 和 \pu{…} 并转换为 LaTeX，大括号没有闭合时按原样保留
+func findChemistry(latex string) []chemistry {
+	var result []chemistry
+	for from := 0; from < len(latex); {
+		match := chemistryPattern.FindStringSubmatchIndex(latex[from:])
+		if match == nil {
+			break
+		}
+		start, open := from+match[0], from+match[1]
+		end := matchingBrace(latex, open)
+		if end < 0 {
+			break
+		}
+
+		argument := latex[open:end]
+		var converted string
+		if latex[from+match[2]:from+match[3]] == "ce" {
+			converted = chemToLatex(argument)
+		} else {
+			converted = unitsToLatex(argument)
+		}
+		result = append(result, chemistry{start: start, end: end + 1, latex: "{" + converted + "}"})
+		from = end + 1
+	}
+	return result
+}
+
+// matchingBrace 返回与 open 之前的左大括号匹配的右大括号的位置，跳过转义的大括号，找不到时返回 -1
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// chemToLatex 将 \ce 的参数转换为 LaTeX
+// 元素符号为直立体，化学式后的数字为下标，末尾的 + 和 - 以及 ^ 后的内容为电荷，_ 和 ^ 在元素之前时为同位素的质量数和原子序数
+// 支持反应箭头及其上下的条件（如 ->[heat]、<=>）、沉淀和气体符号（v 和 ^）、结晶水（* 或 .）和 $…$ 中的数学公式
+func chemToLatex(input string) string {
+	runes := []rune(input)
+	var out strings.Builder
+
+	standalone := func(i int, r rune) bool {
+		return runes[i] == r && (i == 0 || unicode.IsSpace(runes[i-1])) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1]))
+	}
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case chemArrowAt(runes, i) >= 0:
+			i = writeChemArrow(&out, runes, i)
+		case standalone(i, '+'):
+			out.WriteString(" + ")
+			i++
+		case standalone(i, 'v'):
+			out.WriteString(`\downarrow `)
+			i++
+		case standalone(i, '^'):
+			out.WriteString(`\uparrow `)
+			i++
+		default:
+			end := chemWordEnd(runes, i)
+			out.WriteString(chemFormula(runes[i:end]))
+			i = end
+		}
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// chemArrowAt 返回位置 i 处的反应箭头在 chemArrows 中的序号，不是箭头时返回 -1
+func chemArrowAt(runes []rune, i int) int {
+	for index, arrow := range chemArrows {
+		if strings.HasPrefix(string(runes[i:]), arrow.arrow) {
+			return index
+		}
+	}
+	return -1
+}
+
+// writeChemArrow 输出位置 i 处的反应箭头及其后的 [上方条件][下方条件]，返回箭头之后的位置
+func writeChemArrow(out *strings.Builder, runes []rune, i int) int {
+	arrow := chemArrows[chemArrowAt(runes, i)]
+	i += len([]rune(arrow.arrow))
+
+	var conditions []string
+	for len(conditions) < 2 && i < len(runes) && runes[i] == '[' {
+		end := matchingRune(runes, i, '[', ']')
+		if end < 0 {
+			break
+		}
+		conditions = append(conditions, chemCondition(string(runes[i+1:end])))
+		i = end + 1
+	}
+
+	symbol := arrow.command
+	if len(conditions) > 1 && conditions[1] != "" {
+		symbol = `\underset{` + conditions[1] + `}{` + symbol + `}`
+	}
+	if len(conditions) > 0 && conditions[0] != "" {
+		symbol = `\overset{` + conditions[0] + `}{` + symbol + `}`
+	}
+	out.WriteString(` \; ` + symbol + ` \; `)
+	return i
+}
+
+// chemCondition 转换反应条件，大括号中的内容和小写字母开头的单词（如 heat、reflux）为文本，其余的按化学式转换，如 H2O、$T$
+func chemCondition(condition string) string {
+	condition = strings.TrimSpace(condition)
+	if runes := []rune(condition); len(runes) > 1 && runes[0] == '{' && matchingRune(runes, 0, '{', '}') == len(runes)-1 {
+		return `\text{` + string(runes[1:len(runes)-1]) + `}`
+	}
+
+	var parts, text []string
+	flushText := func() {
+		if len(text) > 0 {
+			parts = append(parts, `\text{`+strings.Join(text, " ")+`}`)
+			text = nil
+		}
+	}
+	for _, word := range strings.Fields(condition) {
+		if isChemText(word) {
+			text = append(text, word)
+			continue
+		}
+		flushText()
+		parts = append(parts, chemToLatex(word))
+	}
+	flushText()
+	return strings.Join(parts, ` \; `)
+}
+
+// isChemText 判断条件中的单词是否为文本：以小写字母开头，不包含数字、上下标和公式
+func isChemText(word string) bool {
+	runes := []rune(word)
+	if !unicode.IsLower(runes[0]) {
+		return false
+	}
+	return !strings.ContainsAny(word, "0123456789^_$\\{}")
+}
+
+// chemWordEnd 返回从 start 开始的化学式的结束位置，化学式在空白或反应箭头处结束，大括号和 $…$ 中的空白不计
+func chemWordEnd(runes []rune, start int) int {
+	depth := 0
+	math := false
+	for i := start; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '$':
+			math = !math
+		case math:
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case depth > 0:
+		case unicode.IsSpace(r):
+			return i
+		case i > start && chemArrowAt(runes, i) >= 0:
+			return i
+		}
+	}
+	return len(runes)
+}
+
+// matchingRune 返回与位置 open 处的左括号匹配的右括号的位置，找不到时返回 -1
+func matchingRune(runes []rune, open int, left, right rune) int {
+	depth := 0
+	for i := open; i < len(runes); i++ {
+		switch runes[i] {
+		case left:
+			depth++
+		case right:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// chemFormula 转换一个化学式，如 2H2O、SO4^2-、^{227}_{90}Th、CuSO4*5H2O
+func chemFormula(runes []rune) string {
+	var out strings.Builder
+	var sub, sup string
+	hasBase := false
+
+	// flush 输出前一个元素的上下标
+	flush := func() {
+		if sub == "" && sup == "" {
+			return
+		}
+		if !hasBase {
+			out.WriteString("{}")
+		}
+		if sub != "" {
+			out.WriteString("_{" + uprightLetters(sub) + "}")
+		}
+		if sup != "" {
+			out.WriteString("^{" + uprightLetters(sup) + "}")
+		}
+		sub, sup = "", ""
+	}
+
+	// 化学计量数
+	i := chemCoefficient(&out, runes, 0)
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsUpper(r) || unicode.IsLower(r):
+			// 元素符号：一个大写字母和随后的小写字母；单独的小写字母如 e（电子）、aq 同样为直立体
+			end := i + 1
+			for end < len(runes) && unicode.IsLower(runes[end]) {
+				end++
+			}
+			element := `\mathrm{` + string(runes[i:end]) + `}`
+			if !hasBase && (sub != "" || sup != "") {
+				// 元素之前的上下标为同位素的质量数和原子序数
+				element = `\prescript{` + uprightLetters(sup) + `}{` + uprightLetters(sub) + `}{` + element + `}`
+				sub, sup = "", ""
+			}
+			flush()
+			out.WriteString(element)
+			hasBase = true
+			i = end
+		case unicode.IsDigit(r) && hasBase:
+			end := i
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			sub += string(runes[i:end])
+			i = end
+		case r == '^' || r == '_':
+			script, end := chemScript(runes, i+1)
+			if r == '^' {
+				sup += script
+			} else {
+				sub += script
+			}
+			i = end
+		case (r == '+' || r == '-') && chemChargeEnd(runes, i):
+			// 末尾的电荷，如 Na+、NO3-、e-
+			for ; i < len(runes) && (runes[i] == '+' || runes[i] == '-'); i++ {
+				sup += string(runes[i])
+			}
+		case r == '-':
+			flush()
+			out.WriteString(`{-}`)
+			hasBase = false
+			i++
+		case r == '=':
+			flush()
+			out.WriteString(`{=}`)
+			hasBase = false
+			i++
+		case r == '#':
+			flush()
+			out.WriteString(`{\equiv}`)
+			hasBase = false
+			i++
+		case r == '+':
+			flush()
+			out.WriteString(` + `)
+			hasBase = false
+			i++
+		case r == '*' || r == '.' || r == '·':
+			// 结晶水等加合物，如 CuSO4*5H2O
+			flush()
+			out.WriteString(`\cdot `)
+			hasBase = false
+			i = chemCoefficient(&out, runes, i+1)
+		case r == '(' || r == '[':
+			flush()
+			out.WriteRune(r)
+			hasBase = false
+			i++
+		case r == ')' || r == ']':
+			flush()
+			out.WriteRune(r)
+			hasBase = true
+			i++
+		case r == '$':
+			end := i + 1
+			for end < len(runes) && runes[end] != '$' {
+				end++
+			}
+			flush()
+			out.WriteString("{" + string(runes[i+1:end]) + "}")
+			hasBase = true
+			i = end + 1
+		case r == '{':
+			end := matchingRune(runes, i, '{', '}')
+			if end < 0 {
+				end = len(runes)
+			}
+			flush()
+			out.WriteString(`\mathrm{` + string(runes[i+1:end]) + `}`)
+			hasBase = true
+			i = end + 1
+		case r == '\\':
+			end := i + 1
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+			if end == i+1 && end < len(runes) {
+				end++
+			}
+			flush()
+			out.WriteString(string(runes[i:end]) + " ")
+			hasBase = true
+			i = end
+		default:
+			flush()
+			out.WriteRune(r)
+			hasBase = !unicode.IsDigit(r)
+			i++
+		}
+	}
+	flush()
+	return out.String()
+}
+
+// uprightLetters 将上下标中的字母（如氧化态 III）转换为直立体，命令名保持不变
+func uprightLetters(script string) string {
+	runes := []rune(script)
+	var out strings.Builder
+	for i := 0; i < len(runes); {
+		end := i + 1
+		switch {
+		case runes[i] == '\\':
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+			out.WriteString(string(runes[i:end]))
+		case unicode.IsLetter(runes[i]):
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+			out.WriteString(`\mathrm{` + string(runes[i:end]) + `}`)
+		default:
+			out.WriteRune(runes[i])
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// chemCoefficient 输出从 i 开始的化学计量数，如 2、1/2、0.5，返回其后的位置
+func chemCoefficient(out *strings.Builder, runes []rune, i int) int {
+	end := i
+	for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == '/') {
+		end++
+	}
+	if end == i {
+		return i
+	}
+	coefficient := string(runes[i:end])
+	if numerator, denominator, ok := strings.Cut(coefficient, "/"); ok {
+		coefficient = `\frac{` + numerator + `}{` + denominator + `}`
+	}
+	out.WriteString(coefficient)
+	return end
+}
+
+// chemScript 读取 ^ 或 _ 之后的上下标，可以是大括号包围的内容，也可以是数字和随后的 + 或 -（如 ^2-）
+func chemScript(runes []rune, i int) (string, int) {
+	if i < len(runes) && runes[i] == '{' {
+		end := matchingRune(runes, i, '{', '}')
+		if end < 0 {
+			return string(runes[i+1:]), len(runes)
+		}
+		return string(runes[i+1 : end]), end + 1
+	}
+	end := i
+	for end < len(runes) && unicode.IsDigit(runes[end]) {
+		end++
+	}
+	for end < len(runes) && (runes[end] == '+' || runes[end] == '-') {
+		end++
+	}
+	if end == i && end < len(runes) {
+		end++
+	}
+	return string(runes[i:end]), end
+}
+
+// chemChargeEnd 判断位置 i 开始的 + 或 - 是否为末尾的电荷，其后只能是 + 或 -，或者是状态如 (aq)
+func chemChargeEnd(runes []rune, i int) bool {
+	for ; i < len(runes); i++ {
+		if runes[i] == '(' {
+			return true
+		}
+		if runes[i] != '+' && runes[i] != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// unitsToLatex 将 \pu 的参数转换为 LaTeX，数值与单位之间为细空格，单位为直立体
+// 支持科学计数法（1.2e3）、单位间的 . 或 * 乘号和 / 除号，以及单位的指数（m^2、s^-1）
+func unitsToLatex(input string) string {
+	input = strings.TrimSpace(input)
+	var out strings.Builder
+
+	if match := unitNumberPattern.FindStringSubmatch(input); match != nil {
+		out.WriteString(strings.ReplaceAll(match[1], "−", "-"))
+		if match[2] != "" {
+			out.WriteString(`\cdot 10^{` + strings.ReplaceAll(match[2], "−", "-") + `}`)
+		}
+		input = strings.TrimSpace(input[len(match[0]):])
+		if input != "" {
+			out.WriteString(`\,`)
+		}
+	}
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r) || r == '.' || r == '*' || r == '·':
+			// 单位之间的空白和乘号
+			for i < len(runes) && (unicode.IsSpace(runes[i]) || runes[i] == '.' || runes[i] == '*' || runes[i] == '·') {
+				i++
+			}
+			if i < len(runes) && runes[i] != '/' && out.Len() > 0 {
+				out.WriteString(`\cdot `)
+			}
+		case r == '/':
+			out.WriteString(`/`)
+			i++
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+		case r == '^':
+			exponent, end := unitExponent(runes, i+1)
+			out.WriteString(`^{` + exponent + `}`)
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(".*·/^", runes[end]) {
+				end++
+			}
+			out.WriteString(`\mathrm{` + string(runes[i:end]) + `}`)
+			i = end
+		}
+	}
+	return out.String()
+}
+
+// unitExponent 读取单位的指数，可以是大括号包围的内容，也可以是带符号的整数（如 ^2、^-1）
+func unitExponent(runes []rune, i int) (string, int) {
+	if i < len(runes) && runes[i] == '{' {
+		return chemScript(runes, i)
+	}
+	end := i
+	if end < len(runes) && (runes[end] == '+' || runes[end] == '-' || runes[end] == '−') {
+		end++
+	}
+	for end < len(runes) && unicode.IsDigit(runes[end]) {
+		end++
+	}
+	return strings.ReplaceAll(string(runes[i:end]), "−", "-"), end
+}
@@ -0,0 +1,59 @@
+package latex2mathml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChemToLatex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"H2O", `\mathrm{H}_{2}\mathrm{O}`},
+		{"SO4^2-", `\mathrm{S}\mathrm{O}_{4}^{2-}`},
+		{"Na+", `\mathrm{Na}^{+}`},
+		{"^{14}_{6}C", `\prescript{14}{6}{\mathrm{C}}`},
+		{"^{227}_{90}Th+", `\prescript{227}{90}{\mathrm{Th}}^{+}`},
+		{"CuSO4*5H2O", `\mathrm{Cu}\mathrm{S}\mathrm{O}_{4}\cdot 5\mathrm{H}_{2}\mathrm{O}`},
+		{"A -> B", `\mathrm{A} \; \longrightarrow \; \mathrm{B}`},
+		{"A <=> B", `\mathrm{A} \; \rightleftharpoons \; \mathrm{B}`},
+		{"A <=>> B", `\mathrm{A} \; \underset{\leftharpoondown}{\longrightarrow} \; \mathrm{B}`},
+		{"A <<=> B", `\mathrm{A} \; \overset{\rightharpoonup}{\longleftarrow} \; \mathrm{B}`},
+		{"A ->[heat] B", `\mathrm{A} \; \overset{\text{heat}}{\longrightarrow} \; \mathrm{B}`},
+		{"A ->[{text above}][H2O] B", `\overset{\text{text above}}{\underset{\mathrm{H}_{2}\mathrm{O}}{\longrightarrow}}`},
+		{"A ->[cat. Pt] B", `\overset{\text{cat.} \; \mathrm{Pt}}{\longrightarrow}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := chemToLatex(tt.input); !strings.Contains(got, tt.want) {
+				t.Errorf("chemToLatex(%q) = %s, want it to contain %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertChemistry(t *testing.T) {
+	tests := []struct {
+		latex string
+		want  []string
+	}{
+		{`\ce{^{14}_{6}C}`, []string{"<mmultiscripts>", "<mprescripts/><mrow><mn>6</mn></mrow><mrow><mn>14</mn></mrow></mmultiscripts>"}},
+		{`\ce{A ->[heat] B}`, []string{"<mtext>heat</mtext>"}},
+		{`\pu{1.5e3 kg}`, []string{"<mn>1.5</mn>"}},
+		{`\prescript{}{2}{x}`, []string{"<mprescripts/><mrow><mn>2</mn></mrow><none/>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.latex, func(t *testing.T) {
+			got, err := Converter{}.Convert(tt.latex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Convert(%q) = %s, want it to contain %s", tt.latex, got, want)
+				}
+			}
+		})
+	}
+}
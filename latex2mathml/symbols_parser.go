@@ -6,6 +6,7 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 //go:embed `unimathsymbols.txt`
@@ -13,6 +14,10 @@ var SymbolsFile embed.FS
 
 var Symbols = map[string]string{}
 
+// symbolCommands Unicode 字符到 LaTeX 命令的映射，使用符号表中的 LaTeX 列
+var symbolCommands = map[string]string{}
+
+// Deprecated: 符号表由 ParseSymbol 通过 sync.Once 构建，该变量不能用来判断或控制初始化，不应再读取或修改
 var SYMBOLS_INITILIZED = false
 
 func ConvertSymbol(symbol string) (string, error) {
@@ -24,88 +29,104 @@ func ConvertSymbol(symbol string) (string, error) {
 	}
 }
 
+// SymbolCommand 返回 Unicode 字符对应的 LaTeX 命令，如 "α" 返回 `\alpha`，"−" 返回 "-"
+func SymbolCommand(symbol string) (string, bool) {
+	ParseSymbol()
+	command, exists := symbolCommands[symbol]
+	return command, exists
+}
+
+// ParseSymbol 解析符号表，只在第一次调用时执行，可以并发调用
 func ParseSymbol() {
-	if !SYMBOLS_INITILIZED {
-		file, _ := SymbolsFile.Open("unimathsymbols.txt")
+	symbolsOnce.Do(parseSymbol)
+}
 
-		re := regexp.MustCompile(`[=#]\s*(\\[^,^ ]+),?`)
-		scanner := bufio.NewScanner(file)
+var symbolsOnce sync.Once
 
-		for scanner.Scan() {
-			line := scanner.Text()
+func parseSymbol() {
+	file, _ := SymbolsFile.Open("unimathsymbols.txt")
 
-			if !strings.HasPrefix(line, "#") {
-				columns := strings.Split(strings.Trim(line, " "), "^")
+	re := regexp.MustCompile(`[=#]\s*(\\[^,^ ]+),?`)
+	scanner := bufio.NewScanner(file)
 
-				for i := 1; i <= 3; i++ {
-					_, exists := Symbols[columns[i]]
+	for scanner.Scan() {
+		line := scanner.Text()
 
-					if columns[i] != "" && !exists {
-						Symbols[columns[i]] = columns[0]
-					}
+		if !strings.HasPrefix(line, "#") {
+			columns := strings.Split(strings.Trim(line, " "), "^")
+
+			if _, exists := symbolCommands[columns[1]]; !exists && columns[2] != "" && columns[2] != columns[1] {
+				symbolCommands[columns[1]] = columns[2]
+			}
+
+			for i := 1; i <= 3; i++ {
+				_, exists := Symbols[columns[i]]
+
+				if columns[i] != "" && !exists {
+					Symbols[columns[i]] = columns[0]
 				}
+			}
 
-				for _, equivalents := range re.FindAllStringSubmatch(columns[len(columns)-1], -1) {
-					for _, equivalent := range equivalents[1:] {
-						if len(equivalent) > 0 {
-							_, exists := Symbols[equivalent]
-							if !exists {
-								Symbols[equivalent] = columns[0]
-							}
+			for _, equivalents := range re.FindAllStringSubmatch(columns[len(columns)-1], -1) {
+				for _, equivalent := range equivalents[1:] {
+					if len(equivalent) > 0 {
+						_, exists := Symbols[equivalent]
+						if !exists {
+							Symbols[equivalent] = columns[0]
 						}
 					}
 				}
-
 			}
-		}
 
-		var symbolsUpdate = map[string]string{
-			`\And`:            Symbols[`\ampersand`],
-			`\bigcirc`:        Symbols[`\lgwhtcircle`],
-			`\Box`:            Symbols[`\square`],
-			`\circledS`:       "024C8",
-			`\diagdown`:       "02572",
-			`\diagup`:         "02571",
-			`\dots`:           "02026",
-			`\dotsb`:          Symbols[`\cdots`],
-			`\dotsc`:          "02026",
-			`\dotsi`:          Symbols[`\cdots`],
-			`\dotsm`:          Symbols[`\cdots`],
-			`\dotso`:          "02026",
-			`\emptyset`:       "02205",
-			`\gggt`:           "022D9",
-			`\gvertneqq`:      "02269",
-			`\gt`:             Symbols[`\greate`],
-			`\ldotp`:          Symbols[`\period`],
-			`\llless`:         Symbols[`\lll`],
-			`\lt`:             Symbols[`\less`],
-			`\lvert`:          Symbols[`\vert`],
-			`\lVert`:          Symbols[`\Vert`],
-			`\lvertneqq`:      Symbols[`\lneqq`],
-			`\ngeqq`:          Symbols[`\ngeq`],
-			`\nshortmid`:      Symbols[`\nmid`],
-			`\nshortparallel`: Symbols[`\nparallel`],
-			`\nsubseteqq`:     Symbols[`\nsubseteq`],
-			`\omicron`:        Symbols[`\upomicron`],
-			`\rvert`:          Symbols[`\vert`],
-			`\rVert`:          Symbols[`\Vert`],
-			`\shortmid`:       Symbols[`\mid`],
-			`\smallfrown`:     Symbols[`\frown`],
-			`\smallint`:       "0222B",
-			`\smallsmile`:     Symbols[`\smile`],
-			`\surd`:           Symbols[`\sqrt`],
-			`\thicksim`:       "0223C",
-			`\thickapprox`:    Symbols[`\approx`],
-			`\varsubsetneqq`:  Symbols[`\subsetneqq`],
-			`\varsupsetneq`:   "0228B",
-			`\varsupsetneqq`:  Symbols[`\supsetneqq`],
 		}
+	}
 
-		for key, value := range symbolsUpdate {
-			Symbols[key] = value
-		}
+	var symbolsUpdate = map[string]string{
+		`\And`:            Symbols[`\ampersand`],
+		`\bigcirc`:        Symbols[`\lgwhtcircle`],
+		`\Box`:            Symbols[`\square`],
+		`\circledS`:       "024C8",
+		`\diagdown`:       "02572",
+		`\diagup`:         "02571",
+		`\dots`:           "02026",
+		`\dotsb`:          Symbols[`\cdots`],
+		`\dotsc`:          "02026",
+		`\dotsi`:          Symbols[`\cdots`],
+		`\dotsm`:          Symbols[`\cdots`],
+		`\dotso`:          "02026",
+		`\emptyset`:       "02205",
+		`\gggt`:           "022D9",
+		`\gvertneqq`:      "02269",
+		`\gt`:             Symbols[`\greate`],
+		`\ldotp`:          Symbols[`\period`],
+		`\llless`:         Symbols[`\lll`],
+		`\lt`:             Symbols[`\less`],
+		`\lvert`:          Symbols[`\vert`],
+		`\lVert`:          Symbols[`\Vert`],
+		`\lvertneqq`:      Symbols[`\lneqq`],
+		`\ngeqq`:          Symbols[`\ngeq`],
+		`\nshortmid`:      Symbols[`\nmid`],
+		`\nshortparallel`: Symbols[`\nparallel`],
+		`\nsubseteqq`:     Symbols[`\nsubseteq`],
+		`\omicron`:        Symbols[`\upomicron`],
+		`\rvert`:          Symbols[`\vert`],
+		`\rVert`:          Symbols[`\Vert`],
+		`\shortmid`:       Symbols[`\mid`],
+		`\smallfrown`:     Symbols[`\frown`],
+		`\smallint`:       "0222B",
+		`\smallsmile`:     Symbols[`\smile`],
+		`\surd`:           Symbols[`\sqrt`],
+		`\thicksim`:       "0223C",
+		`\thickapprox`:    Symbols[`\approx`],
+		`\varsubsetneqq`:  Symbols[`\subsetneqq`],
+		`\varsupsetneq`:   "0228B",
+		`\varsupsetneqq`:  Symbols[`\supsetneqq`],
+	}
 
-		delete(Symbols, `\mathring`)
-		SYMBOLS_INITILIZED = true
+	for key, value := range symbolsUpdate {
+		Symbols[key] = value
 	}
+
+	delete(Symbols, `\mathring`)
+	SYMBOLS_INITILIZED = true
 }
@@ -3,6 +3,7 @@ package latex2mathml
 import (
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 var UNITS = []string{"in", "mm", "cm", "pt", "em", "ex", "pc", "bp", "dd", "cc", "sp", "mu"}
@@ -17,6 +18,7 @@ var PATTERN = []string{
 	`(\\(?:begin|end)\s*{[a-zA-Z]+\*?})`,
 	`(\\operatorname\s*{[a-zA-Z\s*]+\*?\s*})`,
 	`(\\(?:color|fbox|hbox|href|mbox|style|text|textbf|textit|textrm|textsf|texttt))\s*{([^}}]*)}`,
+	`(\\(?:tag\*?|label|eqref|ref))\s*{([^}}]*)}`,
 	`(\\[cdt]?frac)\s*([.\d])\s*([.\d])?`,
 	`(\\math[a-z]+)({)([a-zA-Z])(})`,
 	`(\\[a-zA-Z]+)`,
@@ -36,31 +38,101 @@ func Filter(vs []string, f func(string) bool) []string {
 }
 
 func Tokenize(latex string) []string {
+	tokens, _ := tokenize(latex)
+	return tokens
+}
+
+// tokenize 将 LaTeX 拆分为记号，同时返回每个记号在输入中的位置
+// \ce{…} 和 \pu{…} 先转换为 LaTeX 再拆分，得到的记号都使用命令所在的位置
+func tokenize(latex string) ([]string, []Position) {
+	var tokens = []string{}
+	var positions = []Position{}
+
+	appendSegment := func(from, to int) {
+		segmentTokens, segmentPositions := tokenizeLines(latex[from:to])
+		base := positionAt(latex, from)
+		for i := range segmentTokens {
+			tokens = append(tokens, segmentTokens[i])
+			positions = append(positions, shiftPosition(segmentPositions[i], base))
+		}
+	}
+
+	from := 0
+	for _, chem := range findChemistry(latex) {
+		appendSegment(from, chem.start)
+		chemTokens, _ := tokenizeLines(chem.latex)
+		position := positionAt(latex, chem.start)
+		for _, token := range chemTokens {
+			tokens = append(tokens, token)
+			positions = append(positions, position)
+		}
+		from = chem.end
+	}
+	appendSegment(from, len(latex))
+	return tokens, positions
+}
+
+// positionAt 返回字节偏移 offset 处的位置
+func positionAt(latex string, offset int) Position {
+	return endPosition(latex[:offset])
+}
+
+// shiftPosition 将相对于 base 的位置转换为相对于整个输入的位置
+func shiftPosition(position Position, base Position) Position {
+	if position.Line == 1 {
+		position.Column += base.Column - 1
+	}
+	position.Line += base.Line - 1
+	position.Offset += base.Offset
+	return position
+}
+
+// tokenizeLines 按行拆分记号
+func tokenizeLines(latex string) ([]string, []Position) {
 	var tokens = []string{}
+	var positions = []Position{}
 
-	for _, latex_line := range strings.Split(latex, "\n") {
-		for index, matchs := range RE.FindAllStringSubmatch(latex_line, -1) {
-			for _, match := range matchs[1:] {
+	add := func(token string, lineOffset int, line string, lineIndex int, offset int) {
+		if token == "" {
+			return
+		}
+		tokens = append(tokens, token)
+		positions = append(positions, Position{
+			Offset: lineOffset + offset,
+			Line:   lineIndex + 1,
+			Column: utf8.RuneCountInString(line[:offset]) + 1,
+		})
+	}
+
+	var lineOffset = 0
+	for lineIndex, latex_line := range strings.Split(latex, "\n") {
+		for index, indexes := range RE.FindAllStringSubmatchIndex(latex_line, -1) {
+			for group := 1; group < len(indexes)/2; group++ {
+				start, end := indexes[2*group], indexes[2*group+1]
+				if start < 0 {
+					continue
+				}
+				match := latex_line[start:end]
 				if len(match) > 0 && !strings.HasPrefix(match, "%") {
 					if index == 0 && strings.HasPrefix(match, MATH) {
 						symbol, exists := Symbols[match]
 						if exists {
-							tokens = append(tokens, "&#x"+symbol+";")
+							add("&#x"+symbol+";", lineOffset, latex_line, lineIndex, start)
 							continue
 						}
 					} else {
 						var add_token = false
 
 						if match[0] == '_' || match[0] == '^' {
-							tokens = append(tokens, match[0:1])
-							tokens = append(tokens, match[1:])
+							add(match[0:1], lineOffset, latex_line, lineIndex, start)
+							add(match[1:], lineOffset, latex_line, lineIndex, start+1)
 							add_token = true
 						}
 
 						if !add_token {
 							for _, unit := range UNITS {
 								if strings.HasSuffix(match, unit) {
-									tokens = append(tokens, strings.ReplaceAll(match, " ", ""))
+									add(strings.ReplaceAll(match, " ", ""), lineOffset, latex_line, lineIndex, start)
 									add_token = true
 									break
 								}
@@ -70,7 +142,7 @@ func Tokenize(latex string) []string {
 						if !add_token {
 							for _, command := range []string{BEGIN, END, OPERATORNAME} {
 								if strings.HasPrefix(match, command) {
-									tokens = append(tokens, strings.ReplaceAll(match, " ", ""))
+									add(strings.ReplaceAll(match, " ", ""), lineOffset, latex_line, lineIndex, start)
 									add_token = true
 									break
 								}
@@ -78,13 +150,14 @@ func Tokenize(latex string) []string {
 						}
 
 						if !add_token {
-							tokens = append(tokens, match)
+							add(match, lineOffset, latex_line, lineIndex, start)
 						}
 					}
 				}
 			}
 		}
+		lineOffset += len(latex_line) + 1
 	}
 
-	return Filter(tokens, func(s string) bool { return s != "" })
+	return tokens, positions
 }
@@ -27,22 +27,32 @@ var (
 )
 
 func Walk(data string) ([]Node, error) {
-	tokens := Tokenize(data)
-	iterator := makeIterator(tokens)
-	return processToken(iterator, "", 0)
+	return walk(newTokenIterator(data, nil))
 }
 
-func makeIterator(tokens []string) func() string {
-	var index = 0
-
-	return func() string {
-		var token string
-		if index < len(tokens) {
-			token = tokens[index]
+// walk 解析全部记号，返回遇到的第一个语法错误；格式错误导致的 panic 同样作为语法错误返回
+func walk(tokens *tokenIterator) (nodes []Node, err error) {
+	if len(tokens.tokens) == 0 {
+		if tokens.err != nil {
+			return []Node{}, tokens.err
+		}
+		return []Node{}, nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tokens.fail(tokens.current(), fmt.Sprintf("Malformed input: %v", r))
+			nodes, err = nil, tokens.err
 		}
-		index = index + 1
-		return token
+	}()
+
+	nodes, err = processToken(tokens, "", 0)
+	if tokens.err != nil {
+		return nodes, tokens.err
 	}
+	if err != nil {
+		return nodes, tokens.fail(tokens.current(), err.Error())
+	}
+	return nodes, nil
 }
 
 func containsKey[M ~map[K]V, K comparable, V any](m M, k K) bool {
@@ -50,28 +60,29 @@ func containsKey[M ~map[K]V, K comparable, V any](m M, k K) bool {
 	return ok
 }
 
-func processToken(tokens func() string, terminator string, limit int) ([]Node, error) {
+func processToken(tokens *tokenIterator, terminator string, limit int) ([]Node, error) {
 	var nodes = []Node{}
 	var hasAvailableTokens = false
 	var node Node
 
-	for token := tokens(); token != ""; token = tokens() {
+	for token := tokens.next(); token != ""; token = tokens.next() {
 		hasAvailableTokens = true
+		start := tokens.current()
 		var delimiter = ""
 
 		if token == terminator {
 			if terminator == RIGHT {
-				delimiter = tokens()
+				delimiter = tokens.next()
 			}
 			nodes = append(nodes, Node{Token: token, Delimiter: delimiter})
 			break
 		} else if token == RIGHT && terminator != RIGHT || token == MIDDLE && terminator != RIGHT {
-			return nodes, errors.New("Extra left or missing right")
+			return nodes, tokens.fail(start, "Extra left or missing right")
 		} else if token == LEFT {
-			delimiter = tokens()
+			delimiter = tokens.next()
 			children, err := processToken(tokens, RIGHT, -1)
 			if err != nil || len(children) == 0 || children[len(children)-1].Token != RIGHT {
-				return nodes, errors.New("Extra left or missing right")
+				return nodes, tokens.fail(start, "Extra left or missing right")
 			}
 			if len(children) > 0 {
 				node = Node{Token: token, Children: children, Delimiter: delimiter}
@@ -82,6 +93,8 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			children, _ := processToken(tokens, CLOSING_BRACE, -1)
 			if len(children) > 0 && children[len(children)-1].Token == CLOSING_BRACE {
 				children = children[:len(children)-1]
+			} else {
+				tokens.fail(start, "Missing closing brace")
 			}
 			node = Node{Token: BRACES, Children: children}
 		} else if token == SUBSCRIPT || token == SUPERSCRIPT {
@@ -94,10 +107,10 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			}
 
 			if token == previous.Token && token == SUBSCRIPT {
-				return nodes, errors.New("Double subscript")
+				return nodes, tokens.fail(start, "Double subscript")
 			}
 			if token == previous.Token && token == SUPERSCRIPT && len(previous.Children) >= 2 && previous.Children[1].Token != PRIME {
-				return nodes, errors.New("Double superscript")
+				return nodes, tokens.fail(start, "Double superscript")
 			}
 
 			var modifier string
@@ -108,11 +121,11 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 					previous = nodes[len(nodes)-1]
 					nodes = nodes[:len(nodes)-1]
 
-					if !strings.HasSuffix(previous.Token, `\\`) {
-						return nodes, errors.New("Limits must flow math operator")
+					if !strings.HasPrefix(previous.Token, BACKSLASH) {
+						return nodes, tokens.fail(start, "Limits must flow math operator")
 					}
 				} else {
-					return nodes, errors.New("Limits must flow math operator")
+					return nodes, tokens.fail(start, "Limits must flow math operator")
 				}
 			}
 
@@ -137,7 +150,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 				children, err := processToken(tokens, terminator, 1)
 
 				if err != nil {
-					return nodes, errors.New("Missing superscript or subscript")
+					return nodes, tokens.fail(start, "Missing superscript or subscript")
 				}
 
 				if previous.Token == OVERBRACE || previous.Token == UNDERBRACE {
@@ -156,7 +169,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			}
 
 			if previous.Token == SUPERSCRIPT && len(previous.Children) >= 2 && previous.Children[1].Token != PRIME {
-				return nodes, errors.New("Double superscripts")
+				return nodes, tokens.fail(start, "Double superscripts")
 			}
 
 			if previous.Token == SUPERSCRIPT && len(previous.Children) >= 2 && previous.Children[1].Token == PRIME {
@@ -171,14 +184,27 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 				node = Node{Token: SUPERSCRIPT, Children: []Node{previous, {Token: PRIME}}}
 			}
 
+		} else if token == PRESCRIPT {
+			// \prescript{上标}{下标}{底数}，与 mathtools 相同
+			children, _ := processToken(tokens, terminator, 3)
+			if countArguments(children, terminator) < 3 {
+				tokens.fail(start, "Missing argument")
+			}
+			node = Node{Token: token, Children: children}
 		} else if slices.Contains(COMMANDS_WITH_TWO_PARAMETERS, token) {
 			children, _ := processToken(tokens, terminator, 2)
+			if countArguments(children, terminator) < 2 {
+				tokens.fail(start, "Missing argument")
+			}
 			if token == OVERSET || token == UNDERSET {
 				slices.Reverse(children)
 			}
 			node = Node{Token: token, Children: children}
 		} else if slices.Contains(COMMANDS_WITH_ONE_PARAMETER, token) || strings.HasPrefix(token, MATH) {
 			children, _ := processToken(tokens, terminator, 1)
+			if countArguments(children, terminator) < 1 {
+				tokens.fail(start, "Missing argument")
+			}
 			node = Node{Token: token, Children: children}
 		} else if token == NOT {
 			next, err := processToken(tokens, terminator, 1)
@@ -214,7 +240,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			}
 			node = Node{Token: token, Attributes: map[string]string{"width": children[0].Token}}
 		} else if token == COLOR {
-			attributes := map[string]string{"mathcolor": tokens()}
+			attributes := map[string]string{"mathcolor": tokens.next()}
 			children, _ := processToken(tokens, terminator, -1)
 			if len(children) > 0 && children[len(children)-1].Token == terminator {
 				sibling := children[len(children)-1]
@@ -226,14 +252,20 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			}
 			break
 		} else if token == STYLE {
-			attributes := map[string]string{"style": tokens()}
+			attributes := map[string]string{"style": tokens.next()}
 			next, _ := processToken(tokens, terminator, 1)
 			node = next[0]
 			node.Attributes = attributes
 		} else if slices.Contains(BOX_LIST, token) || containsKey(BIG, token) || containsKey(BIG_OPEN_CLOSE, token) {
-			node = Node{Token: token, Text: tokens()}
+			node = Node{Token: token, Text: tokens.next()}
+		} else if slices.Contains(TAG_LIST, token) {
+			text := tokens.next()
+			if text == "" {
+				return nodes, tokens.fail(start, "Missing argument")
+			}
+			node = Node{Token: token, Text: text}
 		} else if token == HREF {
-			attributes := map[string]string{"href": tokens()}
+			attributes := map[string]string{"href": tokens.next()}
 			children, _ := processToken(tokens, terminator, 1)
 			node = Node{Token: token, Children: children, Attributes: attributes}
 		} else if slices.Contains(OTHER_LIST, token) {
@@ -241,10 +273,10 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			var attributes = map[string]string{}
 
 			if token == ABOVEWITHDELIMS {
-				delimiter = strings.TrimLeft(tokens(), `\\`) + strings.TrimLeft(tokens(), `\\`)
+				delimiter = strings.TrimLeft(tokens.next(), `\\`) + strings.TrimLeft(tokens.next(), `\\`)
 			} else if token == ATOPWITHDELIMS {
 				attributes = map[string]string{"linethickness": "0"}
-				delimiter = strings.TrimLeft(tokens(), `\\`) + strings.TrimLeft(tokens(), `\\`)
+				delimiter = strings.TrimLeft(tokens.next(), `\\`) + strings.TrimLeft(tokens.next(), `\\`)
 			} else if token == BRACE {
 				delimiter = "{}"
 			} else if token == BRACK {
@@ -275,7 +307,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 				if token == BRACE || token == BRACK {
 					denominator = []Node{{Token: BRACES}}
 				} else {
-					return nodes, errors.New("Denominator not found")
+					return nodes, tokens.fail(start, "Denominator not found")
 				}
 			}
 
@@ -283,7 +315,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 				if token == BRACE || token == BRACK {
 					nodes = []Node{{Token: BRACES}}
 				} else {
-					return nodes, errors.New("Numerator not found")
+					return nodes, tokens.fail(start, "Numerator not found")
 				}
 
 			}
@@ -307,12 +339,20 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 
 		} else if token == SQRT {
 			next, _ := processToken(tokens, "", 1)
+			if countArguments(next, terminator) < 1 {
+				return nodes, tokens.fail(start, "Missing argument")
+			}
 			nextNode := next[0]
 			var rootNodes = []Node{}
 
 			if nextNode.Token == OPENING_BRACKET {
 				rootNodes, _ = processToken(tokens, CLOSING_BRACKET, -1)
 				rootNodes = rootNodes[:len(rootNodes)-1]
+				next, _ = processToken(tokens, "", 1)
+				if countArguments(next, terminator) < 1 {
+					return nodes, tokens.fail(start, "Missing argument")
+				}
+				nextNode = next[0]
 
 				if len(rootNodes) > 1 {
 					rootNodes = []Node{
@@ -366,7 +406,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			}
 
 		} else if token == GENFRAC {
-			delimiter := strings.TrimLeft(tokens(), `\\`) + strings.TrimLeft(tokens(), `\\`)
+			delimiter := strings.TrimLeft(tokens.next(), `\\`) + strings.TrimLeft(tokens.next(), `\\`)
 			next, _ := processToken(tokens, terminator, 2)
 			dimension := getDimension(next[0])
 			style, _ := getStyle(next[1])
@@ -424,7 +464,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 
 			if width == BRACES {
 				if len(next[0].Children) == 0 {
-					return nodes, errors.New("Invalid width")
+					return nodes, tokens.fail(start, "Invalid width")
 				}
 				width = next[0].Children[0].Token
 			}
@@ -432,7 +472,7 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			value, err := strconv.ParseInt(width, 10, 64)
 
 			if err != nil {
-				return nodes, errors.New("Invalid width")
+				return nodes, tokens.fail(start, "Invalid width")
 			}
 
 			node = Node{
@@ -442,8 +482,17 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 			}
 
 		} else if strings.HasPrefix(token, BEGIN) {
-			node, _ = getEnvinmentNode(token, tokens)
+			var err error
+			node, err = getEnvinmentNode(token, tokens)
+			if err != nil {
+				tokens.fail(start, err.Error())
+			}
 		} else {
+			if token == CLOSING_BRACE {
+				tokens.fail(start, "Unexpected closing brace")
+			} else if isUndefinedCommand(token) {
+				tokens.fail(start, "Undefined control sequence")
+			}
 			node = Node{Token: token}
 		}
 
@@ -461,6 +510,44 @@ func processToken(tokens func() string, terminator string, limit int) ([]Node, e
 	return nodes, nil
 }
 
+// countArguments 统计实际读取到的参数数量，读到结束符说明参数缺失
+func countArguments(children []Node, terminator string) int {
+	count := 0
+	for _, child := range children {
+		if child.Token == terminator || child.Token == CLOSING_BRACE && terminator == "" {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// isUndefinedCommand 判断记号是否为无法识别的命令，这类命令会原样输出
+func isUndefinedCommand(token string) bool {
+	if !strings.HasPrefix(token, BACKSLASH) || strings.HasPrefix(token, MATH) || strings.HasPrefix(token, OPERATORNAME) {
+		return false
+	}
+	for _, table := range []map[string]Style{CONVERSION_MAP, MSTYLE_SIZES, STYLES, BIG, BIG_OPEN_CLOSE} {
+		if containsKey(table, token) {
+			return false
+		}
+	}
+	if containsKey(LOCAL_FONTS, token) || containsKey(GLOBAL_FONTS, token) {
+		return false
+	}
+	for _, list := range [][]string{OPERATORS, SPACE_LIST, SUB_LIST, FUNCTIONS, BOX_LIST, POS_LIST, OTHER_LIST, MATRICES, EQUATION_LIST, TAG_LIST} {
+		if slices.Contains(list, token) {
+			return false
+		}
+	}
+	switch token {
+	case MOD, PMOD, NOT, IDOTSINT, LATEX, TEX, LIMITS, RIGHT, MIDDLE, DOUBLEBACKSLASH, CARRIAGE_RETURN, NONUMBER, NOTAG, CE, PU:
+		return false
+	}
+	_, err := ConvertSymbol(token)
+	return err != nil
+}
+
 func makeSupSub(node Node) (string, []Node, error) {
 	if node.Token != BRACES {
 		return "", []Node{}, errors.New("Token is not BRACES `" + BRACES + "`")
@@ -501,14 +588,14 @@ func getStyle(node Node) (string, error) {
 	return "", errors.New("Invalid style for node")
 }
 
-func getEnvinmentNode(token string, tokens func() string) (Node, error) {
+func getEnvinmentNode(token string, tokens *tokenIterator) (Node, error) {
 	startIndex := strings.Index(token, "{") + 1
 	environment := token[startIndex : len(token)-1]
 	terminator := END + "{" + environment + "}"
 	children, _ := processToken(tokens, terminator, 0)
 
-	if len(children) > 0 && children[len(children)-1].Token != terminator {
-		return Node{}, errors.New("Missing end in tokens")
+	if len(children) == 0 || children[len(children)-1].Token != terminator {
+		return Node{}, errors.New("Missing " + terminator)
 	}
 
 	children = children[:len(children)-1]
@@ -0,0 +1,165 @@
+package pptx
+
+import (
+	"path"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	// NsContentTypes [Content_Types].xml 的命名空间
+	NsContentTypes = "http://schemas.openxmlformats.org/package/2006/content-types"
+
+	// 常用的内容类型
+	ContentTypeRelationships = "application/vnd.openxmlformats-package.relationships+xml"
+	ContentTypeXML           = "application/xml"
+	ContentTypeSlide         = "application/vnd.openxmlformats-officedocument.presentationml.slide+xml"
+	ContentTypeSlideLayout   = "application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"
+	ContentTypeSlideMaster   = "application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"
+)
+
+// contentTypesPath [Content_Types].xml 在包中的路径
+const contentTypesPath = "[Content_Types].xml"
+
+// contentTypeEntry 表示一条 Default 或 Override 声明
+type contentTypeEntry struct {
+	key         string // Default 为扩展名，Override 为部件名（不带前导 /）
+	contentType string
+}
+
+// ContentTypes 管理 [Content_Types].xml 中的 Default 和 Override 声明
+// 部件名和扩展名的比较不区分大小写，声明按添加顺序保存
+type ContentTypes struct {
+	defaults  []*contentTypeEntry
+	overrides []*contentTypeEntry
+	dirty     bool // 解析后是否被修改过
+}
+
+// NewContentTypes 创建只包含 rels 和 xml 默认声明的内容类型集合
+func NewContentTypes() *ContentTypes {
+	ct := &ContentTypes{}
+	ct.SetDefault("rels", ContentTypeRelationships)
+	ct.SetDefault("xml", ContentTypeXML)
+	return ct
+}
+
+// parseContentTypes 解析 [Content_Types].xml 文件内容
+func parseContentTypes(data []byte) (*ContentTypes, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	ct := &ContentTypes{}
+	for _, el := range doc.FindElements("//Default") {
+		ct.SetDefault(el.SelectAttrValue("Extension", ""), el.SelectAttrValue("ContentType", ""))
+	}
+	for _, el := range doc.FindElements("//Override") {
+		ct.SetOverride(el.SelectAttrValue("PartName", ""), el.SelectAttrValue("ContentType", ""))
+	}
+	ct.dirty = false
+	return ct, nil
+}
+
+// Default 获取扩展名对应的默认内容类型，不存在时返回空字符串
+func (ct *ContentTypes) Default(ext string) string {
+	if entry := findContentTypeEntry(ct.defaults, normalizeExt(ext)); entry != nil {
+		return entry.contentType
+	}
+	return ""
+}
+
+// SetDefault 设置扩展名对应的默认内容类型
+func (ct *ContentTypes) SetDefault(ext, contentType string) {
+	if ct.Default(ext) != contentType {
+		ct.defaults = setContentTypeEntry(ct.defaults, normalizeExt(ext), contentType)
+		ct.dirty = true
+	}
+}
+
+// Override 获取部件单独声明的内容类型，不存在时返回空字符串
+func (ct *ContentTypes) Override(partName string) string {
+	if entry := findContentTypeEntry(ct.overrides, normalizePartName(partName)); entry != nil {
+		return entry.contentType
+	}
+	return ""
+}
+
+// SetOverride 为部件单独声明内容类型
+func (ct *ContentTypes) SetOverride(partName, contentType string) {
+	if ct.Override(partName) != contentType {
+		ct.overrides = setContentTypeEntry(ct.overrides, normalizePartName(partName), contentType)
+		ct.dirty = true
+	}
+}
+
+// RemoveOverride 删除部件的单独声明，返回是否删除成功
+func (ct *ContentTypes) RemoveOverride(partName string) bool {
+	key := normalizePartName(partName)
+	for i, entry := range ct.overrides {
+		if strings.EqualFold(entry.key, key) {
+			ct.overrides = append(ct.overrides[:i], ct.overrides[i+1:]...)
+			ct.dirty = true
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType 获取部件实际生效的内容类型，Override 优先于按扩展名的 Default
+func (ct *ContentTypes) ContentType(partName string) string {
+	if contentType := ct.Override(partName); contentType != "" {
+		return contentType
+	}
+	return ct.Default(path.Ext(partName))
+}
+
+// toXML 序列化为 [Content_Types].xml 文件内容
+func (ct *ContentTypes) toXML() ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8" standalone="yes"`)
+	types := doc.CreateElement("Types")
+	types.CreateAttr("xmlns", NsContentTypes)
+
+	for _, entry := range ct.defaults {
+		el := types.CreateElement("Default")
+		el.CreateAttr("Extension", entry.key)
+		el.CreateAttr("ContentType", entry.contentType)
+	}
+	for _, entry := range ct.overrides {
+		el := types.CreateElement("Override")
+		el.CreateAttr("PartName", "/"+entry.key)
+		el.CreateAttr("ContentType", entry.contentType)
+	}
+	return doc.WriteToBytes()
+}
+
+// findContentTypeEntry 不区分大小写地查找声明
+func findContentTypeEntry(entries []*contentTypeEntry, key string) *contentTypeEntry {
+	for _, entry := range entries {
+		if strings.EqualFold(entry.key, key) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// setContentTypeEntry 更新已有声明，不存在时追加
+func setContentTypeEntry(entries []*contentTypeEntry, key, contentType string) []*contentTypeEntry {
+	if entry := findContentTypeEntry(entries, key); entry != nil {
+		entry.contentType = contentType
+		return entries
+	}
+	return append(entries, &contentTypeEntry{key: key, contentType: contentType})
+}
+
+// normalizeExt 去掉扩展名前的点号
+func normalizeExt(ext string) string {
+	return strings.TrimPrefix(ext, ".")
+}
+
+// normalizePartName 去掉部件名的前导 /，与包内路径保持一致
+func normalizePartName(partName string) string {
+	return strings.TrimPrefix(partName, "/")
+}
@@ -0,0 +1,273 @@
+package pptx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/beevik/etree"
+)
+
+// NsWordML WordprocessingML 的命名空间，从 Word 复制的 OMML 中的 w: 元素在演示文稿中无效
+const NsWordML = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// ommlElements ECMA-376 中定义的 OMML 元素
+var ommlElements = map[string]bool{
+	"acc": true, "accPr": true, "aln": true, "alnScr": true, "argPr": true, "argSz": true,
+	"bar": true, "barPr": true, "baseJc": true, "begChr": true, "borderBox": true, "borderBoxPr": true,
+	"box": true, "boxPr": true, "brk": true, "brkBin": true, "brkBinSub": true, "cGp": true,
+	"cGpRule": true, "chr": true, "count": true, "cSp": true, "ctrlPr": true, "d": true,
+	"defJc": true, "deg": true, "degHide": true, "den": true, "diff": true, "dispDef": true,
+	"dPr": true, "e": true, "endChr": true, "eqArr": true, "eqArrPr": true, "f": true,
+	"fName": true, "fPr": true, "func": true, "funcPr": true, "groupChr": true, "groupChrPr": true,
+	"grow": true, "hideBot": true, "hideLeft": true, "hideRight": true, "hideTop": true, "interSp": true,
+	"intLim": true, "intraSp": true, "jc": true, "lim": true, "limLoc": true, "limLow": true,
+	"limLowPr": true, "limUpp": true, "limUppPr": true, "lit": true, "lMargin": true, "m": true,
+	"mathFont": true, "mathPr": true, "maxDist": true, "mc": true, "mcJc": true, "mcPr": true,
+	"mcs": true, "mPr": true, "mr": true, "nary": true, "naryLim": true, "naryPr": true,
+	"noBreak": true, "nor": true, "num": true, "objDist": true, "oMath": true, "oMathPara": true,
+	"oMathParaPr": true, "opEmu": true, "phant": true, "phantPr": true, "plcHide": true, "pos": true,
+	"postSp": true, "preSp": true, "r": true, "rad": true, "radPr": true, "rMargin": true,
+	"rPr": true, "rSp": true, "rSpRule": true, "scr": true, "sepChr": true, "show": true,
+	"shp": true, "smallFrac": true, "sPre": true, "sPrePr": true, "sSub": true, "sSubPr": true,
+	"sSubSup": true, "sSubSupPr": true, "sSup": true, "sSupPr": true, "strikeBLTR": true, "strikeH": true,
+	"strikeTLBR": true, "strikeV": true, "sty": true, "sub": true, "subHide": true, "sup": true,
+	"supHide": true, "t": true, "transp": true, "type": true, "vertJc": true, "wrapIndent": true,
+	"wrapRight": true, "zeroAsc": true, "zeroDesc": true, "zeroWid": true,
+}
+
+// ommlRequired 结构元素必须包含的子元素，与 ECMA-376 的定义一致
+var ommlRequired = map[string][]string{
+	"acc": {"e"}, "bar": {"e"}, "borderBox": {"e"}, "box": {"e"}, "d": {"e"}, "eqArr": {"e"},
+	"f": {"num", "den"}, "func": {"fName", "e"}, "groupChr": {"e"}, "limLow": {"e", "lim"}, "limUpp": {"e", "lim"},
+	"m": {"mr"}, "mr": {"e"}, "nary": {"sub", "sup", "e"}, "phant": {"e"}, "rad": {"deg", "e"},
+	"sPre": {"sub", "sup", "e"}, "sSub": {"e", "sub"}, "sSubSup": {"e", "sub", "sup"}, "sSup": {"e", "sup"},
+}
+
+// Equation 表示直接添加到占位符中的公式，MathML 和 OMML 只能设置一个
+type Equation struct {
+	MathML        string            // MathML 格式的公式，根元素为 math
+	OMML          string            // OMML 格式的公式（如从 Word 复制），根元素为 m:oMath 或 m:oMathPara
+	Display       bool              // 是否为独占一段的行间公式，display="block" 的 MathML 和 m:oMathPara 总是行间公式
+	Justification MathJustification // 行间公式的对齐方式，默认居中
+}
+
+// LatexToMathML 将 LaTeX 公式转换为 MathML，display 为 true 时生成行间公式
+// options 中的宏、导言区和公式编号选项同样有效，语法错误时返回的错误满足 errors.Is(err, ErrLatexSyntax)
+func LatexToMathML(latex string, display bool, options ...TextOption) (string, error) {
+	opts := &TextOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	converter, err := opts.latexConverter()
+	if err != nil {
+		return "", err
+	}
+	mathml, err := convertLatexToMathML(converter, latex, display, nil)
+	if err != nil {
+		return "", &LatexError{Latex: latex, Err: err}
+	}
+	return mathml, nil
+}
+
+// MathMLToOMML 将 MathML 转换为 m:oMath 元素，无法解析时返回的错误满足 errors.Is(err, ErrInvalidEquation)
+func MathMLToOMML(mathml string) (*etree.Element, error) {
+	oMath, err := convertMathMLToOMML(mathml)
+	if err != nil {
+		return nil, &EquationError{Format: "MathML", Err: err}
+	}
+	return oMath, nil
+}
+
+// AddEquation 在占位符文本的末尾添加公式，行内公式添加到最后一个段落中，行间公式独占一段
+// MathML 转换为 OMML 后添加，OMML 先检查其中的元素，Word 特有的 w: 元素会被去掉
+func (p *Placeholder) AddEquation(eq Equation) error {
+	if p.Shape == nil {
+		return fmt.Errorf("shape element is nil")
+	}
+	if err := p.checkAccepts(ContentText); err != nil {
+		return err
+	}
+
+	math, display, err := eq.toOMML()
+	if err != nil {
+		return err
+	}
+
+	txBody := p.Shape.FindElement("p:txBody")
+	if txBody == nil {
+		txBody = p.Shape.CreateElement("p:txBody")
+	}
+	var para *etree.Element
+	if paras := txBody.SelectElements("a:p"); len(paras) > 0 {
+		para = paras[len(paras)-1]
+	}
+
+	if display {
+		if para == nil || !isEmptyParagraph(para) {
+			para = txBody.CreateElement("a:p")
+		}
+		pPr := para.SelectElement("a:pPr")
+		if pPr == nil {
+			pPr = etree.NewElement("a:pPr")
+			para.InsertChildAt(0, pPr)
+		}
+		pPr.CreateAttr("algn", eq.Justification.paragraphAlign())
+		if math.Tag == "oMath" {
+			math = newMathPara(math, eq.Justification)
+		}
+	} else if para == nil || hasDisplayMath(para) {
+		para = txBody.CreateElement("a:p")
+	}
+	appendParagraphContent(para, newMathContainer(math))
+
+	return p.slide.SaveChanges()
+}
+
+// toOMML 将公式转换为 m:oMath 或 m:oMathPara 元素，并返回是否为行间公式
+func (eq Equation) toOMML() (*etree.Element, bool, error) {
+	switch {
+	case eq.MathML != "" && eq.OMML != "":
+		return nil, false, errors.New("equation must have either MathML or OMML, not both")
+	case eq.MathML != "":
+		display := eq.Display || isBlockMathML(eq.MathML)
+		oMath, err := MathMLToOMML(eq.MathML)
+		return oMath, display, err
+	case eq.OMML != "":
+		math, err := parseOMML(eq.OMML)
+		if err != nil {
+			return nil, false, &EquationError{Format: "OMML", Err: err}
+		}
+		return math, eq.Display || math.Tag == "oMathPara", nil
+	default:
+		return nil, false, errors.New("equation is empty")
+	}
+}
+
+// isBlockMathML 判断 MathML 的根元素是否为行间公式
+func isBlockMathML(mathml string) bool {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(mathml); err != nil || doc.Root() == nil {
+		return false
+	}
+	root := doc.Root()
+	return root.SelectAttrValue("display", "") == "block" || root.SelectAttrValue("mode", "") == "display"
+}
+
+// parseOMML 解析并检查 OMML，返回可以直接放入 a14:m 的 m:oMath 或 m:oMathPara 元素
+func parseOMML(omml string) (*etree.Element, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(omml); err != nil {
+		return nil, fmt.Errorf("failed to parse OMML: %w", err)
+	}
+	root := doc.Root()
+	if root == nil {
+		return nil, errors.New("no elements found in OMML")
+	}
+	if !isMathElement(root) || (root.Tag != "oMath" && root.Tag != "oMathPara") {
+		return nil, fmt.Errorf("OMML root element must be m:oMath or m:oMathPara, got %s", root.FullTag())
+	}
+	if err := validateOMML(root); err != nil {
+		return nil, err
+	}
+
+	math := root.Copy()
+	normalizeOMMLPrefix(math)
+	return math, nil
+}
+
+// normalizeOMMLPrefix 将 OMML 元素的前缀统一为 m:（如其他工具生成的 mml:），只在根元素上声明 OMML 命名空间
+// 公式的查找（如 hasDisplayMath）使用 m: 前缀
+func normalizeOMMLPrefix(math *etree.Element) {
+	elements := append([]*etree.Element{math}, math.FindElements(".//*")...)
+	var mathElements []*etree.Element
+	for _, el := range elements {
+		if isMathElement(el) {
+			mathElements = append(mathElements, el)
+		}
+	}
+	for _, el := range mathElements {
+		el.Space = "m"
+	}
+	for _, el := range elements {
+		for i := len(el.Attr) - 1; i >= 0; i-- {
+			attr := el.Attr[i]
+			if attr.Space == "xmlns" && (attr.Key == "m" || attr.Value == NsMath) || attr.Space == "" && attr.Key == "xmlns" && attr.Value == NsMath {
+				el.RemoveAttr(attr.FullKey())
+			}
+		}
+	}
+	math.CreateAttr("xmlns:m", NsMath)
+}
+
+// validateOMML 检查元素是否都是 OMML 元素，以及结构元素（如 m:f）是否包含必需的子元素（如 m:num 和 m:den）
+// m:r 中可以有 a:rPr，Word 的 w:rPr 等元素直接去掉
+func validateOMML(el *etree.Element) error {
+	for _, child := range el.ChildElements() {
+		switch {
+		case isMathElement(child):
+			if !ommlElements[child.Tag] {
+				return fmt.Errorf("unknown OMML element %s", child.FullTag())
+			}
+			if err := validateOMML(child); err != nil {
+				return err
+			}
+		case child.Space == "w" || child.NamespaceURI() == NsWordML:
+			el.RemoveChild(child)
+		case child.Tag == "rPr" && el.Tag == "r" && (child.Space == "a" || child.NamespaceURI() == NsDrawingML):
+			// 文字格式，不检查其中的内容
+		default:
+			return fmt.Errorf("unexpected element %s in %s", child.FullTag(), el.FullTag())
+		}
+	}
+	for _, name := range ommlRequired[el.Tag] {
+		if !hasMathChild(el, name) {
+			return fmt.Errorf("OMML element %s is missing m:%s", el.FullTag(), name)
+		}
+	}
+	return nil
+}
+
+// hasMathChild 判断元素是否有名为 tag 的 OMML 子元素
+func hasMathChild(el *etree.Element, tag string) bool {
+	for _, child := range el.ChildElements() {
+		if child.Tag == tag && isMathElement(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMathElement 判断元素是否属于 OMML 命名空间，没有声明命名空间的 m: 前缀也视为 OMML
+func isMathElement(el *etree.Element) bool {
+	uri := el.NamespaceURI()
+	return uri == NsMath || (uri == "" && el.Space == "m")
+}
+
+// isEmptyParagraph 判断段落中是否没有文本、换行和公式
+func isEmptyParagraph(para *etree.Element) bool {
+	for _, child := range para.ChildElements() {
+		if child.Tag != "pPr" && child.Tag != "endParaRPr" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDisplayMath 判断段落中是否有行间公式
+func hasDisplayMath(para *etree.Element) bool {
+	for _, container := range para.SelectElements("a14:m") {
+		if container.SelectElement("m:oMathPara") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// appendParagraphContent 将元素添加到段落末尾，a:endParaRPr 必须是段落的最后一个元素
+func appendParagraphContent(para *etree.Element, child *etree.Element) {
+	if end := para.SelectElement("a:endParaRPr"); end != nil {
+		para.InsertChildAt(end.Index(), child)
+		return
+	}
+	para.AddChild(child)
+}
@@ -0,0 +1,78 @@
+package pptx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func TestParseOMML(t *testing.T) {
+	const ns = `xmlns:m="` + NsMath + `"`
+	tests := []struct {
+		name    string
+		omml    string
+		want    string
+		wantErr string
+	}{
+		{"fraction", `<m:oMath ` + ns + `><m:f><m:num><m:r><m:t>1</m:t></m:r></m:num><m:den><m:r><m:t>2</m:t></m:r></m:den></m:f></m:oMath>`, `<m:f><m:num>`, ""},
+		{"missing denominator", `<m:oMath ` + ns + `><m:f><m:num><m:r><m:t>1</m:t></m:r></m:num></m:f></m:oMath>`, "", "missing m:den"},
+		{"missing base", `<m:oMath ` + ns + `><m:sSup><m:sup><m:r><m:t>2</m:t></m:r></m:sup></m:sSup></m:oMath>`, "", "missing m:e"},
+		{"matrix without rows", `<m:oMath ` + ns + `><m:m/></m:oMath>`, "", "missing m:mr"},
+		{"unknown element", `<m:oMath ` + ns + `><m:foo/></m:oMath>`, "", "unknown OMML element"},
+		{"word run properties removed", `<m:oMath ` + ns + ` xmlns:w="` + NsWordML + `"><m:r><w:rPr/><m:t>x</m:t></m:r></m:oMath>`, `<m:r><m:t>x</m:t></m:r>`, ""},
+		{"foreign prefix", `<mml:oMathPara xmlns:mml="` + NsMath + `"><mml:oMath><mml:r><mml:t>x</mml:t></mml:r></mml:oMath></mml:oMathPara>`, `<m:oMathPara xmlns:m="` + NsMath + `"><m:oMath><m:r><m:t>x</m:t></m:r></m:oMath></m:oMathPara>`, ""},
+		{"default namespace", `<oMath xmlns="` + NsMath + `"><r><t>x</t></r></oMath>`, `<m:oMath xmlns:m="` + NsMath + `"><m:r><m:t>x</m:t></m:r></m:oMath>`, ""},
+		{"undeclared m prefix", `<m:oMath><m:r><m:t>x</m:t></m:r></m:oMath>`, `<m:oMath xmlns:m="` + NsMath + `">`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			math, err := parseOMML(tt.omml)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseOMML() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := etree.NewDocument()
+			doc.AddChild(math)
+			xml, _ := doc.WriteToString()
+			if !strings.Contains(xml, tt.want) {
+				t.Errorf("parseOMML() = %s, want it to contain %s", xml, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddEquationForeignPrefix(t *testing.T) {
+	slide := newTestSlide(t, testShape(2, "Content 1", "body", 1, testRun("Hello")))
+	placeholder, err := slide.GetPlaceholder(PlaceholderBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = placeholder.AddEquation(Equation{OMML: `<mml:oMathPara xmlns:mml="` + NsMath + `"><mml:oMath><mml:r><mml:t>x</mml:t></mml:r></mml:oMath></mml:oMathPara>`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paras := placeholder.Shape.FindElements("p:txBody/a:p")
+	if len(paras) != 2 || !hasDisplayMath(paras[1]) {
+		t.Fatalf("display equation not found in its own paragraph")
+	}
+	// 行内公式不能放在行间公式所在的段落中
+	if err := placeholder.AddEquation(Equation{MathML: `<math><mi>y</mi></math>`}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(placeholder.Shape.FindElements("p:txBody/a:p")); n != 3 {
+		t.Errorf("paragraphs = %d, want 3", n)
+	}
+
+	err = placeholder.AddEquation(Equation{OMML: `<m:oMath xmlns:m="` + NsMath + `"><m:f/></m:oMath>`})
+	if !errors.Is(err, ErrInvalidEquation) {
+		t.Errorf("AddEquation() error = %v, want ErrInvalidEquation", err)
+	}
+}
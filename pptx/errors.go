@@ -0,0 +1,133 @@
+package pptx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wanglihui/pptx-go/latex2mathml"
+)
+
+// 可以通过 errors.Is 判断的错误
+var (
+	ErrPlaceholderNotFound = errors.New("placeholder not found")
+	ErrLayoutNotFound      = errors.New("layout not found")
+	ErrInvalidSlideIndex   = errors.New("invalid slide index")
+	ErrPartMissing         = errors.New("part missing")
+	ErrLatexSyntax         = errors.New("latex syntax error")
+	ErrInvalidEquation     = errors.New("invalid equation")
+)
+
+// PlaceholderError 表示在幻灯片中找不到匹配的占位符
+type PlaceholderError struct {
+	SlideIndex int    // 幻灯片索引，幻灯片已不在演示文稿中时为 -1
+	SlidePath  string // 幻灯片的部件路径
+	Selector   string // 查找条件的描述
+}
+
+// Error 实现 error 接口
+func (e *PlaceholderError) Error() string {
+	return fmt.Sprintf("placeholder not found on slide %d (%s): %s", e.SlideIndex, e.SlidePath, e.Selector)
+}
+
+// Unwrap 返回 ErrPlaceholderNotFound
+func (e *PlaceholderError) Unwrap() error {
+	return ErrPlaceholderNotFound
+}
+
+// LayoutError 表示找不到指定名称的布局
+type LayoutError struct {
+	Name      string   // 查找的布局名称
+	Available []string // 演示文稿中所有可用的布局名称
+}
+
+// Error 实现 error 接口
+func (e *LayoutError) Error() string {
+	return fmt.Sprintf("layout not found: %s (available: %s)", e.Name, strings.Join(e.Available, ", "))
+}
+
+// Unwrap 返回 ErrLayoutNotFound
+func (e *LayoutError) Unwrap() error {
+	return ErrLayoutNotFound
+}
+
+// SlideIndexError 表示幻灯片索引超出范围
+type SlideIndexError struct {
+	Index int // 传入的索引
+	Count int // 幻灯片数量
+}
+
+// Error 实现 error 接口
+func (e *SlideIndexError) Error() string {
+	return fmt.Sprintf("invalid slide index: %d (slide count: %d)", e.Index, e.Count)
+}
+
+// Unwrap 返回 ErrInvalidSlideIndex
+func (e *SlideIndexError) Unwrap() error {
+	return ErrInvalidSlideIndex
+}
+
+// PartError 表示读取包中的部件失败
+type PartError struct {
+	Path string // 部件路径
+	Err  error  // 部件不存在时为 ErrPartMissing
+}
+
+// Error 实现 error 接口
+func (e *PartError) Error() string {
+	if e.Err == ErrPartMissing {
+		return "part missing: " + e.Path
+	}
+	return fmt.Sprintf("failed to read part %s: %v", e.Path, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *PartError) Unwrap() error {
+	return e.Err
+}
+
+// LatexError 表示 LaTeX 公式无法转换为 MathML
+type LatexError struct {
+	Latex string // 出错的公式
+	Err   error  // 底层错误，语法错误时为 *latex2mathml.SyntaxError
+}
+
+// Error 实现 error 接口
+func (e *LatexError) Error() string {
+	if errors.Is(e, ErrLatexSyntax) {
+		return fmt.Sprintf("latex syntax error in %q: %v", e.Latex, e.Err)
+	}
+	return fmt.Sprintf("failed to convert latex %q: %v", e.Latex, e.Err)
+}
+
+// Is 只在底层错误为语法错误时使 errors.Is(err, ErrLatexSyntax) 成立
+func (e *LatexError) Is(target error) bool {
+	var syntaxErr *latex2mathml.SyntaxError
+	return target == ErrLatexSyntax && errors.As(e.Err, &syntaxErr)
+}
+
+// Unwrap 返回底层错误
+func (e *LatexError) Unwrap() error {
+	return e.Err
+}
+
+// EquationError 表示 MathML 或 OMML 公式无法解析或转换
+type EquationError struct {
+	Format string // 公式的格式，"MathML" 或 "OMML"
+	Err    error  // 底层错误
+}
+
+// Error 实现 error 接口
+func (e *EquationError) Error() string {
+	return fmt.Sprintf("invalid %s equation: %v", e.Format, e.Err)
+}
+
+// Is 使 errors.Is(err, ErrInvalidEquation) 成立
+func (e *EquationError) Is(target error) bool {
+	return target == ErrInvalidEquation
+}
+
+// Unwrap 返回底层错误
+func (e *EquationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,61 @@
+package pptx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.GetSlide(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, layoutErr := pres.AddSlide("不存在的布局")
+	_, indexErr := pres.GetSlide(99)
+	deleteErr := pres.DeleteSlide(-1)
+	_, placeholderErr := slide.GetPlaceholder("不存在的占位符")
+	_, partErr := pres.pkg.readPart("ppt/missing.xml")
+
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   string
+	}{
+		{"layout", layoutErr, ErrLayoutNotFound, "仅标题"},
+		{"slide index", indexErr, ErrInvalidSlideIndex, "slide count: 1"},
+		{"delete slide index", deleteErr, ErrInvalidSlideIndex, "-1"},
+		{"placeholder", placeholderErr, ErrPlaceholderNotFound, "不存在的占位符"},
+		{"part", partErr, ErrPartMissing, "ppt/missing.xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.target) {
+				t.Fatalf("error = %v, want errors.Is(err, %v)", tt.err, tt.target)
+			}
+			if !strings.Contains(tt.err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %s", tt.err, tt.want)
+			}
+		})
+	}
+
+	var layout *LayoutError
+	if !errors.As(layoutErr, &layout) || layout.Name != "不存在的布局" || len(layout.Available) != 11 {
+		t.Errorf("LayoutError = %+v, want the name and all 11 layouts", layout)
+	}
+	var index *SlideIndexError
+	if !errors.As(indexErr, &index) || index.Index != 99 || index.Count != 1 {
+		t.Errorf("SlideIndexError = %+v, want index 99 of 1", index)
+	}
+	var placeholder *PlaceholderError
+	if !errors.As(placeholderErr, &placeholder) || placeholder.SlideIndex != 0 || placeholder.SlidePath != slide.path {
+		t.Errorf("PlaceholderError = %+v, want slide 0 at %s", placeholder, slide.path)
+	}
+	var part *PartError
+	if !errors.As(partErr, &part) || part.Path != "ppt/missing.xml" {
+		t.Errorf("PartError = %+v, want ppt/missing.xml", part)
+	}
+}
@@ -0,0 +1,156 @@
+package pptx
+
+import (
+	"fmt"
+
+	"github.com/beevik/etree"
+)
+
+// SlideJump 定义放映时的命名跳转动作
+type SlideJump string
+
+const (
+	JumpNone          SlideJump = ""
+	JumpNextSlide     SlideJump = "nextslide"
+	JumpPreviousSlide SlideJump = "previousslide"
+	JumpFirstSlide    SlideJump = "firstslide"
+	JumpLastSlide     SlideJump = "lastslide"
+	JumpEndShow       SlideJump = "endshow"
+)
+
+const (
+	actionSlideJump = "ppaction://hlinksldjump"
+	actionShowJump  = "ppaction://hlinkshowjump?jump="
+)
+
+// Action 定义点击或悬停时触发的动作
+// URL、Slide、Jump 三者只需设置其中一个，优先级为 Jump > Slide > URL
+type Action struct {
+	URL     string    // 外部链接地址
+	Slide   *Slide    // 跳转的目标幻灯片，必须属于同一个演示文稿
+	Jump    SlideJump // 命名跳转，如下一张、结束放映
+	Tooltip string    // 鼠标悬停时显示的提示
+}
+
+// SetClickAction 设置单击占位符形状时触发的动作
+func (p *Placeholder) SetClickAction(action Action) error {
+	return p.setShapeAction("hlinkClick", action)
+}
+
+// SetHoverAction 设置鼠标悬停在占位符形状上时触发的动作
+func (p *Placeholder) SetHoverAction(action Action) error {
+	return p.setShapeAction("hlinkHover", action)
+}
+
+// setShapeAction 在形状的 p:cNvPr 上写入 a:hlinkClick 或 a:hlinkHover
+func (p *Placeholder) setShapeAction(tag string, action Action) error {
+	if p.Shape == nil || p.slide == nil {
+		return fmt.Errorf("placeholder is not attached to a slide")
+	}
+
+	var cNvPr *etree.Element
+	for _, nv := range []string{"nvSpPr", "nvPicPr", "nvGraphicFramePr", "nvGrpSpPr", "nvCxnSpPr"} {
+		if cNvPr = p.Shape.FindElement(nv + "/cNvPr"); cNvPr != nil {
+			break
+		}
+	}
+	if cNvPr == nil {
+		return fmt.Errorf("cNvPr not found in placeholder shape")
+	}
+
+	link, err := p.slide.newHyperlink("a:"+tag, action)
+	if err != nil {
+		return err
+	}
+
+	// 移除已有的同类动作，不再被引用的关系一并删除
+	if existing := cNvPr.SelectElement(tag); existing != nil {
+		rId := relationshipAttrValue(existing, "id")
+		cNvPr.RemoveChild(existing)
+		p.slide.releaseRelationship(rId)
+	}
+
+	// 子元素顺序为 hlinkClick、hlinkHover、extLst
+	index := 0
+	if tag == "hlinkHover" {
+		if click := cNvPr.SelectElement("hlinkClick"); click != nil {
+			index = click.Index() + 1
+		}
+	}
+	cNvPr.InsertChildAt(index, link)
+
+	return p.slide.SaveChanges()
+}
+
+// newHyperlink 创建超链接元素并在幻灯片中添加所需的关系
+func (s *Slide) newHyperlink(tag string, action Action) (*etree.Element, error) {
+	link := etree.NewElement(tag)
+
+	switch {
+	case action.Jump != JumpNone:
+		// 命名跳转不需要关系，但 r:id 属性必须存在
+		link.CreateAttr("r:id", "")
+		link.CreateAttr("action", actionShowJump+string(action.Jump))
+	case action.Slide != nil:
+		if action.Slide.pres != s.pres {
+			return nil, fmt.Errorf("target slide belongs to another presentation")
+		}
+		rId := s.rels.Add(RelTypeSlide, relativePartPath(s.path, action.Slide.path), "")
+		link.CreateAttr("r:id", rId)
+		link.CreateAttr("action", actionSlideJump)
+	case action.URL != "":
+		rId := s.rels.Add(RelTypeHyperlink, action.URL, "External")
+		link.CreateAttr("r:id", rId)
+	default:
+		return nil, fmt.Errorf("action has no target")
+	}
+
+	if action.Tooltip != "" {
+		link.CreateAttr("tooltip", action.Tooltip)
+	}
+	return link, nil
+}
+
+// releaseRelationship 删除幻灯片 XML 中已不再引用的关系，rId 为空或仍被其他元素引用时不做处理
+func (s *Slide) releaseRelationship(rId string) {
+	if rId == "" || s.relationshipInUse(rId) {
+		return
+	}
+	s.rels.Remove(rId)
+}
+
+// relationshipInUse 判断幻灯片 XML 中是否有元素引用关系 rId
+func (s *Slide) relationshipInUse(rId string) bool {
+	if s.xml == nil {
+		return false
+	}
+	for _, el := range s.xml.FindElements("//*") {
+		for i := range el.Attr {
+			if el.Attr[i].Value == rId && isRelationshipAttr(&el.Attr[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeLinksTo 删除幻灯片中所有跳转到 target 的链接及其关系
+func (s *Slide) removeLinksTo(target *Slide) {
+	relative := relativePartPath(s.path, target.path)
+	for {
+		rel := s.rels.FindByTarget(RelTypeSlide, relative)
+		if rel == nil {
+			return
+		}
+		s.rels.Remove(rel.Id)
+		if s.xml == nil {
+			continue
+		}
+		for _, link := range s.xml.FindElements("//*[@r:id='" + rel.Id + "']") {
+			if link.Tag == "hlinkClick" || link.Tag == "hlinkHover" {
+				link.Parent().RemoveChild(link)
+				s.dirty = true
+			}
+		}
+	}
+}
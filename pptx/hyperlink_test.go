@@ -0,0 +1,98 @@
+package pptx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetClickActionReleasesRelationship(t *testing.T) {
+	slide := newTestSlide(t, testShape(2, "Title 1", "title", 0, testRun("Hello")))
+	placeholder, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range []string{"https://example.com/a", "https://example.com/b"} {
+		if err := placeholder.SetClickAction(Action{URL: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rels := slide.rels.All()
+	if len(rels) != 1 || rels[0].Target != "https://example.com/b" {
+		t.Fatalf("relationships after replacing the action = %+v, want only the new hyperlink", rels)
+	}
+
+	if err := placeholder.SetClickAction(Action{Jump: JumpNextSlide}); err != nil {
+		t.Fatal(err)
+	}
+	if n := slide.rels.Len(); n != 0 {
+		t.Errorf("relationships after switching to a named jump = %d, want 0", n)
+	}
+}
+
+func TestSetClickActionKeepsSharedRelationship(t *testing.T) {
+	slide := newTestSlide(t,
+		`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Title 1"><a:hlinkClick r:id="rId3"/></p:cNvPr><p:cNvSpPr/><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr>`+
+			`<p:spPr/><p:txBody><a:bodyPr/><a:p><a:r><a:rPr><a:hlinkClick r:id="rId3"/></a:rPr><a:t>Hello</a:t></a:r></a:p></p:txBody></p:sp>`)
+	slide.rels.add(&Relationship{Id: "rId3", Type: RelTypeHyperlink, Target: "https://example.com", TargetMode: "External"})
+	placeholder, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := placeholder.SetHoverAction(Action{URL: "https://example.com/hover"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := placeholder.SetClickAction(Action{URL: "https://example.com/click"}); err != nil {
+		t.Fatal(err)
+	}
+	if slide.rels.Get("rId3") == nil {
+		t.Error("relationship still used by a text run was removed")
+	}
+	if n := slide.rels.Len(); n != 3 {
+		t.Errorf("relationships = %d, want 3", n)
+	}
+}
+
+func TestSetTextLinkRelationships(t *testing.T) {
+	slide := newTestSlide(t, testShape(2, "Title 1", "title", 0, testRun("Hello")))
+	placeholder, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := placeholder.SetText("old", WithLink("https://example.com/old", LinkTypeExternal)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := placeholder.SetTextContext(ctx, "see $x$", WithLatex(), WithLink("https://example.com/new", LinkTypeExternal)); err == nil {
+		t.Fatal("SetTextContext with a cancelled context returned no error")
+	}
+	rels := slide.rels.All()
+	if len(rels) != 1 || rels[0].Target != "https://example.com/old" {
+		t.Fatalf("relationships after a failed SetTextContext = %+v, want only the old hyperlink", rels)
+	}
+
+	if err := placeholder.SetText("new", WithLink("https://example.com/new", LinkTypeExternal)); err != nil {
+		t.Fatal(err)
+	}
+	rels = slide.rels.All()
+	if len(rels) != 1 || rels[0].Target != "https://example.com/new" || rels[0].Id != "rId2" {
+		t.Errorf("relationships after replacing the text = %+v, want only the new hyperlink as rId2", rels)
+	}
+}
+
+func TestSetTextSlideLinkWithoutPresentation(t *testing.T) {
+	slide := newTestSlide(t, testShape(2, "Title 1", "title", 0, testRun("Hello")))
+	placeholder, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := placeholder.SetText("next", WithLink("slide2.xml", LinkTypeSlide)); err == nil {
+		t.Fatal("SetText with a slide link on a slide without presentation returned no error")
+	}
+	if n := slide.rels.Len(); n != 0 {
+		t.Errorf("relationships after the failed SetText = %d, want 0", n)
+	}
+}
@@ -0,0 +1,388 @@
+package pptx
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxImageBytes 默认允许获取的图片最大字节数
+	DefaultMaxImageBytes = 20 << 20
+	// DefaultFetchTimeout 默认的网络请求超时时间
+	DefaultFetchTimeout = 30 * time.Second
+)
+
+// Image 表示获取到的图片
+type Image struct {
+	Data        []byte
+	ContentType string // 如 image/png，为空时根据内容识别
+}
+
+// ImageSource 根据引用（本地路径、URL、data URI 等）获取图片
+type ImageSource interface {
+	Fetch(ctx context.Context, ref string) (*Image, error)
+}
+
+// ImageSourceFunc 将函数适配为 ImageSource
+type ImageSourceFunc func(ctx context.Context, ref string) (*Image, error)
+
+// Fetch 实现 ImageSource
+func (f ImageSourceFunc) Fetch(ctx context.Context, ref string) (*Image, error) {
+	return f(ctx, ref)
+}
+
+// HTTPFetcher 通过 HTTP(S) 下载图片
+// 默认只连接公网地址，回环、内网和链路本地地址在建立连接时被拒绝，重定向和 DNS 解析到这些地址时同样失败
+type HTTPFetcher struct {
+	Client               *http.Client // 为 nil 时使用超时为 DefaultFetchTimeout 的客户端，设置了 Transport 时不检查连接的地址
+	MaxBytes             int64        // 图片最大字节数，为 0 时使用 DefaultMaxImageBytes
+	AllowedHosts         []string     // 允许访问的主机，支持 "*.example.com"，为空时不限制
+	AllowPrivateNetworks bool         // 允许连接回环、内网和链路本地地址
+
+	transportOnce sync.Once
+	transport     *http.Transport // 只连接公网地址的 Transport，第一次使用时创建，之后复用其中的连接
+}
+
+// Fetch 实现 ImageSource，重定向到不允许的主机时同样会失败
+func (f *HTTPFetcher) Fetch(ctx context.Context, ref string) (*Image, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image url: %w", err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image, status code: %d", resp.StatusCode)
+	}
+
+	maxBytes := f.maxBytes()
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxBytes)
+	}
+	data, err := readLimited(resp.Body, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	contentType, err := sniffImageType(data, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// client 返回带有主机检查的 HTTP 客户端
+func (f *HTTPFetcher) client() *http.Client {
+	client := &http.Client{Timeout: DefaultFetchTimeout}
+	if f.Client != nil {
+		copied := *f.Client
+		client = &copied
+	}
+	if client.Transport == nil && !f.AllowPrivateNetworks {
+		client.Transport = f.publicTransport()
+	}
+	next := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := f.checkURL(req.URL); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return client
+}
+
+// checkURL 检查协议和主机是否允许访问
+func (f *HTTPFetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported image url scheme: %s", u.Scheme)
+	}
+	if len(f.AllowedHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("image host not allowed: %s", host)
+}
+
+// maxBytes 返回实际生效的大小限制
+func (f *HTTPFetcher) maxBytes() int64 {
+	return maxImageBytes(f.MaxBytes)
+}
+
+// publicTransport 返回只连接公网地址的 Transport，连接时检查 DNS 解析后的地址
+// 同一个 HTTPFetcher 共用一个 Transport，不使用环境变量中的代理，否则检查的是代理的地址
+func (f *HTTPFetcher) publicTransport() *http.Transport {
+	f.transportOnce.Do(func() {
+		f.transport = newPublicTransport()
+	})
+	return f.transport
+}
+
+// newPublicTransport 创建在建立连接时检查地址的 Transport
+func newPublicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: DefaultFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("image host address not allowed: %s", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// sharedAddressSpace 运营商级 NAT 使用的共享地址 100.64.0.0/10（RFC 6598），云服务的元数据等内部服务也会使用
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP 判断地址是否为公网地址
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// maxImageBytes 返回实际生效的大小限制，maxBytes 为 0 时使用 DefaultMaxImageBytes
+func maxImageBytes(maxBytes int64) int64 {
+	if maxBytes > 0 {
+		return maxBytes
+	}
+	return DefaultMaxImageBytes
+}
+
+// DataURISource 解析 data: URI 中的图片，如 data:image/png;base64,....
+type DataURISource struct {
+	MaxBytes int64 // 解码后的最大字节数，为 0 时使用 DefaultMaxImageBytes
+}
+
+// Fetch 实现 ImageSource
+func (s DataURISource) Fetch(ctx context.Context, ref string) (*Image, error) {
+	if !strings.HasPrefix(ref, "data:") {
+		return nil, fmt.Errorf("not a data uri")
+	}
+	meta, payload, ok := strings.Cut(ref[len("data:"):], ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid data uri: missing comma")
+	}
+
+	isBase64 := strings.HasSuffix(meta, ";base64")
+	mediaType, _, _ := mime.ParseMediaType(strings.TrimSuffix(meta, ";base64"))
+
+	// 解码前先按编码后的长度检查，避免解码过大的内容
+	maxBytes := maxImageBytes(s.MaxBytes)
+	if isBase64 && int64(len(payload)) > int64(base64.StdEncoding.EncodedLen(int(maxBytes))) {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxBytes)
+	}
+
+	var data []byte
+	var err error
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(payload)
+	} else {
+		var unescaped string
+		unescaped, err = url.PathUnescape(payload)
+		data = []byte(unescaped)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid data uri: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxBytes)
+	}
+
+	contentType, err := sniffImageType(data, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// FSSource 从 fs.FS 中读取图片，可配合 os.DirFS 或 fstest.MapFS 使用
+type FSSource struct {
+	FS       fs.FS
+	MaxBytes int64 // 文件的最大字节数，为 0 时使用 DefaultMaxImageBytes
+}
+
+// Fetch 实现 ImageSource，ref 为 fs.FS 中的路径
+func (s FSSource) Fetch(ctx context.Context, ref string) (*Image, error) {
+	file, err := s.FS.Open(strings.TrimPrefix(ref, "/"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	maxBytes := maxImageBytes(s.MaxBytes)
+	if info, err := file.Stat(); err == nil && info.Size() > maxBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxBytes)
+	}
+	data, err := readLimited(file, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return imageFromFile(data, ref)
+}
+
+// MemorySource 从内存中的映射读取图片，键为引用
+type MemorySource map[string][]byte
+
+// Fetch 实现 ImageSource
+func (s MemorySource) Fetch(ctx context.Context, ref string) (*Image, error) {
+	data, ok := s[ref]
+	if !ok {
+		return nil, fmt.Errorf("image not found: %s", ref)
+	}
+	return imageFromFile(data, ref)
+}
+
+// defaultImageSource 默认的图片源：data: URI、HTTP(S)，以及通过 WithImageFiles 允许的文件
+type defaultImageSource struct {
+	http  *HTTPFetcher
+	files fs.FS // 允许读取的文件，为 nil 时不读取任何文件
+}
+
+// ImageSourceOption 定义 NewImageSource 的选项
+type ImageSourceOption func(*defaultImageSource)
+
+// WithImageFiles 允许图片源读取 fsys 中的文件，引用为 fsys 中的路径，如 WithImageFiles(os.DirFS("/srv/images"))
+func WithImageFiles(fsys fs.FS) ImageSourceOption {
+	return func(s *defaultImageSource) {
+		s.files = fsys
+	}
+}
+
+// NewImageSource 创建按引用类型分发的图片源：data: URI 直接解析，http(s) 使用 fetcher 下载，
+// 其余引用只有通过 WithImageFiles 允许后才作为文件读取，默认不访问本地文件系统
+// fetcher 为 nil 时使用默认配置的 HTTPFetcher，它的 MaxBytes 同样限制 data: URI 和文件的大小
+func NewImageSource(fetcher *HTTPFetcher, options ...ImageSourceOption) ImageSource {
+	if fetcher == nil {
+		fetcher = &HTTPFetcher{}
+	}
+	s := &defaultImageSource{http: fetcher}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Fetch 实现 ImageSource
+func (s *defaultImageSource) Fetch(ctx context.Context, ref string) (*Image, error) {
+	switch {
+	case strings.HasPrefix(ref, "data:"):
+		return DataURISource{MaxBytes: s.http.MaxBytes}.Fetch(ctx, ref)
+	case strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://"):
+		return s.http.Fetch(ctx, ref)
+	case s.files == nil:
+		return nil, fmt.Errorf("reading local image files is disabled by default, set an image source with WithImageFiles to allow it: %s", ref)
+	default:
+		return FSSource{FS: s.files, MaxBytes: s.http.MaxBytes}.Fetch(ctx, ref)
+	}
+}
+
+// imageFromFile 根据文件内容和扩展名识别图片类型
+func imageFromFile(data []byte, name string) (*Image, error) {
+	contentType, err := sniffImageType(data, mime.TypeByExtension(strings.ToLower(path.Ext(name))))
+	if err != nil {
+		return nil, err
+	}
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// sniffImageType 识别图片的内容类型，无法从内容识别时使用 hint（响应头或扩展名对应的类型）
+func sniffImageType(data []byte, hint string) (string, error) {
+	if detected := http.DetectContentType(data); strings.HasPrefix(detected, "image/") {
+		return detected, nil
+	}
+	hint = strings.ToLower(hint)
+	if strings.HasPrefix(hint, "image/") {
+		return hint, nil
+	}
+	return "", fmt.Errorf("content is not a supported image")
+}
+
+// readLimited 读取不超过 maxBytes 字节的内容
+func readLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxBytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// imageExtension 获取内容类型对应的扩展名，未知类型时使用引用中的扩展名
+func imageExtension(contentType, ref string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpeg"
+	case "image/gif":
+		return ".gif"
+	case "image/bmp":
+		return ".bmp"
+	case "image/tiff":
+		return ".tiff"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	case "image/x-wmf":
+		return ".wmf"
+	case "image/x-emf":
+		return ".emf"
+	}
+	if !strings.HasPrefix(ref, "data:") {
+		if ext := strings.ToLower(filepath.Ext(ref)); ext != "" && !strings.ContainsAny(ext, "?#/") {
+			return ext
+		}
+	}
+	return ".png"
+}
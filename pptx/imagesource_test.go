@@ -0,0 +1,135 @@
+package pptx
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefaultImageSourceFiles(t *testing.T) {
+	files := fstest.MapFS{"images/a.png": {Data: testPNG("a")}}
+	tests := []struct {
+		name    string
+		source  ImageSource
+		ref     string
+		wantErr bool
+	}{
+		{"files disabled by default", NewImageSource(nil), "images/a.png", true},
+		{"absolute path disabled by default", NewImageSource(nil), "/etc/passwd", true},
+		{"files enabled", NewImageSource(nil, WithImageFiles(files)), "images/a.png", false},
+		{"outside the file system", NewImageSource(nil, WithImageFiles(files)), "../a.png", true},
+		{"file too large", NewImageSource(&HTTPFetcher{MaxBytes: 4}, WithImageFiles(files)), "images/a.png", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := tt.source.Fetch(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if err != nil && tt.source.(*defaultImageSource).files == nil && !strings.Contains(err.Error(), "WithImageFiles") {
+				t.Errorf("Fetch(%q) error = %v, want a hint to use WithImageFiles", tt.ref, err)
+			}
+			if err == nil && img.ContentType != "image/png" {
+				t.Errorf("Fetch(%q) content type = %q, want image/png", tt.ref, img.ContentType)
+			}
+		})
+	}
+}
+
+func TestDataURISourceMaxBytes(t *testing.T) {
+	encoded := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG("0123456789"))
+	tests := []struct {
+		name     string
+		maxBytes int64
+		ref      string
+		wantErr  bool
+	}{
+		{"within limit", 64, encoded, false},
+		{"base64 too large", 8, encoded, true},
+		{"percent-encoded too large", 4, "data:image/svg+xml,%3Csvg%3E%3C/svg%3E", true},
+		{"default limit", 0, encoded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DataURISource{MaxBytes: tt.maxBytes}.Fetch(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(testPNG("remote"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		fetcher *HTTPFetcher
+		wantErr string
+	}{
+		{"loopback refused by default", &HTTPFetcher{}, "not allowed"},
+		{"private networks allowed", &HTTPFetcher{AllowPrivateNetworks: true}, ""},
+		{"host not in allow list", &HTTPFetcher{AllowPrivateNetworks: true, AllowedHosts: []string{"*.example.com"}}, "not allowed"},
+		{"host in allow list", &HTTPFetcher{AllowPrivateNetworks: true, AllowedHosts: []string{"127.0.0.1"}}, ""},
+		{"too large", &HTTPFetcher{AllowPrivateNetworks: true, MaxBytes: 4}, "exceeds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.fetcher.Fetch(context.Background(), server.URL+"/a.png")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Fetch() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherReusesTransport(t *testing.T) {
+	f := &HTTPFetcher{}
+	first := f.client().Transport
+	if first == nil {
+		t.Fatal("client() has no restricted transport")
+	}
+	if second := f.client().Transport; second != first {
+		t.Error("client() created a new transport for the second request")
+	}
+	if transport := (&HTTPFetcher{AllowPrivateNetworks: true}).client().Transport; transport != nil {
+		t.Errorf("client() with AllowPrivateNetworks transport = %v, want the default transport", transport)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"192.168.0.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"100.128.0.1", true},
+		{"::1", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package pptx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// Frame 表示形状的位置和大小，单位为 EMU
+type Frame struct {
+	X      int64
+	Y      int64
+	Width  int64
+	Height int64
+}
+
+// bulletGroups 同一组内的元素互斥，合并文本样式时需要整体替换
+var bulletGroups = map[string]string{
+	"buClrTx":   "buClr",
+	"buClr":     "buClr",
+	"buSzTx":    "buSz",
+	"buSzPct":   "buSz",
+	"buSzPts":   "buSz",
+	"buFontTx":  "buFont",
+	"buFont":    "buFont",
+	"buNone":    "bu",
+	"buAutoNum": "bu",
+	"buChar":    "bu",
+	"buBlip":    "bu",
+}
+
+// EffectiveFrame 获取占位符实际生效的位置和大小
+// 依次查找幻灯片、布局和母版中匹配的占位符，返回第一个定义了 a:xfrm 的结果
+func (p *Placeholder) EffectiveFrame() (Frame, error) {
+	for _, shape := range p.inheritanceChain() {
+		frame, ok, err := shapeFrame(shape)
+		if err != nil {
+			return Frame{}, err
+		}
+		if ok {
+			return frame, nil
+		}
+	}
+	return Frame{}, fmt.Errorf("frame not found for placeholder")
+}
+
+// EffectiveTextStyle 获取占位符指定层级（1-9）实际生效的段落样式
+// 合并顺序为母版文本样式、母版占位符、布局占位符、幻灯片占位符，越靠近幻灯片优先级越高
+// 返回的是合并后的 a:lvlNpPr 副本，修改它不会影响文档
+func (p *Placeholder) EffectiveTextStyle(level int) (*etree.Element, error) {
+	if level < 1 || level > 9 {
+		return nil, fmt.Errorf("invalid text style level: %d", level)
+	}
+	tag := fmt.Sprintf("lvl%dpPr", level)
+
+	// 按从远到近的顺序收集样式
+	var styles []*etree.Element
+	if master := p.master(); master != nil && master.xml != nil {
+		if txStyles := master.xml.FindElement("//p:txStyles"); txStyles != nil {
+			if style := txStyles.SelectElement(masterTextStyleName(p.phType())); style != nil {
+				if lvl := style.SelectElement(tag); lvl != nil {
+					styles = append(styles, lvl)
+				}
+			}
+		}
+	}
+	chain := p.inheritanceChain()
+	for i := len(chain) - 1; i >= 0; i-- {
+		if lvl := chain[i].FindElement("txBody/lstStyle/" + tag); lvl != nil {
+			styles = append(styles, lvl)
+		}
+	}
+
+	if len(styles) == 0 {
+		return nil, fmt.Errorf("text style not found for level %d", level)
+	}
+
+	result := etree.NewElement("a:" + tag)
+	for _, style := range styles {
+		mergeStyleElement(result, style)
+	}
+	return result, nil
+}
+
+// inheritanceChain 返回占位符的继承链：幻灯片形状、布局形状、母版形状
+func (p *Placeholder) inheritanceChain() []*etree.Element {
+	var chain []*etree.Element
+	if p.Shape != nil {
+		chain = append(chain, p.Shape)
+	}
+
+	ph := placeholderProps(p.Shape)
+	if ph == nil || p.slide == nil {
+		return chain
+	}
+
+	if layout := p.slide.layout; layout != nil && layout.xml != nil {
+		if shape := findInheritedShape(layout.xml, ph, false); shape != nil {
+			chain = append(chain, shape)
+		}
+	}
+	if master := p.master(); master != nil && master.xml != nil {
+		if shape := findInheritedShape(master.xml, ph, true); shape != nil {
+			chain = append(chain, shape)
+		}
+	}
+	return chain
+}
+
+// master 获取占位符所在幻灯片对应的母版
+func (p *Placeholder) master() *Master {
+	if p.slide == nil {
+		return nil
+	}
+	if p.slide.master != nil {
+		return p.slide.master
+	}
+	if p.slide.pres != nil && p.slide.layout != nil {
+		return p.slide.pres.findMasterForLayout(p.slide.layout)
+	}
+	return nil
+}
+
+// phType 获取占位符 p:ph 元素上的原始类型
+func (p *Placeholder) phType() string {
+	if ph := placeholderProps(p.Shape); ph != nil {
+		return ph.SelectAttrValue("type", "")
+	}
+	return ""
+}
+
+// placeholderProps 获取形状的 p:ph 元素
+func placeholderProps(shape *etree.Element) *etree.Element {
+	if shape == nil {
+		return nil
+	}
+	for _, nv := range []string{"nvSpPr", "nvPicPr", "nvGraphicFramePr", "nvGrpSpPr", "nvCxnSpPr"} {
+		if ph := shape.FindElement(nv + "/nvPr/ph"); ph != nil {
+			return ph
+		}
+	}
+	return nil
+}
+
+// findInheritedShape 在布局或母版中查找与 ph 匹配的占位符形状
+// 布局优先按 idx 匹配，再按类型匹配；母版只按归一化后的类型匹配
+func findInheritedShape(doc *etree.Document, ph *etree.Element, isMaster bool) *etree.Element {
+	spTree := doc.FindElement("//p:cSld/p:spTree")
+	if spTree == nil {
+		return nil
+	}
+
+	type candidate struct {
+		shape *etree.Element
+		ph    *etree.Element
+	}
+	var candidates []candidate
+	for _, shape := range spTree.ChildElements() {
+		if shapePh := placeholderProps(shape); shapePh != nil {
+			candidates = append(candidates, candidate{shape, shapePh})
+		}
+	}
+
+	phType := ph.SelectAttrValue("type", "")
+	idx := ph.SelectAttr("idx")
+
+	if !isMaster {
+		if idx != nil {
+			for _, c := range candidates {
+				if c.ph.SelectAttrValue("idx", "") == idx.Value {
+					return c.shape
+				}
+			}
+		}
+		for _, c := range candidates {
+			if phType != "" && c.ph.SelectAttrValue("type", "") == phType {
+				return c.shape
+			}
+		}
+	}
+
+	target := masterPlaceholderType(phType)
+	for _, c := range candidates {
+		if masterPlaceholderType(c.ph.SelectAttrValue("type", "")) == target {
+			return c.shape
+		}
+	}
+	return nil
+}
+
+// masterPlaceholderType 将占位符类型归一化为母版中使用的类型
+func masterPlaceholderType(phType string) string {
+	switch phType {
+	case "title", "ctrTitle":
+		return "title"
+	case "dt", "ftr", "hdr", "sldNum":
+		return phType
+	default:
+		// subTitle、obj、pic、tbl、chart 等以及未指定类型的占位符都继承母版正文
+		return "body"
+	}
+}
+
+// masterTextStyleName 获取占位符类型对应的母版文本样式名称
+func masterTextStyleName(phType string) string {
+	switch masterPlaceholderType(phType) {
+	case "title":
+		return "p:titleStyle"
+	case "body":
+		return "p:bodyStyle"
+	default:
+		return "p:otherStyle"
+	}
+}
+
+// shapeFrame 读取形状自身定义的 a:xfrm，没有定义时 ok 为 false，坐标不是整数时返回错误
+func shapeFrame(shape *etree.Element) (frame Frame, ok bool, err error) {
+	xfrm := shape.FindElement("spPr/xfrm")
+	if xfrm == nil {
+		xfrm = shape.FindElement("xfrm")
+	}
+	if xfrm == nil {
+		return Frame{}, false, nil
+	}
+	off := xfrm.SelectElement("off")
+	ext := xfrm.SelectElement("ext")
+	if off == nil || ext == nil {
+		return Frame{}, false, nil
+	}
+	fields := []struct {
+		el  *etree.Element
+		key string
+		dst *int64
+	}{
+		{off, "x", &frame.X},
+		{off, "y", &frame.Y},
+		{ext, "cx", &frame.Width},
+		{ext, "cy", &frame.Height},
+	}
+	for _, field := range fields {
+		if *field.dst, err = parseCoordinate(field.el, field.key); err != nil {
+			return Frame{}, false, err
+		}
+	}
+	return frame, true, nil
+}
+
+// parseCoordinate 解析 a:off 或 a:ext 上以 EMU 为单位的整数属性，属性不存在时为 0
+func parseCoordinate(el *etree.Element, key string) (int64, error) {
+	value := el.SelectAttrValue(key, "0")
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s attribute %s=%q: %w", el.FullTag(), key, value, err)
+	}
+	return n, nil
+}
+
+// writeFrame 将 Frame 写入为 tag 指定的变换元素（a:xfrm 或 p:xfrm）
+func writeFrame(parent *etree.Element, tag string, frame Frame) *etree.Element {
+	xfrm := parent.CreateElement(tag)
+	off := xfrm.CreateElement("a:off")
+	off.CreateAttr("x", fmt.Sprintf("%d", frame.X))
+	off.CreateAttr("y", fmt.Sprintf("%d", frame.Y))
+	ext := xfrm.CreateElement("a:ext")
+	ext.CreateAttr("cx", fmt.Sprintf("%d", frame.Width))
+	ext.CreateAttr("cy", fmt.Sprintf("%d", frame.Height))
+	return xfrm
+}
+
+// mergeStyleElement 将 src 的属性和子元素合并到 dst，src 优先
+func mergeStyleElement(dst, src *etree.Element) {
+	for _, attr := range src.Attr {
+		dst.CreateAttr(attr.FullKey(), attr.Value)
+	}
+
+	for _, child := range src.ChildElements() {
+		if child.Tag == "defRPr" {
+			if existing := dst.SelectElement("defRPr"); existing != nil {
+				mergeStyleElement(existing, child)
+				continue
+			}
+		}
+
+		// 替换同名或同组元素，并保持其原有位置
+		group, grouped := bulletGroups[child.Tag]
+		index := -1
+		for _, existing := range dst.ChildElements() {
+			if existing.Tag == child.Tag || grouped && bulletGroups[existing.Tag] == group {
+				if index < 0 {
+					index = existing.Index()
+				}
+				dst.RemoveChild(existing)
+			}
+		}
+
+		// 新增的段落属性需要位于 defRPr 和 extLst 之前
+		if index < 0 && child.Tag != "defRPr" && child.Tag != "extLst" {
+			for _, tag := range []string{"defRPr", "extLst"} {
+				if next := dst.SelectElement(tag); next != nil {
+					index = next.Index()
+					break
+				}
+			}
+		}
+
+		if index < 0 {
+			dst.AddChild(child.Copy())
+		} else {
+			dst.InsertChildAt(index, child.Copy())
+		}
+	}
+}
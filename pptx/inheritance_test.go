@@ -0,0 +1,164 @@
+package pptx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+// templatePath 测试使用的示例模板
+const templatePath = "../example/templates/template4.pptx"
+
+// openTemplate 打开示例模板，测试结束时关闭
+func openTemplate(t *testing.T) *Presentation {
+	t.Helper()
+	pres, err := Open(templatePath)
+	if err != nil {
+		t.Fatalf("failed to open template: %v", err)
+	}
+	t.Cleanup(func() { pres.Close() })
+	return pres
+}
+
+// removeFrame 去掉形状自身的 a:xfrm，使其继承布局或母版中的位置
+func removeFrame(t *testing.T, p *Placeholder) {
+	t.Helper()
+	if xfrm := p.Shape.FindElement("p:spPr/a:xfrm"); xfrm != nil {
+		xfrm.Parent().RemoveChild(xfrm)
+	}
+}
+
+func TestEffectiveFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		params []interface{}
+		want   Frame
+	}{
+		// 布局“图片与标题”中的图片占位符（idx=1）定义了位置
+		{"from layout by idx", "图片与标题", []interface{}{PlaceholderImage}, Frame{5183188, 987425, 6172200, 4873625}},
+		// 布局“仅标题”中的标题没有定义位置，使用母版中的标题
+		{"from master by type", "仅标题", []interface{}{PlaceholderTitle}, Frame{838200, 365125, 10515600, 1325563}},
+		// 没有类型的内容占位符（idx=1）继承母版正文
+		{"object from master body", "标题和内容", []interface{}{1}, Frame{838200, 1825625, 10515600, 4351338}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pres := openTemplate(t)
+			slide, err := pres.AddSlide(tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			placeholder, err := slide.GetPlaceholder(tt.params...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			removeFrame(t, placeholder)
+			frame, err := placeholder.EffectiveFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frame != tt.want {
+				t.Errorf("EffectiveFrame() = %+v, want %+v", frame, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveFrameSlideOverride(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("仅标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Frame{X: 1, Y: 2, Width: 3, Height: 4}
+	writeFrame(title.Shape.FindElement("p:spPr"), "a:xfrm", want)
+	if frame, err := title.EffectiveFrame(); err != nil || frame != want {
+		t.Errorf("EffectiveFrame() = %+v, %v, want %+v", frame, err, want)
+	}
+
+	title.Shape.FindElement("p:spPr/a:xfrm/a:off").CreateAttr("x", "1.5")
+	if frame, err := title.EffectiveFrame(); err == nil || !strings.Contains(err.Error(), `x="1.5"`) {
+		t.Errorf("EffectiveFrame() = %+v, %v, want an error for the malformed offset", frame, err)
+	}
+}
+
+func TestEffectiveTextStyle(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("图片与标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := slide.GetPlaceholder(PlaceholderBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 幻灯片上的样式优先于布局和母版
+	lstStyle := body.Shape.FindElement("p:txBody/a:lstStyle")
+	for _, child := range lstStyle.ChildElements() {
+		lstStyle.RemoveChild(child)
+	}
+	lstStyle.CreateElement("a:lvl1pPr").CreateAttr("algn", "r")
+
+	style, err := body.EffectiveTextStyle(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := []struct{ key, want string }{
+		{"algn", "r"},          // 幻灯片
+		{"marL", "0"},          // 布局覆盖母版的 228600
+		{"indent", "0"},        // 布局覆盖母版的 -228600
+		{"defTabSz", "914400"}, // 母版文本样式
+		{"hangingPunct", "1"},  // 母版文本样式
+	}
+	for _, attr := range attrs {
+		if got := style.SelectAttrValue(attr.key, ""); got != attr.want {
+			t.Errorf("lvl1pPr %s = %q, want %q", attr.key, got, attr.want)
+		}
+	}
+
+	// 布局的 a:buNone 替换母版的 a:buChar，a:buFont 不在同一组，仍然保留
+	if style.SelectElement("buNone") == nil || style.SelectElement("buChar") != nil {
+		t.Errorf("bullet = %s, want buNone replacing buChar", elementString(t, style))
+	}
+	if style.SelectElement("buFont") == nil {
+		t.Errorf("buFont from the master text style is missing: %s", elementString(t, style))
+	}
+	defRPr := style.SelectElement("defRPr")
+	if defRPr == nil {
+		t.Fatalf("defRPr is missing: %s", elementString(t, style))
+	}
+	if sz, kern := defRPr.SelectAttrValue("sz", ""), defRPr.SelectAttrValue("kern", ""); sz != "1600" || kern != "1200" {
+		t.Errorf("defRPr sz = %q, kern = %q, want 1600 from the layout and 1200 from the master", sz, kern)
+	}
+	if defRPr.FindElement("solidFill/schemeClr") == nil {
+		t.Errorf("defRPr fill from the master text style is missing: %s", elementString(t, style))
+	}
+
+	// 母版文本样式本身不会被修改
+	master := body.master().xml.FindElement("//p:txStyles/p:bodyStyle/a:lvl1pPr")
+	if master.SelectAttrValue("algn", "") != "l" || master.SelectElement("buChar") == nil {
+		t.Errorf("EffectiveTextStyle modified the master text style: %s", elementString(t, master))
+	}
+
+	if _, err := body.EffectiveTextStyle(10); err == nil {
+		t.Error("EffectiveTextStyle(10) succeeded, want an error")
+	}
+}
+
+// elementString 序列化元素，用于错误信息
+func elementString(t *testing.T, el *etree.Element) string {
+	t.Helper()
+	doc := etree.NewDocument()
+	doc.AddChild(el.Copy())
+	xml, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xml
+}
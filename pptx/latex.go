@@ -1,156 +1,287 @@
 package pptx
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
-
-	_ "embed"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/beevik/etree"
-	xslt "github.com/wamuir/go-xslt"
 	"github.com/wanglihui/pptx-go/latex2mathml"
 )
 
-// 初始化 XSLT 转换器
-var mathmlToOmmlXslt *xslt.Stylesheet
-
-//go:embed MATH2OMML.xsl
-var xsltContent []byte
+// convertMathMLToOMML 将 MathML 转换为 m:oMath 元素，默认使用纯 Go 实现，
+// 使用 -tags xslt 构建时替换为基于 XSLT 的实现
+var convertMathMLToOMML = mathMLToOMML
 
-func init() {
-	var err error
-	// 读取本地 XSLT 文件
-	mathmlToOmmlXslt, err = xslt.NewStylesheet(xsltContent)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to parse XSLT stylesheet: %v", err))
+// convertLatexToMathML 使用 converter 将 LaTeX 转换为 MathML，display 为 true 时生成行间公式
+// 语法错误以 *latex2mathml.SyntaxError 返回，commit 不为 nil 时的行为与 Converter.ConvertCommit 相同
+func convertLatexToMathML(converter latex2mathml.Converter, latex string, display bool, commit func(mathml string) error) (string, error) {
+	converter.Display = "inline"
+	if display {
+		converter.Display = "block"
 	}
-	// defer mathmlToOmmlXslt.Close()
-}
-
-// convertLatexToMathML 使用 latex2mathml 将 LaTeX 转换为 MathML
-func convertLatexToMathML(latex string) (string, error) {
-	// 创建 latex2mathml 命令
-	mathml := latex2mathml.Convert(latex, "http://www.w3.org/1998/Math/MathML", "inline", 0)
-	return mathml, nil
+	return converter.ConvertCommit(latex, commit)
 }
 
-// convertLatexToOMML 将 LaTeX 转换为 OMML
-func convertLatexToOMML(latex string) ([]*etree.Element, error) {
-	// fmt.Println("latex===>", latex)
+// convertLatexToOMML 将 LaTeX 转换为 m:oMath 元素，每个转换步骤之前检查 ctx 是否已取消
+// LaTeX 转换失败时返回 *LatexError，MathML 转换为 OMML 失败时返回 *EquationError
+// 公式编号只在转换为 OMML 成功后才生效，失败的公式不占用编号
+func convertLatexToOMML(ctx context.Context, converter latex2mathml.Converter, latex string, display bool) (*etree.Element, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var oMath *etree.Element
+	var ommlErr error // 取消或转换为 OMML 时的错误，不是 LaTeX 错误
 	// 1. 使用 latex2mathml 将 LaTeX 转换为 MathML
-	mathml, err := convertLatexToMathML(latex)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert LaTeX to MathML: %w", err)
-	}
-	// fmt.Println("mathml===>", mathml)
-	// 2. 使用 XSLT 将 MathML 转换为 OMML
-	omml, err := convertMathMLToOMMLUsingXSLT(mathml)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert MathML to OMML: %w", err)
-	}
-	// fmt.Println("omml===>", omml)
-	// 3. 解析 OMML 为 etree.Element
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(omml); err != nil {
-		return nil, fmt.Errorf("failed to parse OMML: %w", err)
-	}
-
-	// 4. 创建正确的 PPTX 数学公式结构
-	if root := doc.Root(); root != nil {
-		// 创建 m:oMathPara 元素包装
-		oMathPara := etree.NewElement("m:oMathPara")
-		oMathPara.CreateAttr("xmlns:m", "http://schemas.openxmlformats.org/officeDocument/2006/math")
-
-		// 添加 m:oMathParaPr 元素
-		oMathParaPr := etree.NewElement("m:oMathParaPr")
-		oMathPara.AddChild(oMathParaPr)
-
-		oMath := etree.NewElement("m:oMath")
-		oMath.CreateAttr("xmlns:m", "http://schemas.openxmlformats.org/officeDocument/2006/math")
-
-		// 将转换后的数学公式元素添加到 oMath 中
-		for _, child := range root.Child {
-			if elem, ok := child.(*etree.Element); ok {
-				oMath.AddChild(elem.Copy())
-			}
+	_, err := convertLatexToMathML(converter, latex, display, func(mathml string) error {
+		if ommlErr = ctx.Err(); ommlErr != nil {
+			return ommlErr
 		}
-
-		oMathPara.AddChild(oMath)
-		return []*etree.Element{oMathPara}, nil
+		// 2. 将 MathML 转换为 OMML
+		oMath, ommlErr = convertMathMLToOMML(mathml)
+		if ommlErr != nil {
+			ommlErr = fmt.Errorf("failed to convert latex %q: %w", latex, &EquationError{Format: "MathML", Err: ommlErr})
+		}
+		return ommlErr
+	})
+	switch {
+	case ommlErr != nil:
+		return nil, ommlErr
+	case err != nil:
+		return nil, &LatexError{Latex: latex, Err: err}
 	}
+	return oMath, nil
+}
+
+// MathJustification 定义行间公式的对齐方式
+type MathJustification string
+
+const (
+	MathJustifyCenter      MathJustification = "center"      // 整体居中
+	MathJustifyCenterGroup MathJustification = "centerGroup" // 多个公式作为一组居中，组内左对齐
+	MathJustifyLeft        MathJustification = "left"        // 左对齐
+	MathJustifyRight       MathJustification = "right"       // 右对齐
+)
 
-	return nil, fmt.Errorf("no elements found in OMML")
+// paragraphAlign 返回与公式对齐方式一致的段落对齐方式
+func (j MathJustification) paragraphAlign() string {
+	switch j {
+	case MathJustifyLeft:
+		return "l"
+	case MathJustifyRight:
+		return "r"
+	default:
+		return "ctr"
+	}
 }
 
-// convertMathMLToOMMLUsingXSLT 使用 XSLT 将 MathML 转换为 OMML
-func convertMathMLToOMMLUsingXSLT(mathml string) (string, error) {
+// newMathContainer 创建放在 a:p 中的 a14:m 容器
+func newMathContainer(math *etree.Element) *etree.Element {
+	container := etree.NewElement("a14:m")
+	container.CreateAttr("xmlns:a14", "http://schemas.microsoft.com/office/drawing/2010/main")
+	container.AddChild(math)
+	return container
+}
 
-	// 应用 XSLT 转换
-	result, err := mathmlToOmmlXslt.Transform([]byte(mathml))
-	if err != nil {
-		return "", fmt.Errorf("failed to apply XSLT transformation: %w", err)
+// newMathPara 将 m:oMath 包装为行间公式使用的 m:oMathPara
+func newMathPara(oMath *etree.Element, jc MathJustification) *etree.Element {
+	if jc == "" {
+		jc = MathJustifyCenter
 	}
-	return string(result), nil
+	oMathPara := etree.NewElement("m:oMathPara")
+	oMathPara.CreateAttr("xmlns:m", NsMath)
+	setMathVal(oMathPara.CreateElement("m:oMathParaPr").CreateElement("m:jc"), string(jc))
+	oMathPara.AddChild(oMath)
+	return oMathPara
 }
 
-// TextSegment 表示文本片段，可以是普通文本或LaTeX公式
+// TextSegment 表示文本片段，可以是普通文本、LaTeX公式或MathML公式
 type TextSegment struct {
-	Text    string
-	IsLatex bool
+	Text     string
+	IsLatex  bool
+	IsMathML bool   // 是否为 MathML 公式，此时 Text 为完整的 <math> 元素
+	Display  bool   // 是否为独占一段的行间公式（$$…$$、\[…\] 或 display="block" 的 <math>）
+	Source   string // 公式包含定界符的原始文本
+}
+
+// LatexDelimiter 表示一对公式定界符
+type LatexDelimiter struct {
+	Open    string
+	Close   string
+	Display bool // 是否为独占一段的行间公式
+}
+
+// DefaultLatexDelimiters 默认识别的公式定界符：$…$ 和 \(…\) 为行内公式，$$…$$ 和 \[…\] 为行间公式
+var DefaultLatexDelimiters = []LatexDelimiter{
+	{Open: "$$", Close: "$$", Display: true},
+	{Open: `\[`, Close: `\]`, Display: true},
+	{Open: `\(`, Close: `\)`},
+	{Open: "$", Close: "$"},
+}
+
+// mathMLOpenPattern 匹配 MathML 的开始标签，如 <math>、<math display="block"> 和 <mml:math ...>
+var mathMLOpenPattern = regexp.MustCompile(`^<(?:([A-Za-z_][\w.-]*):)?math[\s>]`)
+
+// mathMLDisplayPattern 匹配开始标签中表示行间公式的属性
+var mathMLDisplayPattern = regexp.MustCompile(`\s(?:display\s*=\s*["']block["']|mode\s*=\s*["']display["'])`)
+
+// formulaScanner 在文本中查找 LaTeX 和 MathML 公式
+type formulaScanner struct {
+	delimiters []LatexDelimiter // 按开始定界符长度从长到短排列，$$ 先于 $ 匹配，不识别 LaTeX 时为空
+	latex      bool             // 是否识别 LaTeX 公式和反斜杠转义
+	mathml     bool             // 是否识别 <math> 元素
+	dollarRule bool             // 单个 $ 是否使用 Pandoc 规则
 }
 
-// parseLatexFormula 解析文本中的LaTeX公式
-func parseLatexFormula(text string) []TextSegment {
+// newFormulaScanner 根据文本选项创建 formulaScanner，没有设置定界符时使用 DefaultLatexDelimiters
+func newFormulaScanner(opts *TextOptions) *formulaScanner {
+	s := &formulaScanner{latex: opts.EnableLatex, mathml: opts.EnableMathML, dollarRule: opts.PandocDollarRule}
+	if !s.latex {
+		return s
+	}
+	delimiters := opts.LatexDelimiters
+	if len(delimiters) == 0 {
+		delimiters = DefaultLatexDelimiters
+	}
+	s.delimiters = make([]LatexDelimiter, 0, len(delimiters))
+	for _, delim := range delimiters {
+		if delim.Open != "" && delim.Close != "" {
+			s.delimiters = append(s.delimiters, delim)
+		}
+	}
+	sort.SliceStable(s.delimiters, func(i, j int) bool {
+		return len(s.delimiters[i].Open) > len(s.delimiters[j].Open)
+	})
+	return s
+}
+
+// scan 将文本拆分为普通文本和公式
+// \$ 表示字符 $，\\ 表示字符 \，没有闭合或内容为空白的定界符按普通文本处理，普通文本中的空白原样保留
+func (s *formulaScanner) scan(text string) []TextSegment {
 	var segments []TextSegment
 	var currentText strings.Builder
-	inFormula := false
-
-	// 遍历文本的每个字符
-	for i := 0; i < len(text); i++ {
-		if text[i] == '$' {
-			// 检查是否为转义的 $
-			if i > 0 && text[i-1] == '\\' {
-				currentText.WriteByte('$')
-				continue
-			}
 
-			// 处理当前累积的文本
-			if currentText.Len() > 0 {
-				segments = append(segments, TextSegment{
-					Text:    currentText.String(),
-					IsLatex: inFormula,
-				})
-				currentText.Reset()
-			}
+	flush := func() {
+		if currentText.Len() > 0 {
+			segments = append(segments, TextSegment{Text: currentText.String()})
+			currentText.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		// 转义的 $ 和反斜杠
+		if s.latex && (strings.HasPrefix(text[i:], `\$`) || strings.HasPrefix(text[i:], `\\`)) {
+			currentText.WriteByte(text[i+1])
+			i += 2
+			continue
+		}
 
-			// 切换公式状态
-			inFormula = !inFormula
+		if segment, ok := s.mathMLAt(text, i); ok {
+			flush()
+			segments = append(segments, segment)
+			i += len(segment.Source)
 			continue
 		}
 
-		// 累积当前字符
-		currentText.WriteByte(text[i])
+		matched := false
+		for _, delim := range s.delimiters {
+			if !strings.HasPrefix(text[i:], delim.Open) || !s.canOpen(text, i, delim) {
+				continue
+			}
+			start := i + len(delim.Open)
+			end := s.findClose(text, start, delim)
+			if end < 0 || strings.TrimSpace(text[start:end]) == "" {
+				continue
+			}
+			flush()
+			segments = append(segments, TextSegment{
+				Text:    text[start:end],
+				IsLatex: true,
+				Display: delim.Display,
+				Source:  text[i : end+len(delim.Close)],
+			})
+			i = end + len(delim.Close)
+			matched = true
+			break
+		}
+		if !matched {
+			currentText.WriteByte(text[i])
+			i++
+		}
+	}
+	flush()
+
+	return segments
+}
+
+// mathMLAt 识别位置 i 处的 <math> 元素，没有结束标签时按普通文本处理
+func (s *formulaScanner) mathMLAt(text string, i int) (TextSegment, bool) {
+	if !s.mathml || text[i] != '<' {
+		return TextSegment{}, false
+	}
+	match := mathMLOpenPattern.FindStringSubmatch(text[i:])
+	if match == nil {
+		return TextSegment{}, false
+	}
+	closeTag := "</math>"
+	if match[1] != "" {
+		closeTag = "</" + match[1] + ":math>"
 	}
+	end := strings.Index(text[i:], closeTag)
+	if end < 0 {
+		return TextSegment{}, false
+	}
+	source := text[i : i+end+len(closeTag)]
+	openTag := source
+	if gt := strings.IndexByte(source, '>'); gt >= 0 {
+		openTag = source[:gt]
+	}
+	return TextSegment{
+		Text:     source,
+		IsMathML: true,
+		Display:  mathMLDisplayPattern.MatchString(openTag),
+		Source:   source,
+	}, true
+}
 
-	// 处理最后剩余的文本
-	if currentText.Len() > 0 {
-		segments = append(segments, TextSegment{
-			Text:    currentText.String(),
-			IsLatex: inFormula,
-		})
+// usesDollarRule 判断定界符是否适用 Pandoc 规则，只有单个 $ 适用
+func (s *formulaScanner) usesDollarRule(delim LatexDelimiter) bool {
+	return s.dollarRule && delim.Open == "$" && delim.Close == "$"
+}
+
+// canOpen 判断位置 i 处的定界符能否开始公式，Pandoc 规则要求开始的 $ 后面紧跟非空白字符
+func (s *formulaScanner) canOpen(text string, i int, delim LatexDelimiter) bool {
+	if !s.usesDollarRule(delim) {
+		return true
 	}
+	next, _ := utf8.DecodeRuneInString(text[i+len(delim.Open):])
+	return next != utf8.RuneError && !unicode.IsSpace(next)
+}
 
-	// 如果最后还在公式状态，说明式没有正确闭合
-	if inFormula {
-		// 将最后一个片段标记为普通文本
-		if len(segments) > 0 {
-			lastSegment := segments[len(segments)-1]
-			segments[len(segments)-1] = TextSegment{
-				Text:    "$" + lastSegment.Text,
-				IsLatex: false,
-			}
+// findClose 从 start 开始查找公式的结束定界符，跳过反斜杠转义的字符，找不到时返回 -1
+// Pandoc 规则要求结束的 $ 前面紧跟非空白字符，后面不能紧跟数字，因此 "$5 和 $10" 不是公式
+func (s *formulaScanner) findClose(text string, start int, delim LatexDelimiter) int {
+	for i := start; i < len(text); i++ {
+		if strings.HasPrefix(text[i:], delim.Close) && s.canClose(text, i, delim) {
+			return i
+		}
+		if text[i] == '\\' {
+			i++
 		}
 	}
+	return -1
+}
 
-	return segments
+// canClose 判断位置 i 处的定界符能否结束公式
+func (s *formulaScanner) canClose(text string, i int, delim LatexDelimiter) bool {
+	if !s.usesDollarRule(delim) {
+		return true
+	}
+	previous, _ := utf8.DecodeLastRuneInString(text[:i])
+	next, _ := utf8.DecodeRuneInString(text[i+len(delim.Close):])
+	return !unicode.IsSpace(previous) && !unicode.IsDigit(next)
 }
@@ -0,0 +1,194 @@
+package pptx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/wanglihui/pptx-go/latex2mathml"
+)
+
+func TestLatexErrorIs(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantSyntax bool
+	}{
+		{"syntax error", &LatexError{Latex: `\frac{`, Err: &latex2mathml.SyntaxError{Message: "missing argument"}}, true},
+		{"other error", &LatexError{Latex: `x`, Err: errors.New("boom")}, false},
+		{"nil error", &LatexError{Latex: `x`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, ErrLatexSyntax); got != tt.wantSyntax {
+				t.Errorf("errors.Is(%v, ErrLatexSyntax) = %v, want %v", tt.err, got, tt.wantSyntax)
+			}
+		})
+	}
+}
+
+func TestSetTextLatexErrors(t *testing.T) {
+	failOMML := func(string) (*etree.Element, error) { return nil, errors.New("unsupported element") }
+	tests := []struct {
+		name       string
+		text       string
+		options    []TextOption
+		omml       func(string) (*etree.Element, error)
+		wantSyntax bool
+		wantText   string
+	}{
+		{"syntax error", `a $\frac{1$`, []TextOption{WithLatex()}, nil, true, ""},
+		{"syntax error with fallback", `a $\frac{1$`, []TextOption{WithLatex(), WithLatexFallback()}, nil, false, `a $\frac{1$`},
+		{"preamble syntax error", `a $x$`, []TextOption{WithLatex(), WithLatexPreamble(`\newcommand{\x}{`), WithLatexFallback()}, nil, true, ""},
+		{"omml failure with fallback", `a $x$`, []TextOption{WithLatex(), WithLatexFallback()}, failOMML, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.omml != nil {
+				defer func(convert func(string) (*etree.Element, error)) { convertMathMLToOMML = convert }(convertMathMLToOMML)
+				convertMathMLToOMML = tt.omml
+			}
+			slide := newTestSlide(t, testShape(2, "Title 1", "title", 0, testRun("Hello")))
+			placeholder, err := slide.GetPlaceholder(PlaceholderTitle)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = placeholder.SetText(tt.text, tt.options...)
+			switch {
+			case tt.wantText != "":
+				if err != nil {
+					t.Fatalf("SetText() error = %v", err)
+				}
+				if got := placeholder.Text(); got != tt.wantText {
+					t.Errorf("Text() = %q, want %q", got, tt.wantText)
+				}
+			case err == nil:
+				t.Fatal("SetText() returned no error")
+			case errors.Is(err, ErrLatexSyntax) != tt.wantSyntax:
+				t.Errorf("errors.Is(%v, ErrLatexSyntax) = %v, want %v", err, !tt.wantSyntax, tt.wantSyntax)
+			case tt.omml != nil && !errors.Is(err, ErrInvalidEquation):
+				t.Errorf("errors.Is(%v, ErrInvalidEquation) = false, want true", err)
+			}
+			if err != nil && placeholder.Text() != "Hello" {
+				t.Errorf("Text() after a failed SetText = %q, want the original text", placeholder.Text())
+			}
+		})
+	}
+}
+
+func TestSetTextNumbersOnlyConvertedEquations(t *testing.T) {
+	slide := newTestSlide(t, testShape(2, "Content 1", "body", 1, testRun("Hello")))
+	placeholder, err := slide.GetPlaceholder(PlaceholderBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equations := &latex2mathml.Equations{}
+	text := `$$\begin{equation} x \label{x} \end{equation}$$`
+
+	convert := convertMathMLToOMML
+	convertMathMLToOMML = func(string) (*etree.Element, error) { return nil, errors.New("unsupported element") }
+	err = placeholder.SetText(text, WithLatex(), WithEquationNumbering(equations))
+	convertMathMLToOMML = convert
+	if err == nil {
+		t.Fatal("SetText() returned no error")
+	}
+	if _, ok := equations.Label("x"); ok || equations.Count() != 0 {
+		t.Fatalf("failed equation was numbered: count %d", equations.Count())
+	}
+
+	if err := placeholder.SetText(text, WithLatex(), WithEquationNumbering(equations)); err != nil {
+		t.Fatal(err)
+	}
+	if tag, _ := equations.Label("x"); tag != "1" {
+		t.Errorf("label x = %q, want 1", tag)
+	}
+}
+
+func TestTextLatexRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"escaped dollar", `costs \$5 and $x$`, `costs \$5 and $x$`},
+		{"backslash", `a\\b $y$`, `a\\b $y$`},
+		{"display equation", "\\$1\n$$z$$", "\\$1\n$$z$$"},
+		{"no equation", `costs \$5`, `costs $5`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slide := newTestSlide(t, testShape(2, "Content 1", "body", 1, testRun("Hello")))
+			placeholder, err := slide.GetPlaceholder(PlaceholderBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := placeholder.SetText(tt.text, WithLatex()); err != nil {
+				t.Fatal(err)
+			}
+			got := placeholder.Text()
+			if got != tt.want {
+				t.Fatalf("Text() = %q, want %q", got, tt.want)
+			}
+			if err := placeholder.SetText(got, WithLatex()); err != nil {
+				t.Fatal(err)
+			}
+			if again := placeholder.Text(); again != got {
+				t.Errorf("Text() after setting it again = %q, want %q", again, got)
+			}
+		})
+	}
+}
+
+// describeSegments 将片段表示为便于比较的字符串，T 为文本，L 为行内公式，D 为行间公式，M 为 MathML
+func describeSegments(segments []TextSegment) string {
+	var parts []string
+	for _, segment := range segments {
+		kind := "T"
+		switch {
+		case segment.IsMathML:
+			kind = "M"
+		case segment.IsLatex && segment.Display:
+			kind = "D"
+		case segment.IsLatex:
+			kind = "L"
+		}
+		parts = append(parts, kind+":"+segment.Text)
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestFormulaScanner(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		options []TextOption
+		want    string
+	}{
+		{"inline dollar", `a $x$ b`, []TextOption{WithLatex()}, `T:a |L:x|T: b`},
+		{"display dollar", `a $$x$$ b`, []TextOption{WithLatex()}, `T:a |D:x|T: b`},
+		{"parentheses and brackets", `\(x\) \[y\]`, []TextOption{WithLatex()}, `L:x|T: |D:y`},
+		{"escaped dollar", `\$5 and $x$`, []TextOption{WithLatex()}, `T:$5 and |L:x`},
+		{"escaped backslash", `a\\b`, []TextOption{WithLatex()}, `T:a\b`},
+		{"unclosed", `costs $5`, []TextOption{WithLatex()}, `T:costs $5`},
+		{"blank formula", `$ $`, []TextOption{WithLatex()}, `T:$ $`},
+		{"money without pandoc rule", `$5 and $10`, []TextOption{WithLatex()}, `L:5 and |T:10`},
+		{"money with pandoc rule", `$5 and $10`, []TextOption{WithLatex(), WithPandocDollarRule()}, `T:$5 and $10`},
+		{"pandoc rule formula", `$x$ and $ y$`, []TextOption{WithLatex(), WithPandocDollarRule()}, `L:x|T: and $ y$`},
+		{"custom delimiters", `a @@x@@ $y$`, []TextOption{WithLatex(), WithLatexDelimiters(LatexDelimiter{Open: "@@", Close: "@@"})}, `T:a |L:x|T: $y$`},
+		{"latex disabled", `a $x$`, nil, `T:a $x$`},
+		{"mathml", `a <math><mi>x</mi></math>`, []TextOption{WithMathML()}, `T:a |M:<math><mi>x</mi></math>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &TextOptions{}
+			for _, option := range tt.options {
+				option(opts)
+			}
+			if got := describeSegments(newFormulaScanner(opts).scan(tt.text)); got != tt.want {
+				t.Errorf("scan(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
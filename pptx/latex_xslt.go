@@ -0,0 +1,52 @@
+//go:build xslt
+
+package pptx
+
+import (
+	"fmt"
+	"sync"
+
+	_ "embed"
+
+	"github.com/beevik/etree"
+	xslt "github.com/wamuir/go-xslt"
+)
+
+// 使用 -tags xslt 构建时，改用 MATH2OMML.xsl（需要 cgo 和 libxslt）将 MathML 转换为 OMML，
+// 便于与原生转换器的结果进行对比
+
+//go:embed MATH2OMML.xsl
+var xsltContent []byte
+
+var (
+	mathmlToOmmlXslt     *xslt.Stylesheet
+	mathmlToOmmlXsltErr  error
+	mathmlToOmmlXsltOnce sync.Once
+)
+
+func init() {
+	convertMathMLToOMML = convertMathMLToOMMLUsingXSLT
+}
+
+// convertMathMLToOMMLUsingXSLT 使用 XSLT 将 MathML 转换为 m:oMath 元素
+func convertMathMLToOMMLUsingXSLT(mathml string) (*etree.Element, error) {
+	mathmlToOmmlXsltOnce.Do(func() {
+		mathmlToOmmlXslt, mathmlToOmmlXsltErr = xslt.NewStylesheet(xsltContent)
+	})
+	if mathmlToOmmlXsltErr != nil {
+		return nil, fmt.Errorf("failed to parse XSLT stylesheet: %w", mathmlToOmmlXsltErr)
+	}
+
+	result, err := mathmlToOmmlXslt.Transform([]byte(mathml))
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply XSLT transformation: %w", err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(result); err != nil {
+		return nil, fmt.Errorf("failed to parse OMML: %w", err)
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("no elements found in OMML")
+	}
+	return doc.Root(), nil
+}
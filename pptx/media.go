@@ -0,0 +1,179 @@
+package pptx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// mediaDir 媒体部件所在的目录
+const mediaDir = "ppt/media/"
+
+// MediaStats 媒体部件的统计信息
+type MediaStats struct {
+	Count          int   // 媒体部件数量
+	TotalBytes     int64 // 媒体部件未压缩的总大小
+	Duplicates     int   // 与其它媒体内容完全相同的部件数量
+	DuplicateBytes int64 // 重复部件占用的大小，即 CompactMedia 可以节省的大小
+}
+
+// MediaStats 统计演示文稿中的媒体部件，会读取所有媒体内容计算哈希
+func (p *Presentation) MediaStats() (MediaStats, error) {
+	var stats MediaStats
+	groups, err := p.mediaGroups()
+	if err != nil {
+		return stats, err
+	}
+	for _, group := range groups {
+		for i, part := range group {
+			stats.Count++
+			stats.TotalBytes += part.Size()
+			if i > 0 {
+				stats.Duplicates++
+				stats.DuplicateBytes += part.Size()
+			}
+		}
+	}
+	return stats, nil
+}
+
+// CompactMedia 合并内容相同的媒体部件，所有关系改为指向保留的第一个部件，返回删除的部件数量
+func (p *Presentation) CompactMedia() (int, error) {
+	groups, err := p.mediaGroups()
+	if err != nil {
+		return 0, err
+	}
+
+	// 重复部件 -> 保留的部件
+	replace := make(map[string]string)
+	for _, group := range groups {
+		for _, part := range group[1:] {
+			replace[part.Name()] = group[0].Name()
+		}
+	}
+	if len(replace) == 0 {
+		return 0, nil
+	}
+
+	if err := p.updateFiles(); err != nil {
+		return 0, err
+	}
+	for _, set := range p.relationshipSets() {
+		rels := set.rels
+		if rels == nil {
+			data, err := p.pkg.readPart(set.relsPath)
+			if err != nil {
+				return 0, err
+			}
+			if rels, err = parseRelationships(data); err != nil {
+				return 0, fmt.Errorf("failed to parse %s: %w", set.relsPath, err)
+			}
+		}
+
+		changed := false
+		for _, rel := range rels.All() {
+			if rel.TargetMode == "External" {
+				continue
+			}
+			if keep, ok := replace[resolvePartPath(set.source, rel.Target)]; ok {
+				rel.Target = relativePartPath(set.source, keep)
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		rels.dirty = true
+		if set.rels == nil {
+			if err := p.writeRelationships(set.relsPath, rels); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for name := range replace {
+		p.pkg.RemovePart(name)
+	}
+	return len(replace), nil
+}
+
+// addMedia 添加媒体部件，已有内容完全相同的媒体时直接复用，返回部件路径
+func (p *Presentation) addMedia(data []byte, ext, contentType string) (string, error) {
+	sum := sha256.Sum256(data)
+	for _, part := range p.pkg.Parts() {
+		if !strings.HasPrefix(part.Name(), mediaDir) || part.Size() != int64(len(data)) {
+			continue
+		}
+		hash, err := part.hash()
+		if err != nil {
+			return "", err
+		}
+		if bytes.Equal(hash, sum[:]) {
+			return part.Name(), nil
+		}
+	}
+
+	name := p.pkg.NextPartName(mediaDir + "image%d" + ext)
+	part := p.pkg.AddPart(name, contentType, data)
+	part.sum = sum[:]
+	return name, nil
+}
+
+// mediaGroups 将媒体部件按内容分组，每组按部件顺序排列，组的顺序与第一个部件的顺序一致
+func (p *Presentation) mediaGroups() ([][]*Part, error) {
+	var groups [][]*Part
+	index := make(map[string]int)
+	for _, part := range p.pkg.Parts() {
+		if !strings.HasPrefix(part.Name(), mediaDir) {
+			continue
+		}
+		hash, err := part.hash()
+		if err != nil {
+			return nil, err
+		}
+		key := string(hash)
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], part)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []*Part{part})
+	}
+	return groups, nil
+}
+
+// relationshipSet 表示一个部件的关系集合
+type relationshipSet struct {
+	source   string         // 关系所属的部件，包级关系为空字符串
+	relsPath string         // 关系文件路径
+	rels     *Relationships // 已加载到内存的关系，为 nil 时需要从包中读取
+}
+
+// relationshipSets 返回包中所有的关系文件，幻灯片、布局、母版和演示文稿使用内存中的关系
+func (p *Presentation) relationshipSets() []relationshipSet {
+	loaded := map[string]*Relationships{relsPathFor(presentationPath): p.rels}
+	for _, slide := range p.slides {
+		loaded[slide.relsPath] = slide.rels
+	}
+	for _, master := range p.masters {
+		loaded[master.relsPath] = master.rels
+		for _, layout := range master.layouts {
+			loaded[layout.relsPath] = layout.rels
+		}
+	}
+
+	var sets []relationshipSet
+	for _, part := range p.pkg.Parts() {
+		name := part.Name()
+		if !strings.HasSuffix(name, ".rels") {
+			continue
+		}
+		sets = append(sets, relationshipSet{
+			source:   sourcePartFor(name),
+			relsPath: name,
+			rels:     loaded[name],
+		})
+	}
+	return sets
+}
@@ -0,0 +1,80 @@
+package pptx
+
+import "testing"
+
+func TestSetImageDeduplicatesMedia(t *testing.T) {
+	pres := openTemplate(t)
+	source := WithImageSource(MemorySource{"a.png": testPNG("a"), "copy.png": testPNG("a"), "b.png": testPNG("b")})
+	var targets []string
+	for _, ref := range []string{"a.png", "copy.png", "b.png"} {
+		slide, err := pres.AddSlide("图片与标题")
+		if err != nil {
+			t.Fatal(err)
+		}
+		picture, err := slide.GetPlaceholder(PlaceholderImage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := picture.SetImage(ref, source); err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, resolvePartPath(slide.path, slide.rels.FirstByType(RelTypeImage).Target))
+	}
+
+	if targets[0] != targets[1] {
+		t.Errorf("identical images stored as %s and %s", targets[0], targets[1])
+	}
+	if targets[0] == targets[2] {
+		t.Errorf("different images share %s", targets[0])
+	}
+	stats, err := pres.MediaStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Duplicates != 0 || stats.DuplicateBytes != 0 {
+		t.Errorf("MediaStats() = %+v, want no duplicates", stats)
+	}
+}
+
+func TestCompactMedia(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("图片与标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	picture, err := slide.GetPlaceholder(PlaceholderImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := picture.SetImage("a.png", WithImageSource(MemorySource{"a.png": testPNG("a")})); err != nil {
+		t.Fatal(err)
+	}
+	kept := resolvePartPath(slide.path, slide.rels.FirstByType(RelTypeImage).Target)
+
+	// 模拟其他程序写入的重复媒体
+	duplicate := pres.pkg.NextPartName(mediaDir + "image%d.png")
+	pres.pkg.AddPart(duplicate, "image/png", testPNG("a"))
+	rId := slide.rels.Add(RelTypeImage, relativePartPath(slide.path, duplicate), "")
+
+	stats, err := pres.MediaStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Duplicates != 1 || stats.DuplicateBytes != int64(len(testPNG("a"))) {
+		t.Errorf("MediaStats() = %+v, want one duplicate", stats)
+	}
+
+	removed, err := pres.CompactMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || pres.pkg.Part(duplicate) != nil {
+		t.Fatalf("CompactMedia() = %d, duplicate part kept: %v", removed, pres.pkg.Part(duplicate) != nil)
+	}
+	if target := resolvePartPath(slide.path, slide.rels.Get(rId).Target); target != kept {
+		t.Errorf("relationship %s points to %s, want %s", rId, target, kept)
+	}
+	if removed, err := pres.CompactMedia(); err != nil || removed != 0 {
+		t.Errorf("second CompactMedia() = %d, %v, want 0", removed, err)
+	}
+}
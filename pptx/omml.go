@@ -0,0 +1,716 @@
+package pptx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/beevik/etree"
+)
+
+// NsMath OMML（Office Math Markup Language）的命名空间
+const NsMath = "http://schemas.openxmlformats.org/officeDocument/2006/math"
+
+// naryOperators 转换为 m:nary 的大型运算符，值为 true 时上下限放在右侧（积分类）
+var naryOperators = map[string]bool{
+	"∑": false, "∏": false, "∐": false,
+	"⋃": false, "⋂": false, "⋁": false, "⋀": false,
+	"⨀": false, "⨁": false, "⨂": false, "⨄": false, "⨆": false,
+	"∫": true, "∬": true, "∭": true, "⨌": true,
+	"∮": true, "∯": true, "∰": true, "∱": true, "∲": true, "∳": true,
+}
+
+// mathFunctions 转换为 m:func 的函数名
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true,
+	"sinh": true, "cosh": true, "tanh": true, "coth": true,
+	"log": true, "ln": true, "lg": true, "exp": true,
+	"lim": true, "liminf": true, "limsup": true, "max": true, "min": true, "sup": true, "inf": true,
+	"det": true, "gcd": true, "arg": true, "deg": true, "dim": true, "hom": true, "ker": true,
+	"Pr": true, "sgn": true,
+}
+
+// accentChars 上方重音符号与 m:acc 使用的组合字符
+var accentChars = map[string]string{
+	"^": "̂", "ˆ": "̂", "̂": "̂",
+	"~": "̃", "˜": "̃", "̃": "̃",
+	"¯": "̅", "̄": "̅", "̅": "̅",
+	"˙": "̇", "̇": "̇",
+	"¨": "̈", "̈": "̈",
+	"˚": "̊", "̊": "̊",
+	"ˇ": "̌", "̌": "̌",
+	"˘": "̆", "̆": "̆",
+	"´": "́", "́": "́",
+	"`": "̀", "̀": "̀",
+	"→": "⃗", "⃗": "⃗",
+	"←": "⃖", "⃖": "⃖",
+	"↔": "⃡", "⃡": "⃡",
+}
+
+// barChars 转换为 m:bar 的上划线和下划线字符
+var barChars = map[string]bool{"―": true, "‾": true, "_": true, "̲": true, "−": true}
+
+// groupChars 转换为 m:groupChr 的括号字符，值为字符位置
+var groupChars = map[string]string{"⏞": "top", "⏟": "bot", "︷": "top", "︸": "bot", "⎴": "top", "⎵": "bot", "⏜": "top", "⏝": "bot"}
+
+// operandBreaks 结束 n 元运算符和函数参数的运算符
+var operandBreaks = map[string]bool{
+	"=": true, "≠": true, "<": true, ">": true, "≤": true, "≥": true, "≈": true, "≡": true,
+	"∼": true, "≃": true, "≅": true, "∝": true, "→": true, "⟶": true, "⇒": true, "⟹": true,
+	"⇔": true, "⟺": true, "↦": true, ",": true, ";": true, "+": true, "-": true, "−": true,
+	"±": true, "∓": true, "∈": true, "∉": true, "⊂": true, "⊆": true, "⊃": true, "⊇": true,
+}
+
+// mathMLToOMML 将 MathML 转换为 m:oMath 元素
+func mathMLToOMML(mathml string) (*etree.Element, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(mathml); err != nil {
+		return nil, fmt.Errorf("failed to parse MathML: %w", err)
+	}
+	root := doc.Root()
+	if root == nil || root.Tag != "math" {
+		return nil, fmt.Errorf("MathML root element must be math")
+	}
+
+	oMath := etree.NewElement("m:oMath")
+	oMath.CreateAttr("xmlns:m", NsMath)
+	appendOMML(oMath, root.ChildElements())
+	return oMath, nil
+}
+
+// appendOMML 将一行 MathML 元素转换后追加到 parent 中
+// n 元运算符、函数名和成对的伸缩括号需要结合后面的兄弟元素处理，因此按行转换
+func appendOMML(parent *etree.Element, nodes []*etree.Element) {
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+
+		if chr, ok := naryOperator(node); ok {
+			end := operandEnd(nodes, i+1)
+			parent.AddChild(naryToOMML(node, chr, nodes[i+1:end]))
+			i = end - 1
+			continue
+		}
+
+		if isMathFunction(node) {
+			start := i + 1
+			if start < len(nodes) && isFunctionApplication(nodes[start]) {
+				// m:func 本身表示函数应用，不保留不可见的 U+2061
+				start++
+			}
+			end := operandEnd(nodes, start)
+			if end == start {
+				convertMathML(parent, node)
+				i = start - 1
+				continue
+			}
+			fn := parent.CreateElement("m:func")
+			convertMathML(fn.CreateElement("m:fName"), node)
+			appendOMML(fn.CreateElement("m:e"), nodes[start:end])
+			i = end - 1
+			continue
+		}
+
+		if isSizedDelimiter(nodes, i) {
+			// 矩阵和二项式两侧的括号
+			d := parent.CreateElement("m:d")
+			dPr := d.CreateElement("m:dPr")
+			setMathVal(dPr.CreateElement("m:begChr"), node.Text())
+			setMathVal(dPr.CreateElement("m:endChr"), nodes[i+2].Text())
+			convertMathML(d.CreateElement("m:e"), nodes[i+1])
+			i += 2
+			continue
+		}
+
+		if isOpeningFence(node) {
+			end := closingFence(nodes, i)
+			d := parent.CreateElement("m:d")
+			dPr := d.CreateElement("m:dPr")
+			setMathVal(dPr.CreateElement("m:begChr"), node.Text())
+			if end < len(nodes) {
+				setMathVal(dPr.CreateElement("m:endChr"), nodes[end].Text())
+				appendOMML(d.CreateElement("m:e"), nodes[i+1:end])
+			} else {
+				// 没有闭合括号时（如 cases 环境）只显示左括号
+				setMathVal(dPr.CreateElement("m:endChr"), "")
+				appendOMML(d.CreateElement("m:e"), nodes[i+1:])
+			}
+			i = end
+			continue
+		}
+
+		convertMathML(parent, node)
+	}
+}
+
+// convertMathML 将单个 MathML 元素转换后追加到 parent 中
+func convertMathML(parent *etree.Element, node *etree.Element) {
+	children := node.ChildElements()
+	switch node.Tag {
+	case "mi", "mn", "mo", "mtext", "ms":
+		appendMathRun(parent, node)
+
+	case "mspace":
+		if space := mathSpace(node.SelectAttrValue("width", "")); space != "" {
+			appendRun(parent, space, nil)
+		}
+
+	case "mrow", "mstyle", "mpadded", "merror", "math":
+		appendOMML(parent, children)
+
+	case "semantics", "maction":
+		if len(children) > 0 {
+			convertMathML(parent, children[0])
+		}
+
+	case "mfrac":
+		f := parent.CreateElement("m:f")
+		switch {
+		case isZeroThickness(node.SelectAttrValue("linethickness", "")):
+			setMathVal(f.CreateElement("m:fPr").CreateElement("m:type"), "noBar")
+		case node.SelectAttrValue("bevelled", "") == "true":
+			setMathVal(f.CreateElement("m:fPr").CreateElement("m:type"), "skw")
+		}
+		convertArg(f.CreateElement("m:num"), children, 0)
+		convertArg(f.CreateElement("m:den"), children, 1)
+
+	case "msqrt":
+		rad := parent.CreateElement("m:rad")
+		setMathVal(rad.CreateElement("m:radPr").CreateElement("m:degHide"), "1")
+		rad.CreateElement("m:deg")
+		appendOMML(rad.CreateElement("m:e"), children)
+
+	case "mroot":
+		rad := parent.CreateElement("m:rad")
+		rad.CreateElement("m:radPr")
+		convertArg(rad.CreateElement("m:deg"), children, 1)
+		convertArg(rad.CreateElement("m:e"), children, 0)
+
+	case "msub":
+		s := parent.CreateElement("m:sSub")
+		convertArg(s.CreateElement("m:e"), children, 0)
+		convertArg(s.CreateElement("m:sub"), children, 1)
+
+	case "msup":
+		s := parent.CreateElement("m:sSup")
+		convertArg(s.CreateElement("m:e"), children, 0)
+		convertArg(s.CreateElement("m:sup"), children, 1)
+
+	case "msubsup":
+		s := parent.CreateElement("m:sSubSup")
+		convertArg(s.CreateElement("m:e"), children, 0)
+		convertArg(s.CreateElement("m:sub"), children, 1)
+		convertArg(s.CreateElement("m:sup"), children, 2)
+
+	case "mmultiscripts":
+		convertMultiscripts(parent, children)
+
+	case "mover":
+		convertOver(parent, node, children)
+
+	case "munder":
+		convertUnder(parent, children)
+
+	case "munderover":
+		upp := parent.CreateElement("m:limUpp")
+		low := upp.CreateElement("m:e").CreateElement("m:limLow")
+		convertArg(low.CreateElement("m:e"), children, 0)
+		convertArg(low.CreateElement("m:lim"), children, 1)
+		convertArg(upp.CreateElement("m:lim"), children, 2)
+
+	case "mtable":
+		if isEquationArray(node) {
+			convertEquationArray(parent, node)
+		} else {
+			convertTable(parent, node)
+		}
+
+	case "mfenced":
+		d := parent.CreateElement("m:d")
+		dPr := d.CreateElement("m:dPr")
+		setMathVal(dPr.CreateElement("m:begChr"), node.SelectAttrValue("open", "("))
+		if separators := strings.TrimSpace(node.SelectAttrValue("separators", ",")); separators != "" {
+			r, _ := utf8.DecodeRuneInString(separators)
+			setMathVal(dPr.CreateElement("m:sepChr"), string(r))
+		}
+		setMathVal(dPr.CreateElement("m:endChr"), node.SelectAttrValue("close", ")"))
+		for _, child := range children {
+			convertMathML(d.CreateElement("m:e"), child)
+		}
+
+	case "mphantom":
+		appendOMML(parent.CreateElement("m:phant").CreateElement("m:e"), children)
+
+	case "menclose":
+		appendOMML(parent.CreateElement("m:borderBox").CreateElement("m:e"), children)
+
+	case "none", "mprescripts", "annotation", "annotation-xml":
+		// 没有可见内容
+
+	default:
+		if len(children) > 0 {
+			appendOMML(parent, children)
+		} else if text := node.Text(); text != "" {
+			appendRun(parent, text, nil)
+		}
+	}
+}
+
+// convertArg 转换第 i 个子元素作为参数，不存在时保留空参数
+func convertArg(parent *etree.Element, children []*etree.Element, i int) {
+	if i < len(children) {
+		convertMathML(parent, children[i])
+	}
+}
+
+// convertOver 转换 mover：重音、上划线、上方括号或上方极限
+func convertOver(parent *etree.Element, node *etree.Element, children []*etree.Element) {
+	if len(children) == 2 {
+		over := strings.TrimSpace(scriptText(children[1]))
+		if barChars[over] {
+			bar := parent.CreateElement("m:bar")
+			setMathVal(bar.CreateElement("m:barPr").CreateElement("m:pos"), "top")
+			convertMathML(bar.CreateElement("m:e"), children[0])
+			return
+		}
+		if pos, ok := groupChars[over]; ok {
+			convertGroupChr(parent, children[0], over, pos)
+			return
+		}
+		if chr, ok := accentChars[over]; ok && (children[1].Tag == "mo" || node.SelectAttrValue("accent", "") == "true") {
+			acc := parent.CreateElement("m:acc")
+			setMathVal(acc.CreateElement("m:accPr").CreateElement("m:chr"), chr)
+			convertMathML(acc.CreateElement("m:e"), children[0])
+			return
+		}
+	}
+
+	upp := parent.CreateElement("m:limUpp")
+	convertArg(upp.CreateElement("m:e"), children, 0)
+	convertArg(upp.CreateElement("m:lim"), children, 1)
+}
+
+// convertUnder 转换 munder：下划线、下方括号或下方极限
+func convertUnder(parent *etree.Element, children []*etree.Element) {
+	if len(children) == 2 {
+		under := strings.TrimSpace(scriptText(children[1]))
+		if barChars[under] {
+			bar := parent.CreateElement("m:bar")
+			setMathVal(bar.CreateElement("m:barPr").CreateElement("m:pos"), "bot")
+			convertMathML(bar.CreateElement("m:e"), children[0])
+			return
+		}
+		if pos, ok := groupChars[under]; ok {
+			convertGroupChr(parent, children[0], under, pos)
+			return
+		}
+	}
+
+	low := parent.CreateElement("m:limLow")
+	convertArg(low.CreateElement("m:e"), children, 0)
+	convertArg(low.CreateElement("m:lim"), children, 1)
+}
+
+// convertGroupChr 转换 \overbrace、\underbrace 等分组括号
+func convertGroupChr(parent *etree.Element, base *etree.Element, chr, pos string) {
+	group := parent.CreateElement("m:groupChr")
+	groupPr := group.CreateElement("m:groupChrPr")
+	setMathVal(groupPr.CreateElement("m:chr"), chr)
+	setMathVal(groupPr.CreateElement("m:pos"), pos)
+	if pos == "top" {
+		setMathVal(groupPr.CreateElement("m:vertJc"), "bot")
+	} else {
+		setMathVal(groupPr.CreateElement("m:vertJc"), "top")
+	}
+	convertMathML(group.CreateElement("m:e"), base)
+}
+
+// convertMultiscripts 转换 mmultiscripts，只使用第一组前置和后置上下标
+func convertMultiscripts(parent *etree.Element, children []*etree.Element) {
+	if len(children) == 0 {
+		return
+	}
+	var post, pre []*etree.Element
+	prescripts := false
+	for _, child := range children[1:] {
+		switch {
+		case child.Tag == "mprescripts":
+			prescripts = true
+		case prescripts:
+			pre = append(pre, child)
+		default:
+			post = append(post, child)
+		}
+	}
+
+	target := parent
+	if len(pre) > 0 {
+		sPre := parent.CreateElement("m:sPre")
+		convertArg(sPre.CreateElement("m:sub"), pre, 0)
+		convertArg(sPre.CreateElement("m:sup"), pre, 1)
+		target = sPre.CreateElement("m:e")
+	}
+	if len(post) > 0 {
+		s := target.CreateElement("m:sSubSup")
+		convertMathML(s.CreateElement("m:e"), children[0])
+		convertArg(s.CreateElement("m:sub"), post, 0)
+		convertArg(s.CreateElement("m:sup"), post, 1)
+		return
+	}
+	convertMathML(target, children[0])
+}
+
+// convertTable 将 mtable 转换为 m:m 矩阵
+func convertTable(parent *etree.Element, table *etree.Element) {
+	rows := table.ChildElements()
+	columns := 0
+	for _, row := range rows {
+		if n := len(tableCells(row)); n > columns {
+			columns = n
+		}
+	}
+	if columns == 0 {
+		return
+	}
+
+	m := parent.CreateElement("m:m")
+	mPr := m.CreateElement("m:mPr")
+	setMathVal(mPr.CreateElement("m:baseJc"), "center")
+	setMathVal(mPr.CreateElement("m:plcHide"), "on")
+	mcs := mPr.CreateElement("m:mcs")
+	aligns := strings.Fields(table.SelectAttrValue("columnalign", ""))
+	for col := 0; col < columns; col++ {
+		align := "center"
+		if col < len(aligns) {
+			align = aligns[col]
+		} else if len(aligns) > 0 {
+			align = aligns[len(aligns)-1]
+		}
+		if cells := tableCells(rows[0]); col < len(cells) {
+			align = cells[col].SelectAttrValue("columnalign", align)
+		}
+		mcPr := mcs.CreateElement("m:mc").CreateElement("m:mcPr")
+		setMathVal(mcPr.CreateElement("m:count"), "1")
+		setMathVal(mcPr.CreateElement("m:mcJc"), align)
+	}
+
+	for _, row := range rows {
+		mr := m.CreateElement("m:mr")
+		cells := tableCells(row)
+		for col := 0; col < columns; col++ {
+			e := mr.CreateElement("m:e")
+			if col < len(cells) {
+				appendOMML(e, cells[col].ChildElements())
+			}
+		}
+	}
+}
+
+// isEquationArray 判断 mtable 是否为 align、gather 等环境生成的多行公式或带编号的公式，表格线不能用 m:eqArr 表示
+func isEquationArray(table *etree.Element) bool {
+	for _, attr := range []string{"frame", "rowlines", "columnlines"} {
+		if value := table.SelectAttrValue(attr, "none"); value != "" && value != "none" {
+			return false
+		}
+	}
+	if table.SelectAttrValue("displaystyle", "") == "true" {
+		return true
+	}
+	return table.SelectElement("mlabeledtr") != nil
+}
+
+// convertEquationArray 将 mtable 转换为 m:eqArr，单元格之间插入 & 作为对齐点
+// mlabeledtr 的编号以 # 分隔放在行末，与在 Office 中输入 公式#(1) 的结果一致
+func convertEquationArray(parent *etree.Element, table *etree.Element) {
+	eqArr := parent.CreateElement("m:eqArr")
+	for _, row := range table.ChildElements() {
+		if row.Tag != "mtr" && row.Tag != "mlabeledtr" {
+			continue
+		}
+		e := eqArr.CreateElement("m:e")
+		for i, cell := range tableCells(row) {
+			if i > 0 {
+				appendRun(e, "&", nil)
+			}
+			appendOMML(e, cell.ChildElements())
+		}
+		if cells := row.SelectElements("mtd"); row.Tag == "mlabeledtr" && len(cells) > 0 {
+			appendRun(e, "#", nil)
+			appendOMML(e, cells[0].ChildElements())
+		}
+	}
+}
+
+// tableCells 获取表格行中的单元格，mlabeledtr 的第一个单元格是编号，不属于矩阵内容
+func tableCells(row *etree.Element) []*etree.Element {
+	cells := row.SelectElements("mtd")
+	if row.Tag == "mlabeledtr" && len(cells) > 0 {
+		cells = cells[1:]
+	}
+	return cells
+}
+
+// naryToOMML 创建 m:nary，operand 为运算符作用的元素
+func naryToOMML(node *etree.Element, chr string, operand []*etree.Element) *etree.Element {
+	nary := etree.NewElement("m:nary")
+	naryPr := nary.CreateElement("m:naryPr")
+	setMathVal(naryPr.CreateElement("m:chr"), chr)
+	if naryOperators[chr] && node.Tag != "munder" && node.Tag != "mover" && node.Tag != "munderover" {
+		setMathVal(naryPr.CreateElement("m:limLoc"), "subSup")
+	} else {
+		setMathVal(naryPr.CreateElement("m:limLoc"), "undOvr")
+	}
+
+	var sub, sup *etree.Element
+	children := node.ChildElements()
+	switch node.Tag {
+	case "msub", "munder":
+		sub = children[1]
+	case "msup", "mover":
+		sup = children[1]
+	case "msubsup", "munderover":
+		sub, sup = children[1], children[2]
+	}
+	if sub == nil {
+		setMathVal(naryPr.CreateElement("m:subHide"), "1")
+	}
+	if sup == nil {
+		setMathVal(naryPr.CreateElement("m:supHide"), "1")
+	}
+
+	subEl := nary.CreateElement("m:sub")
+	if sub != nil {
+		convertMathML(subEl, sub)
+	}
+	supEl := nary.CreateElement("m:sup")
+	if sup != nil {
+		convertMathML(supEl, sup)
+	}
+	appendOMML(nary.CreateElement("m:e"), operand)
+	return nary
+}
+
+// naryOperator 判断元素是否为（可能带上下限的）n 元运算符，返回运算符字符
+func naryOperator(node *etree.Element) (string, bool) {
+	base := node
+	switch node.Tag {
+	case "mi", "mo":
+	case "msub", "msup", "msubsup", "munder", "mover", "munderover":
+		children := node.ChildElements()
+		if len(children) < 2 || (len(children) < 3 && (node.Tag == "msubsup" || node.Tag == "munderover")) {
+			return "", false
+		}
+		base = children[0]
+		if base.Tag != "mi" && base.Tag != "mo" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	chr := strings.TrimSpace(base.Text())
+	_, ok := naryOperators[chr]
+	return chr, ok
+}
+
+// isMathFunction 判断元素是否为（可能带下标或下方极限的）函数名
+func isMathFunction(node *etree.Element) bool {
+	switch node.Tag {
+	case "mi", "mo":
+		return isFunctionName(node)
+	case "msub", "msup", "msubsup", "munder", "munderover":
+		children := node.ChildElements()
+		return len(children) > 0 && isFunctionName(children[0])
+	}
+	return false
+}
+
+// isFunctionName 判断 mi 或 mo 是否为函数名，由字母组成的 mo 来自 \operatorname 或 \DeclareMathOperator，同样按函数处理
+func isFunctionName(node *etree.Element) bool {
+	switch node.Tag {
+	case "mi":
+		return mathFunctions[functionName(node.Text())]
+	case "mo":
+		return mathFunctions[functionName(node.Text())] || isWord(node.Text())
+	}
+	return false
+}
+
+// isFunctionApplication 判断元素是否为 MathJax 等工具在函数名之后生成的 <mo>&#x2061;</mo>
+func isFunctionApplication(node *etree.Element) bool {
+	return node.Tag == "mo" && strings.TrimSpace(node.Text()) == "\u2061"
+}
+
+// functionName 去掉函数名中的空白，如 "lim inf"
+func functionName(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == ' ' || r == ' ' || r == ' ' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// operandEnd 获取从 start 开始的运算对象的结束位置，遇到关系符、逗号或加减号时结束
+func operandEnd(nodes []*etree.Element, start int) int {
+	end := start
+	for end < len(nodes) {
+		node := nodes[end]
+		if (node.Tag == "mo" || node.Tag == "mi") && operandBreaks[strings.TrimSpace(node.Text())] {
+			break
+		}
+		if isClosingFence(node) {
+			break
+		}
+		if isOpeningFence(node) {
+			end = closingFence(nodes, end)
+		}
+		end++
+	}
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+	return end
+}
+
+// isOpeningFence 判断元素是否为 \left 等生成的伸缩左括号
+func isOpeningFence(node *etree.Element) bool {
+	return node.Tag == "mo" && node.SelectAttrValue("fence", "") == "true" && node.SelectAttrValue("form", "") == "prefix"
+}
+
+// isClosingFence 判断元素是否为 \right 等生成的伸缩右括号
+func isClosingFence(node *etree.Element) bool {
+	return node.Tag == "mo" && node.SelectAttrValue("fence", "") == "true" && node.SelectAttrValue("form", "") == "postfix"
+}
+
+// isSizedDelimiter 判断 nodes[i:i+3] 是否为用括号包围的矩阵或指定了大小的括号，如 pmatrix 和 \binom
+func isSizedDelimiter(nodes []*etree.Element, i int) bool {
+	if i+2 >= len(nodes) || nodes[i].Tag != "mo" || nodes[i+2].Tag != "mo" {
+		return false
+	}
+	if !strings.Contains("([{|‖⟨⌊⌈", nodes[i].Text()) || nodes[i].Text() == "" {
+		return false
+	}
+	return nodes[i+1].Tag == "mtable" || nodes[i].SelectAttr("minsize") != nil
+}
+
+// closingFence 查找与 nodes[open] 配对的右括号，找不到时返回 len(nodes)
+func closingFence(nodes []*etree.Element, open int) int {
+	depth := 0
+	for i := open + 1; i < len(nodes); i++ {
+		switch {
+		case isOpeningFence(nodes[i]):
+			depth++
+		case isClosingFence(nodes[i]):
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return len(nodes)
+}
+
+// appendMathRun 将 mi、mn、mo、mtext 等文本元素转换为 m:r
+func appendMathRun(parent *etree.Element, node *etree.Element) {
+	text := node.Text()
+	if node.Tag == "ms" {
+		text = node.SelectAttrValue("lquote", "\"") + text + node.SelectAttrValue("rquote", "\"")
+	}
+	if text == "" {
+		return
+	}
+
+	rPr := etree.NewElement("m:rPr")
+	if node.Tag == "mtext" || node.Tag == "ms" {
+		rPr.CreateElement("m:nor")
+	}
+
+	variant := node.SelectAttrValue("mathvariant", "")
+	switch variant {
+	case "double-struck", "script", "fraktur", "sans-serif", "monospace":
+		setMathVal(rPr.CreateElement("m:scr"), variant)
+	case "bold-script", "bold-fraktur", "bold-sans-serif":
+		setMathVal(rPr.CreateElement("m:scr"), strings.TrimPrefix(variant, "bold-"))
+	}
+
+	switch {
+	case strings.HasPrefix(variant, "bold") && strings.Contains(variant, "italic"):
+		setMathVal(rPr.CreateElement("m:sty"), "bi")
+	case strings.HasPrefix(variant, "bold"):
+		setMathVal(rPr.CreateElement("m:sty"), "b")
+	case variant == "normal" || variant == "double-struck" || (node.Tag == "mi" || node.Tag == "mo") && isWord(text):
+		setMathVal(rPr.CreateElement("m:sty"), "p")
+	}
+
+	appendRun(parent, text, rPr)
+}
+
+// appendRun 追加 m:r，rPr 没有子元素时省略
+func appendRun(parent *etree.Element, text string, rPr *etree.Element) {
+	r := parent.CreateElement("m:r")
+	if rPr != nil && len(rPr.ChildElements()) > 0 {
+		r.AddChild(rPr)
+	}
+	t := r.CreateElement("m:t")
+	if strings.TrimSpace(text) != text {
+		t.CreateAttr("xml:space", "preserve")
+	}
+	t.SetText(text)
+}
+
+// setMathVal 设置 m:val 属性
+func setMathVal(el *etree.Element, val string) {
+	el.CreateAttr("m:val", val)
+}
+
+// scriptText 获取上下标元素的文本，用于识别重音和括号字符
+func scriptText(node *etree.Element) string {
+	if node.Tag == "mrow" {
+		children := node.ChildElements()
+		if len(children) != 1 {
+			return ""
+		}
+		return scriptText(children[0])
+	}
+	return node.Text()
+}
+
+// isZeroThickness 判断分数线宽度是否为 0，如 "0"、"0pt"、"0em"
+func isZeroThickness(value string) bool {
+	number := strings.TrimRight(strings.TrimSpace(value), "abcdefghijklmnopqrstuvwxyz%")
+	f, err := strconv.ParseFloat(number, 64)
+	return err == nil && f == 0
+}
+
+// mathSpace 根据 mspace 的宽度（单位为 em）选择空白字符
+func mathSpace(width string) string {
+	em, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(width), "em"), 64)
+	switch {
+	case err != nil:
+		return " "
+	case em <= 0:
+		return ""
+	case em >= 1:
+		return "\u2003"
+	case em >= 0.5:
+		return "\u2002"
+	default:
+		return "\u2009"
+	}
+}
+
+// isWord 判断文本是否由字母组成，如 sin、lim，允许单词之间有空格，如 arg max
+func isWord(text string) bool {
+	letters := 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case !unicode.IsSpace(r):
+			return false
+		}
+	}
+	return letters > 1
+}
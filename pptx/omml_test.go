@@ -0,0 +1,140 @@
+package pptx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+// latexOMML 使用纯 Go 实现将 LaTeX 转换为 OMML，同时返回序列化的结果
+func latexOMML(t *testing.T, latex string) (*etree.Element, string) {
+	t.Helper()
+	mathml, err := LatexToMathML(latex, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oMath, err := mathMLToOMML(mathml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oMath, ommlString(t, oMath)
+}
+
+// ommlString 序列化 OMML 元素
+func ommlString(t *testing.T, oMath *etree.Element) string {
+	t.Helper()
+	doc := etree.NewDocument()
+	doc.AddChild(oMath.Copy())
+	xml, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xml
+}
+
+func TestMathMLToOMML(t *testing.T) {
+	tests := []struct {
+		name   string
+		mathml string
+		want   string
+	}{
+		{"fraction", `<math><mfrac><mi>a</mi><mn>2</mn></mfrac></math>`, `f(num(a) den(2))`},
+		{"fraction without bar", `<math><mfrac linethickness="0"><mi>n</mi><mi>k</mi></mfrac></math>`, `f(fPr(type=noBar) num(n) den(k))`},
+		{"subscript", `<math><msub><mi>x</mi><mn>1</mn></msub></math>`, `sSub(e(x) sub(1))`},
+		{"superscript", `<math><msup><mi>x</mi><mn>2</mn></msup></math>`, `sSup(e(x) sup(2))`},
+		{"subscript and superscript", `<math><msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup></math>`, `sSubSup(e(x) sub(i) sup(2))`},
+		{"square root", `<math><msqrt><mi>x</mi></msqrt></math>`, `rad(radPr(degHide=1) deg() e(x))`},
+		{"root with degree", `<math><mroot><mi>x</mi><mn>3</mn></mroot></math>`, `rad(radPr() deg(3) e(x))`},
+		{"sum with limits", `<math><munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><msub><mi>a</mi><mi>i</mi></msub><mo>=</mo><mi>s</mi></math>`, `nary(naryPr(chr=∑ limLoc=undOvr) sub(i = 1) sup(n) e(sSub(e(a) sub(i)))) = s`},
+		{"integral with limits", `<math><msubsup><mo>∫</mo><mn>0</mn><mn>1</mn></msubsup><mi>f</mi><mi>d</mi><mi>x</mi></math>`, `nary(naryPr(chr=∫ limLoc=subSup) sub(0) sup(1) e(f d x))`},
+		{"product with lower limit only", `<math><munder><mo>∏</mo><mi>i</mi></munder><msub><mi>x</mi><mi>i</mi></msub></math>`, `nary(naryPr(chr=∏ limLoc=undOvr supHide=1) sub(i) sup() e(sSub(e(x) sub(i))))`},
+		{"mfenced with separators", `<math><mfenced open="[" close=")"><mi>a</mi><mi>b</mi></mfenced></math>`, `d(dPr(begChr=[ sepChr=, endChr=)) e(a) e(b))`},
+		{"mfenced defaults", `<math><mfenced><mi>x</mi></mfenced></math>`, `d(dPr(begChr=( sepChr=, endChr=)) e(x))`},
+		{"stretchy fences", `<math><mo fence="true" form="prefix" stretchy="true">(</mo><mi>a</mi><mo>+</mo><mi>b</mi><mo fence="true" form="postfix" stretchy="true">)</mo></math>`, `d(dPr(begChr=( endChr=)) e(a + b))`},
+		{"fence without closing (cases)", `<math><mo fence="true" form="prefix" stretchy="true">{</mo><mi>a</mi></math>`, `d(dPr(begChr={ endChr=) e(a))`},
+		{"plain parentheses stay runs", `<math><mo>(</mo><mi>a</mi><mo>)</mo></math>`, `( a )`},
+		{"binomial with sized parentheses", `<math><mo minsize="2em">(</mo><mfrac linethickness="0"><mi>n</mi><mi>k</mi></mfrac><mo minsize="2em">)</mo></math>`, `d(dPr(begChr=( endChr=)) e(f(fPr(type=noBar) num(n) den(k))))`},
+		{"matrix", `<math><mtable><mtr><mtd><mn>1</mn></mtd><mtd><mn>2</mn></mtd></mtr><mtr><mtd><mn>3</mn></mtd><mtd><mn>4</mn></mtd></mtr></mtable></math>`, `m(mPr(baseJc=center plcHide=on mcs(mc(mcPr(count=1 mcJc=center)) mc(mcPr(count=1 mcJc=center)))) mr(e(1) e(2)) mr(e(3) e(4)))`},
+		{"matrix in parentheses", `<math><mo>(</mo><mtable><mtr><mtd><mn>1</mn></mtd></mtr></mtable><mo>)</mo></math>`, `d(dPr(begChr=( endChr=)) e(m(mPr(baseJc=center plcHide=on mcs(mc(mcPr(count=1 mcJc=center)))) mr(e(1)))))`},
+		{"hat accent", `<math><mover><mi>x</mi><mo>^</mo></mover></math>`, `acc(accPr(chr=̂) e(x))`},
+		{"vector accent", `<math><mover><mi>x</mi><mo stretchy="true">→</mo></mover></math>`, `acc(accPr(chr=⃗) e(x))`},
+		{"overline", `<math><mover><mrow><mi>a</mi><mi>b</mi></mrow><mo accent="true">―</mo></mover></math>`, `bar(barPr(pos=top) e(a b))`},
+		{"underline", `<math><munder><mi>x</mi><mo>_</mo></munder></math>`, `bar(barPr(pos=bot) e(x))`},
+		{"underbrace", `<math><munder><mrow><mi>a</mi><mi>b</mi></mrow><mo>⏟</mo></munder></math>`, `groupChr(groupChrPr(chr=⏟ pos=bot vertJc=top) e(a b))`},
+		{"overbrace", `<math><mover><mrow><mi>a</mi><mi>b</mi></mrow><mo>⏞</mo></mover></math>`, `groupChr(groupChrPr(chr=⏞ pos=top vertJc=bot) e(a b))`},
+		{"function", `<math><mi>sin</mi><mi>x</mi></math>`, `func(fName(sin) e(x))`},
+		{"function application character", `<math><mi>sin</mi><mo>&#x2061;</mo><mi>x</mi><mo>+</mo><mn>1</mn></math>`, `func(fName(sin) e(x)) + 1`},
+		{"function with superscript", `<math><msup><mi>cos</mi><mn>2</mn></msup><mi>x</mi></math>`, `func(fName(sSup(e(cos) sup(2))) e(x))`},
+		{"limit", `<math><munder><mo>lim</mo><mrow><mi>x</mi><mo>→</mo><mn>0</mn></mrow></munder><mi>f</mi></math>`, `func(fName(limLow(e(lim) lim(x → 0))) e(f))`},
+		{"function name without argument", `<math><mi>sin</mi></math>`, `sin`},
+		{"operator name", `<math><mo>max</mo><mi>x</mi></math>`, `func(fName(max) e(x))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oMath, err := mathMLToOMML(tt.mathml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ommlStructure(oMath); got != tt.want {
+				t.Errorf("mathMLToOMML(%s)\n got %s\nwant %s", tt.mathml, got, tt.want)
+			}
+			if err := validateOMML(oMath); err != nil {
+				t.Errorf("mathMLToOMML(%s) is not valid OMML: %v", tt.mathml, err)
+			}
+		})
+	}
+}
+
+func TestMathMLToOMMLPrescripts(t *testing.T) {
+	tests := []struct {
+		latex    string
+		wantSPre bool
+	}{
+		{`\ce{^{14}_{6}C}`, true},
+		{`\prescript{14}{6}{C}`, true},
+		{`{}_1 x`, false},
+		{`{}^{a} b`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.latex, func(t *testing.T) {
+			_, xml := latexOMML(t, tt.latex)
+			if got := strings.Contains(xml, "<m:sPre>"); got != tt.wantSPre {
+				t.Errorf("OMML of %q = %s, want m:sPre %v", tt.latex, xml, tt.wantSPre)
+			}
+		})
+	}
+}
+
+func TestOMMLToLatexPrescript(t *testing.T) {
+	oMath, _ := latexOMML(t, `\ce{^{14}_{6}C}`)
+	latex, err := OMMLToLatex(oMath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `\prescript{14}{6}{\mathrm{C}}`; latex != want {
+		t.Errorf("OMMLToLatex() = %s, want %s", latex, want)
+	}
+}
+
+// ommlStructure 将 OMML 元素写成紧凑的结构，便于在测试中比较
+// m:r 写成其中的文本，带 m:val 的属性元素写成 名称=值，其余元素写成 名称(子元素...)，m:rPr 不输出
+func ommlStructure(el *etree.Element) string {
+	var parts []string
+	for _, child := range el.ChildElements() {
+		switch {
+		case child.Tag == "rPr":
+		case child.Tag == "r":
+			var text strings.Builder
+			for _, t := range child.SelectElements("m:t") {
+				text.WriteString(t.Text())
+			}
+			parts = append(parts, text.String())
+		case child.SelectAttr("m:val") != nil:
+			parts = append(parts, child.Tag+"="+child.SelectAttrValue("m:val", ""))
+		default:
+			parts = append(parts, child.Tag+"("+ommlStructure(child)+")")
+		}
+	}
+	return strings.Join(parts, " ")
+}
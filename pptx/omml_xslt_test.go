@@ -0,0 +1,95 @@
+//go:build xslt
+
+package pptx
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/beevik/etree"
+)
+
+// displayedText 按阅读顺序收集 OMML 中显示的字符：m:t 的文本、括号和 n 元运算符，忽略空白
+// 两种转换的结构不同（如 XSLT 将 \sum 转换为 m:sSubSup，将 \left( 转换为普通文本），但显示的字符应该相同
+func displayedText(el *etree.Element) string {
+	var text strings.Builder
+	var walk func(el *etree.Element)
+	walk = func(el *etree.Element) {
+		for _, child := range el.ChildElements() {
+			switch child.Tag {
+			case "t":
+				text.WriteString(child.Text())
+			case "dPr":
+				if begin := child.SelectElement("m:begChr"); begin != nil {
+					text.WriteString(begin.SelectAttrValue("m:val", ""))
+				} else {
+					text.WriteString("(")
+				}
+			case "naryPr":
+				if chr := child.SelectElement("m:chr"); chr != nil {
+					text.WriteString(chr.SelectAttrValue("m:val", ""))
+				} else {
+					text.WriteString("∫")
+				}
+			default:
+				walk(child)
+			}
+			if child.Tag == "d" {
+				if end := child.FindElement("m:dPr/m:endChr"); end != nil {
+					text.WriteString(end.SelectAttrValue("m:val", ""))
+				} else {
+					text.WriteString(")")
+				}
+			}
+		}
+	}
+	walk(el)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text.String())
+}
+
+func TestMathMLToOMMLMatchesXSLT(t *testing.T) {
+	for _, latex := range []string{
+		`\frac{a}{2}`,
+		`\frac{1}{1+x^2}`,
+		`x_1^2`,
+		`x_{i,j}`,
+		`\sqrt{x}`,
+		`\sqrt[n]{a+b}`,
+		`\sum_{i=1}^{n} a_i`,
+		`\prod_{i} x_i`,
+		`\int_0^1 f\,dx`,
+		`\left( a+b \right)`,
+		`\left[ \frac{a}{b} \right]`,
+		`\begin{pmatrix}1&2\\3&4\end{pmatrix}`,
+		`\begin{cases}1 & x>0\\0 & x\le 0\end{cases}`,
+		`\binom{n}{k}`,
+		`\sin x`,
+		`\log_2 x`,
+		`\lim_{x\to 0} f`,
+		`e^{i\pi}+1=0`,
+	} {
+		t.Run(latex, func(t *testing.T) {
+			mathml, err := LatexToMathML(latex, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			native, err := mathMLToOMML(mathml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			xslt, err := convertMathMLToOMMLUsingXSLT(mathml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := displayedText(native), displayedText(xslt); got != want {
+				t.Errorf("text of %s = %q, XSLT gives %q\n go:   %s\n xslt: %s", latex, got, want, ommlStructure(native), ommlStructure(xslt))
+			}
+		})
+	}
+}
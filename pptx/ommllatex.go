@@ -0,0 +1,566 @@
+package pptx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/beevik/etree"
+	"github.com/wanglihui/pptx-go/latex2mathml"
+)
+
+// latexCommandTail 匹配以字母结尾的命令，其后紧跟字母时需要用空格分隔
+var latexCommandTail = regexp.MustCompile(`\\[A-Za-z]+$`)
+
+// latexSingleCommand 匹配单个命令，如 \alpha、\lim
+var latexSingleCommand = regexp.MustCompile(`^\\[A-Za-z]+$`)
+
+// latexAtom 匹配单个命令或带一个参数的命令，如 \alpha、\mathrm{d}，作为上下标的底数时不需要大括号
+var latexAtom = regexp.MustCompile(`^\\[A-Za-z]+(\{[^{}]*\})?$`)
+
+// latexUprightRun 匹配单独的正体文本，相邻的正体文本合并为一个 \mathrm
+var latexUprightRun = regexp.MustCompile(`^\\mathrm\{[^{}\\]*\}$`)
+
+// latexTextChars 数学模式中需要转义或改写的字符
+var latexTextChars = map[rune]string{
+	'{': `\{`, '}': `\}`, '#': `\#`, '$': `\$`, '%': `\%`, '&': `\&`, '_': `\_`,
+	'\\': `\backslash`, '~': `\sim`, '^': `\hat{}`, '·': `\cdot`,
+	' ': "~", ' ': `\enspace`, ' ': `\quad`, ' ': `\;`, ' ': `\:`, ' ': `\,`,
+	'⁡': "", '⁢': "", '⁣': "", '⁤': "",
+}
+
+// latexAccents m:acc 的组合字符对应的重音命令
+var latexAccents = map[string]string{
+	"̂": `\hat`, "̃": `\tilde`, "̅": `\bar`, "̄": `\bar`, "̇": `\dot`, "̈": `\ddot`,
+	"̊": `\mathring`, "̌": `\check`, "̆": `\breve`, "́": `\acute`, "̀": `\grave`,
+	"⃗": `\vec`, "⃖": `\overleftarrow`, "⃡": `\overleftrightarrow`,
+}
+
+// latexGroupChars m:groupChr 的括号字符对应的命令
+var latexGroupChars = map[string]string{
+	"⏞": `\overbrace`, "⏟": `\underbrace`, "︷": `\overbrace`, "︸": `\underbrace`,
+	"⏜": `\overparen`, "⏝": `\underparen`,
+}
+
+// latexMatrixEnvironments 两侧括号对应的矩阵环境
+var latexMatrixEnvironments = map[string]string{
+	"()": "pmatrix", "[]": "bmatrix", "{}": "Bmatrix", "||": "vmatrix", "‖‖": "Vmatrix", "{": "cases",
+}
+
+// latexFonts m:scr 对应的字体命令
+var latexFonts = map[string]string{
+	"double-struck": `\mathbb`, "script": `\mathcal`, "fraktur": `\mathfrak`, "sans-serif": `\mathsf`, "monospace": `\mathtt`,
+}
+
+// latexWriter 拼接 LaTeX 片段，在命令和其后的字母之间插入空格
+type latexWriter struct {
+	buf     []byte
+	command bool // 最后写入的内容是否以字母结尾的命令结束
+	upright bool // 最后写入的内容是否为单独的 \mathrm{…}
+}
+
+func (w *latexWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	upright := latexUprightRun.MatchString(s)
+	if upright && w.upright {
+		// \mathrm{C}\mathrm{a} 合并为 \mathrm{Ca}
+		w.buf = append(w.buf[:len(w.buf)-1], s[len(`\mathrm{`):]...)
+		return
+	}
+	if r, _ := utf8.DecodeRuneInString(s); w.command && unicode.IsLetter(r) {
+		w.buf = append(w.buf, ' ')
+	}
+	w.buf = append(w.buf, s...)
+	w.command = latexCommandTail.MatchString(s)
+	w.upright = upright
+}
+
+func (w *latexWriter) String() string {
+	return string(w.buf)
+}
+
+// OMMLToLatex 将 m:oMath、m:oMathPara 或包含公式的 a14:m 元素转换为 LaTeX（不含定界符）
+// m:oMathPara 中的多个公式以 \\ 分隔，作为唯一内容的 m:eqArr 转换为 align* 或 gather* 环境，编号转换为 \tag
+func OMMLToLatex(math *etree.Element) (string, error) {
+	math, err := ommlRoot(math)
+	if err != nil {
+		return "", err
+	}
+	if math.Tag == "oMath" {
+		return oMathLatex(math), nil
+	}
+	var lines []string
+	for _, oMath := range math.SelectElements("oMath") {
+		lines = append(lines, oMathLatex(oMath))
+	}
+	return strings.Join(lines, ` \\ `), nil
+}
+
+// ommlText 将 a14:m 中的公式转换为带定界符的 LaTeX，行内公式为 $…$，行间公式为 $$…$$
+// 无法转换时返回公式中的文本
+func ommlText(container *etree.Element) string {
+	math, err := ommlRoot(container)
+	if err != nil {
+		var text strings.Builder
+		for _, t := range container.FindElements(".//t") {
+			text.WriteString(t.Text())
+		}
+		return text.String()
+	}
+	if math.Tag == "oMath" {
+		return "$" + oMathLatex(math) + "$"
+	}
+	var lines []string
+	for _, oMath := range math.SelectElements("oMath") {
+		lines = append(lines, "$$"+oMathLatex(oMath)+"$$")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ommlRoot 获取要转换的 m:oMath 或 m:oMathPara 元素，a14:m 容器取其中的公式
+func ommlRoot(math *etree.Element) (*etree.Element, error) {
+	if math == nil {
+		return nil, fmt.Errorf("OMML element is nil")
+	}
+	if !isMathElement(math) {
+		for _, child := range math.ChildElements() {
+			if isMathElement(child) && (child.Tag == "oMath" || child.Tag == "oMathPara") {
+				return child, nil
+			}
+		}
+	}
+	if !isMathElement(math) || (math.Tag != "oMath" && math.Tag != "oMathPara") {
+		return nil, fmt.Errorf("OMML root element must be m:oMath or m:oMathPara, got %s", math.FullTag())
+	}
+	return math, nil
+}
+
+// oMathLatex 转换单个 m:oMath，只包含 m:eqArr 时转换为多行公式环境
+func oMathLatex(oMath *etree.Element) string {
+	if content := ommlContent(oMath); len(content) == 1 && content[0].Tag == "eqArr" {
+		rows, aligned := eqArrLatex(content[0], true)
+		environment := "gather*"
+		if aligned {
+			environment = "align*"
+		}
+		return `\begin{` + environment + `}` + rows + `\end{` + environment + `}`
+	}
+	return ommlLatex(oMath)
+}
+
+// ommlContent 获取元素中除属性元素（如 m:rPr、m:fPr）以外的子元素
+func ommlContent(el *etree.Element) []*etree.Element {
+	var content []*etree.Element
+	for _, child := range el.ChildElements() {
+		if !strings.HasSuffix(child.Tag, "Pr") {
+			content = append(content, child)
+		}
+	}
+	return content
+}
+
+// ommlLatex 转换元素的所有子元素
+func ommlLatex(el *etree.Element) string {
+	if el == nil {
+		return ""
+	}
+	w := &latexWriter{}
+	for _, child := range ommlContent(el) {
+		writeOMMLLatex(w, child)
+	}
+	return w.String()
+}
+
+// writeOMMLLatex 转换单个 OMML 元素
+func writeOMMLLatex(w *latexWriter, node *etree.Element) {
+	switch node.Tag {
+	case "r":
+		w.write(runLatex(node))
+
+	case "f":
+		num, den := ommlLatex(node.SelectElement("num")), ommlLatex(node.SelectElement("den"))
+		switch ommlProperty(node, "fPr", "type", "bar") {
+		case "noBar":
+			w.write("{" + num + ` \atop ` + den + "}")
+		case "lin", "skw":
+			w.write(latexBase(num) + "/" + latexBase(den))
+		default:
+			w.write(`\frac{` + num + "}{" + den + "}")
+		}
+
+	case "rad":
+		deg := ommlLatex(node.SelectElement("deg"))
+		if deg == "" || ommlFlag(node, "radPr", "degHide") {
+			w.write(`\sqrt{` + ommlLatex(node.SelectElement("e")) + "}")
+		} else {
+			w.write(`\sqrt[` + deg + "]{" + ommlLatex(node.SelectElement("e")) + "}")
+		}
+
+	case "sSub", "sSup", "sSubSup":
+		w.write(latexBase(ommlLatex(node.SelectElement("e"))) + latexScripts(node))
+
+	case "sPre":
+		w.write(`\prescript{` + ommlLatex(node.SelectElement("sup")) + "}{" + ommlLatex(node.SelectElement("sub")) + "}{" + ommlLatex(node.SelectElement("e")) + "}")
+
+	case "nary":
+		chr := ommlProperty(node, "naryPr", "chr", "∫")
+		command := latexSymbols(chr)
+		switch limLoc := ommlProperty(node, "naryPr", "limLoc", ""); {
+		case naryOperators[chr] && limLoc == "undOvr":
+			command += `\limits`
+		case !naryOperators[chr] && limLoc == "subSup":
+			command += `\nolimits`
+		}
+		w.write(command)
+		if !ommlFlag(node, "naryPr", "subHide") {
+			w.write(latexScript("_", ommlLatex(node.SelectElement("sub"))))
+		}
+		if !ommlFlag(node, "naryPr", "supHide") {
+			w.write(latexScript("^", ommlLatex(node.SelectElement("sup"))))
+		}
+		if operand := ommlLatex(node.SelectElement("e")); operand != "" {
+			w.write(" ")
+			w.write(operand)
+		}
+
+	case "func":
+		w.write(ommlLatex(node.SelectElement("fName")))
+		if operand := ommlLatex(node.SelectElement("e")); operand != "" {
+			w.write(" ")
+			w.write(operand)
+		}
+
+	case "limLow", "limUpp":
+		w.write(limitLatex(node))
+
+	case "d":
+		w.write(delimiterLatex(node))
+
+	case "m":
+		w.write(`\begin{matrix}` + matrixLatex(node) + `\end{matrix}`)
+
+	case "eqArr":
+		rows, aligned := eqArrLatex(node, false)
+		environment := "gathered"
+		if aligned {
+			environment = "aligned"
+		}
+		w.write(`\begin{` + environment + `}` + rows + `\end{` + environment + `}`)
+
+	case "acc":
+		command, ok := latexAccents[ommlProperty(node, "accPr", "chr", "\u0302")]
+		if !ok {
+			command = `\hat`
+		}
+		w.write(command + "{" + ommlLatex(node.SelectElement("e")) + "}")
+
+	case "bar":
+		command := `\underline`
+		if ommlProperty(node, "barPr", "pos", "bot") == "top" {
+			command = `\overline`
+		}
+		w.write(command + "{" + ommlLatex(node.SelectElement("e")) + "}")
+
+	case "groupChr":
+		w.write(groupLatex(node))
+
+	case "phant":
+		w.write(`\phantom{` + ommlLatex(node.SelectElement("e")) + "}")
+
+	case "borderBox":
+		w.write(`\boxed{` + ommlLatex(node.SelectElement("e")) + "}")
+
+	default:
+		// m:box、m:e 等容器
+		for _, child := range ommlContent(node) {
+			writeOMMLLatex(w, child)
+		}
+	}
+}
+
+// runLatex 转换 m:r，m:nor 转换为 \text，字体和粗体转换为对应的命令，函数名转换为 \sin 等命令
+func runLatex(r *etree.Element) string {
+	var text strings.Builder
+	for _, t := range r.SelectElements("t") {
+		text.WriteString(t.Text())
+	}
+	var rPr *etree.Element
+	for _, child := range r.SelectElements("rPr") {
+		if isMathElement(child) {
+			rPr = child
+		}
+	}
+
+	if rPr != nil && rPr.SelectElement("nor") != nil {
+		return `\text{` + strings.NewReplacer(`\`, `\backslash `, "{", `\{`, "}", `\}`, "$", `\$`).Replace(text.String()) + "}"
+	}
+	if name := functionName(text.String()); mathFunctions[name] && name != "sgn" {
+		return `\` + name
+	}
+
+	body := latexSymbols(text.String())
+	if rPr == nil {
+		return body
+	}
+	if font, ok := latexFonts[ommlValue(rPr.SelectElement("scr"), "")]; ok {
+		body = font + "{" + body + "}"
+	}
+	switch ommlValue(rPr.SelectElement("sty"), "") {
+	case "b":
+		return `\mathbf{` + body + "}"
+	case "bi":
+		return `\boldsymbol{` + body + "}"
+	case "p":
+		if isWord(text.String()) && rPr.SelectElement("scr") == nil {
+			return `\operatorname{` + text.String() + "}"
+		}
+		if hasASCIILetter(text.String()) && rPr.SelectElement("scr") == nil {
+			return `\mathrm{` + body + "}"
+		}
+	}
+	return body
+}
+
+// latexSymbols 将文本中的字符转换为 LaTeX，特殊字符转义，其他符号使用符号表中的命令
+func latexSymbols(text string) string {
+	w := &latexWriter{}
+	for _, r := range text {
+		switch command, ok := latexTextChars[r]; {
+		case ok:
+			w.write(command)
+		case r < utf8.RuneSelf:
+			w.write(string(r))
+		default:
+			if command, ok := latex2mathml.SymbolCommand(string(r)); ok {
+				w.write(command)
+			} else {
+				w.write(string(r))
+			}
+		}
+	}
+	return w.String()
+}
+
+// latexScripts 转换 m:sub 和 m:sup 为下标和上标
+func latexScripts(node *etree.Element) string {
+	return latexScript("_", ommlLatex(node.SelectElement("sub"))) + latexScript("^", ommlLatex(node.SelectElement("sup")))
+}
+
+// latexScript 生成下标或上标，单个字符不加大括号
+func latexScript(mark, script string) string {
+	switch {
+	case script == "":
+		return ""
+	case utf8.RuneCountInString(script) == 1:
+		return mark + script
+	default:
+		return mark + "{" + script + "}"
+	}
+}
+
+// latexBase 为上下标的底数或分式的一部分加上大括号，单个字符和单个命令不加
+func latexBase(base string) string {
+	if base == "" {
+		return "{}"
+	}
+	if utf8.RuneCountInString(base) == 1 || latexAtom.MatchString(base) {
+		return base
+	}
+	return "{" + base + "}"
+}
+
+// limitLatex 转换 m:limLow 和 m:limUpp，函数名和分组括号的极限使用下标或上标，其他使用 \underset 和 \overset
+func limitLatex(node *etree.Element) string {
+	base := ommlLatex(node.SelectElement("e"))
+	limit := ommlLatex(node.SelectElement("lim"))
+	mark, command := "_", `\underset`
+	if node.Tag == "limUpp" {
+		mark, command = "^", `\overset`
+	}
+
+	isFunction := latexSingleCommand.MatchString(base) && mathFunctions[strings.TrimPrefix(base, `\`)]
+	var isGroup bool
+	if e := node.SelectElement("e"); e != nil {
+		content := ommlContent(e)
+		isGroup = len(content) == 1 && content[0].Tag == "groupChr"
+	}
+	if isFunction || isGroup {
+		return base + latexScript(mark, limit)
+	}
+	if strings.HasPrefix(base, `\operatorname{`) && node.Tag == "limLow" {
+		return `\operatorname*` + strings.TrimPrefix(base, `\operatorname`) + latexScript(mark, limit)
+	}
+	return command + "{" + limit + "}{" + base + "}"
+}
+
+// groupLatex 转换 m:groupChr
+func groupLatex(node *etree.Element) string {
+	pos := ommlProperty(node, "groupChrPr", "pos", "bot")
+	chr := ommlProperty(node, "groupChrPr", "chr", "\u23df")
+	base := ommlLatex(node.SelectElement("e"))
+	if command, ok := latexGroupChars[chr]; ok {
+		return command + "{" + base + "}"
+	}
+	if pos == "top" {
+		return `\overset{` + latexSymbols(chr) + "}{" + base + "}"
+	}
+	return `\underset{` + latexSymbols(chr) + "}{" + base + "}"
+}
+
+// delimiterLatex 转换 m:d，括号中只有矩阵时转换为 pmatrix 等环境，只有无分数线的分式时转换为 \binom
+func delimiterLatex(node *etree.Element) string {
+	open := ommlProperty(node, "dPr", "begChr", "(")
+	close := ommlProperty(node, "dPr", "endChr", ")")
+	separator := ommlProperty(node, "dPr", "sepChr", "|")
+	args := node.SelectElements("e")
+
+	if len(args) == 1 {
+		content := ommlContent(args[0])
+		if len(content) == 1 {
+			inner := content[0]
+			if environment, ok := latexMatrixEnvironments[open+close]; ok && (inner.Tag == "m" || inner.Tag == "eqArr") {
+				rows := ""
+				if inner.Tag == "m" {
+					rows = matrixLatex(inner)
+				} else {
+					rows, _ = eqArrLatex(inner, false)
+				}
+				return `\begin{` + environment + `}` + rows + `\end{` + environment + `}`
+			}
+			if open+close == "()" && inner.Tag == "f" && ommlProperty(inner, "fPr", "type", "") == "noBar" {
+				return `\binom{` + ommlLatex(inner.SelectElement("num")) + "}{" + ommlLatex(inner.SelectElement("den")) + "}"
+			}
+		}
+	}
+
+	w := &latexWriter{}
+	w.write(`\left`)
+	w.write(latexDelimiter(open))
+	for i, arg := range args {
+		if i > 0 {
+			w.write(latexDelimiter(separator))
+		}
+		w.write(ommlLatex(arg))
+	}
+	w.write(`\right`)
+	w.write(latexDelimiter(close))
+	return w.String()
+}
+
+// latexDelimiter 转换 \left 和 \right 使用的括号，没有括号时为 .
+func latexDelimiter(chr string) string {
+	switch chr {
+	case "":
+		return "."
+	case "(", ")", "[", "]", "|", "/":
+		return chr
+	default:
+		return latexSymbols(chr)
+	}
+}
+
+// matrixLatex 转换 m:m 的各行，单元格之间以 & 分隔，行之间以 \\ 分隔
+func matrixLatex(m *etree.Element) string {
+	var rows []string
+	for _, mr := range m.SelectElements("mr") {
+		var cells []string
+		for _, e := range mr.SelectElements("e") {
+			cells = append(cells, ommlLatex(e))
+		}
+		rows = append(rows, strings.Join(cells, " & "))
+	}
+	return strings.Join(rows, ` \\ `)
+}
+
+// eqArrLatex 转换 m:eqArr 的各行，内容为 & 的 m:r 是对齐点，内容为 # 的 m:r 之后是编号
+// tags 为 true 时编号转换为 \tag，否则忽略编号；返回的 aligned 表示是否有对齐点
+func eqArrLatex(eqArr *etree.Element, tags bool) (string, bool) {
+	var rows []string
+	aligned := false
+	for _, e := range eqArr.SelectElements("e") {
+		var cells []string
+		var label *strings.Builder
+		cell := &latexWriter{}
+		for _, child := range ommlContent(e) {
+			switch text := runText(child); {
+			case label != nil:
+				for _, t := range child.FindElements(".//t") {
+					label.WriteString(t.Text())
+				}
+			case text == "&":
+				cells = append(cells, cell.String())
+				cell = &latexWriter{}
+				aligned = true
+			case text == "#":
+				label = &strings.Builder{}
+			default:
+				writeOMMLLatex(cell, child)
+			}
+		}
+		row := strings.Join(append(cells, cell.String()), " & ")
+		if label != nil && tags {
+			switch tag := strings.TrimSpace(label.String()); {
+			case strings.HasPrefix(tag, "(") && strings.HasSuffix(tag, ")"):
+				row += ` \tag{` + tag[1:len(tag)-1] + "}"
+			case tag != "":
+				row += ` \tag*{` + tag + "}"
+			}
+		}
+		rows = append(rows, row)
+	}
+	return strings.Join(rows, ` \\ `), aligned
+}
+
+// runText 获取 m:r 的文本，其他元素返回空字符串
+func runText(node *etree.Element) string {
+	if node.Tag != "r" {
+		return ""
+	}
+	var text strings.Builder
+	for _, t := range node.SelectElements("t") {
+		text.WriteString(t.Text())
+	}
+	return text.String()
+}
+
+// ommlProperty 获取属性元素（如 m:fPr）中子元素的 m:val，不存在时返回 def
+func ommlProperty(node *etree.Element, pr, name, def string) string {
+	props := node.SelectElement(pr)
+	if props == nil {
+		return def
+	}
+	return ommlValue(props.SelectElement(name), def)
+}
+
+// ommlValue 获取元素的 m:val，元素不存在时返回 def
+func ommlValue(el *etree.Element, def string) string {
+	if el == nil {
+		return def
+	}
+	return el.SelectAttrValue("val", def)
+}
+
+// ommlFlag 获取属性元素中的开关，元素存在且 m:val 不为 0、off 或 false 时为 true
+func ommlFlag(node *etree.Element, pr, name string) bool {
+	props := node.SelectElement(pr)
+	if props == nil || props.SelectElement(name) == nil {
+		return false
+	}
+	switch ommlValue(props.SelectElement(name), "on") {
+	case "0", "off", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+// hasASCIILetter 判断文本中是否有 ASCII 字母
+func hasASCIILetter(text string) bool {
+	return strings.IndexFunc(text, func(r rune) bool {
+		return r < utf8.RuneSelf && unicode.IsLetter(r)
+	}) >= 0
+}
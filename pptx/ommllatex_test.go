@@ -0,0 +1,56 @@
+package pptx
+
+import "testing"
+
+func TestOMMLToLatex(t *testing.T) {
+	tests := []struct {
+		latex string
+		want  string
+	}{
+		{`\frac{a}{b}`, `\frac{a}{b}`},
+		{`x_{i}^{2}`, `x_i^2`},
+		{`\sum_{i=1}^{n} i`, `\sum_{i=1}^n i`},
+		{`\left(a+b\right)`, `\left(a+b\right)`},
+		{`\sin x`, `\sin x`},
+		{`\begin{align} a &= b \\ c &= d \end{align}`, `\begin{align*}a & =b \\ c & =d\end{align*}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.latex, func(t *testing.T) {
+			oMath, _ := latexOMML(t, tt.latex)
+			if got, err := OMMLToLatex(oMath); err != nil || got != tt.want {
+				t.Errorf("OMMLToLatex() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOMMLToLatexRoundTrip(t *testing.T) {
+	tests := []string{
+		`\frac{a}{b}`,
+		`\sqrt{x}`,
+		`\sqrt[3]{x}`,
+		`x^{2}`,
+		`x_{i}^{2}`,
+		`\sum_{i=1}^{n} i`,
+		`\int_0^1 f`,
+		`\left(a+b\right)`,
+		`\begin{matrix}a & b \\ c & d\end{matrix}`,
+		`\sin x`,
+		`\hat{a}`,
+		`\alpha + \beta`,
+	}
+	for _, latex := range tests {
+		t.Run(latex, func(t *testing.T) {
+			oMath, _ := latexOMML(t, latex)
+			got, err := OMMLToLatex(oMath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, want := latexOMML(t, latex)
+			_, again := latexOMML(t, got)
+			if again != want {
+				t.Errorf("OMMLToLatex() = %s, converting it again gives\n%s\nwant\n%s", got, again, want)
+			}
+		})
+	}
+}
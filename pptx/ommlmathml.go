@@ -0,0 +1,388 @@
+package pptx
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/beevik/etree"
+)
+
+// NsMathML MathML 的命名空间
+const NsMathML = "http://www.w3.org/1998/Math/MathML"
+
+// mathMLAccents m:acc 的组合字符对应的 MathML 重音字符
+var mathMLAccents = map[string]string{
+	"̂": "^", "̃": "~", "̅": "¯", "̄": "¯", "̇": "˙", "̈": "¨",
+	"̊": "˚", "̌": "ˇ", "̆": "˘", "́": "´", "̀": "`",
+	"⃗": "→", "⃖": "←", "⃡": "↔",
+}
+
+// mathMLSpaces 空白字符对应的 mspace 宽度
+var mathMLSpaces = map[rune]string{'\u2003': "1em", '\u2002': "0.5em", '\u2004': "0.2778em", '\u2005': "0.2222em", '\u2009': "0.1667em"}
+
+// OMMLToMathML 将 m:oMath、m:oMathPara 或包含公式的 a14:m 元素转换为 MathML，m:oMathPara 转换为 display="block" 的公式
+func OMMLToMathML(math *etree.Element) (string, error) {
+	math, err := ommlRoot(math)
+	if err != nil {
+		return "", err
+	}
+
+	root := etree.NewElement("math")
+	root.CreateAttr("xmlns", NsMathML)
+	if math.Tag == "oMath" {
+		appendMathMLChildren(root.CreateElement("mrow"), math)
+	} else {
+		root.CreateAttr("display", "block")
+		oMaths := math.SelectElements("oMath")
+		if len(oMaths) == 1 {
+			appendMathMLChildren(root.CreateElement("mrow"), oMaths[0])
+		} else {
+			table := root.CreateElement("mtable")
+			for _, oMath := range oMaths {
+				appendMathMLChildren(table.CreateElement("mtr").CreateElement("mtd"), oMath)
+			}
+		}
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(root)
+	return doc.WriteToString()
+}
+
+// appendMathMLChildren 转换元素的所有子元素并追加到 parent 中
+func appendMathMLChildren(parent *etree.Element, el *etree.Element) {
+	if el == nil {
+		return
+	}
+	for _, child := range ommlContent(el) {
+		appendMathML(parent, child)
+	}
+}
+
+// mathMLRow 将元素的子元素转换为 mrow，只有一个元素时不使用 mrow
+func mathMLRow(el *etree.Element) *etree.Element {
+	row := etree.NewElement("mrow")
+	appendMathMLChildren(row, el)
+	if children := row.ChildElements(); len(children) == 1 {
+		row.RemoveChild(children[0])
+		return children[0]
+	}
+	return row
+}
+
+// appendMathML 转换单个 OMML 元素
+func appendMathML(parent *etree.Element, node *etree.Element) {
+	switch node.Tag {
+	case "r":
+		appendMathMLRun(parent, node)
+
+	case "f":
+		num, den := mathMLRow(node.SelectElement("num")), mathMLRow(node.SelectElement("den"))
+		fracType := ommlProperty(node, "fPr", "type", "bar")
+		if fracType == "lin" {
+			row := parent.CreateElement("mrow")
+			row.AddChild(num)
+			row.CreateElement("mo").SetText("/")
+			row.AddChild(den)
+			return
+		}
+		frac := parent.CreateElement("mfrac")
+		switch fracType {
+		case "noBar":
+			frac.CreateAttr("linethickness", "0")
+		case "skw":
+			frac.CreateAttr("bevelled", "true")
+		}
+		frac.AddChild(num)
+		frac.AddChild(den)
+
+	case "rad":
+		deg := node.SelectElement("deg")
+		if deg == nil || len(ommlContent(deg)) == 0 || ommlFlag(node, "radPr", "degHide") {
+			appendMathMLChildren(parent.CreateElement("msqrt"), node.SelectElement("e"))
+			return
+		}
+		root := parent.CreateElement("mroot")
+		root.AddChild(mathMLRow(node.SelectElement("e")))
+		root.AddChild(mathMLRow(deg))
+
+	case "sSub", "sSup", "sSubSup":
+		tag := map[string]string{"sSub": "msub", "sSup": "msup", "sSubSup": "msubsup"}[node.Tag]
+		scripts := parent.CreateElement(tag)
+		scripts.AddChild(mathMLRow(node.SelectElement("e")))
+		if node.Tag != "sSup" {
+			scripts.AddChild(mathMLRow(node.SelectElement("sub")))
+		}
+		if node.Tag != "sSub" {
+			scripts.AddChild(mathMLRow(node.SelectElement("sup")))
+		}
+
+	case "sPre":
+		scripts := parent.CreateElement("mmultiscripts")
+		scripts.AddChild(mathMLRow(node.SelectElement("e")))
+		scripts.CreateElement("mprescripts")
+		scripts.AddChild(mathMLRow(node.SelectElement("sub")))
+		scripts.AddChild(mathMLRow(node.SelectElement("sup")))
+
+	case "nary":
+		chr := ommlProperty(node, "naryPr", "chr", "∫")
+		op := etree.NewElement("mo")
+		op.SetText(chr)
+		var sub, sup *etree.Element
+		if !ommlFlag(node, "naryPr", "subHide") {
+			sub = mathMLRow(node.SelectElement("sub"))
+		}
+		if !ommlFlag(node, "naryPr", "supHide") {
+			sup = mathMLRow(node.SelectElement("sup"))
+		}
+		limLoc := ommlProperty(node, "naryPr", "limLoc", "")
+		under := limLoc == "undOvr" || limLoc == "" && !naryOperators[chr]
+		parent.AddChild(mathMLScripts(op, sub, sup, under))
+		appendMathMLChildren(parent.CreateElement("mrow"), node.SelectElement("e"))
+
+	case "func":
+		// 不在 mathFunctions 中的函数名使用 mo，与 \operatorname 的转换结果一致
+		name := etree.NewElement("mrow")
+		appendMathMLChildren(name, node.SelectElement("fName"))
+		for _, child := range name.ChildElements() {
+			base := child
+			if child.Tag != "mi" && len(child.ChildElements()) > 0 {
+				base = child.ChildElements()[0]
+			}
+			if base.Tag == "mi" && isWord(base.Text()) && !mathFunctions[functionName(base.Text())] {
+				base.Tag = "mo"
+			}
+			parent.AddChild(child)
+		}
+		parent.AddChild(mathMLRow(node.SelectElement("e")))
+
+	case "limLow", "limUpp":
+		tag := "munder"
+		if node.Tag == "limUpp" {
+			tag = "mover"
+		}
+		limit := parent.CreateElement(tag)
+		limit.AddChild(mathMLRow(node.SelectElement("e")))
+		limit.AddChild(mathMLRow(node.SelectElement("lim")))
+
+	case "d":
+		row := parent.CreateElement("mrow")
+		if open := ommlProperty(node, "dPr", "begChr", "("); open != "" {
+			appendMathMLFence(row, open, "prefix")
+		}
+		separator := ommlProperty(node, "dPr", "sepChr", "|")
+		for i, arg := range node.SelectElements("e") {
+			if i > 0 {
+				row.CreateElement("mo").SetText(separator)
+			}
+			appendMathMLChildren(row, arg)
+		}
+		if close := ommlProperty(node, "dPr", "endChr", ")"); close != "" {
+			appendMathMLFence(row, close, "postfix")
+		}
+
+	case "m":
+		table := parent.CreateElement("mtable")
+		var aligns []string
+		for _, mc := range node.FindElements("mPr/mcs/mc") {
+			align := ommlProperty(mc, "mcPr", "mcJc", "center")
+			count := atoi(ommlProperty(mc, "mcPr", "count", "1"))
+			for i := 0; i < count || i == 0; i++ {
+				aligns = append(aligns, align)
+			}
+		}
+		for _, align := range aligns {
+			if align != "center" {
+				table.CreateAttr("columnalign", strings.Join(aligns, " "))
+				break
+			}
+		}
+		for _, mr := range node.SelectElements("mr") {
+			tr := table.CreateElement("mtr")
+			for _, e := range mr.SelectElements("e") {
+				appendMathMLChildren(tr.CreateElement("mtd"), e)
+			}
+		}
+
+	case "eqArr":
+		appendMathMLEquationArray(parent, node)
+
+	case "acc":
+		chr, ok := mathMLAccents[ommlProperty(node, "accPr", "chr", "̂")]
+		if !ok {
+			chr = "^"
+		}
+		over := parent.CreateElement("mover")
+		over.CreateAttr("accent", "true")
+		over.AddChild(mathMLRow(node.SelectElement("e")))
+		over.CreateElement("mo").SetText(chr)
+
+	case "bar":
+		tag, chr := "munder", "_"
+		if ommlProperty(node, "barPr", "pos", "bot") == "top" {
+			tag, chr = "mover", "‾"
+		}
+		bar := parent.CreateElement(tag)
+		bar.AddChild(mathMLRow(node.SelectElement("e")))
+		bar.CreateElement("mo").SetText(chr)
+
+	case "groupChr":
+		tag := "munder"
+		if ommlProperty(node, "groupChrPr", "pos", "bot") == "top" {
+			tag = "mover"
+		}
+		group := parent.CreateElement(tag)
+		group.AddChild(mathMLRow(node.SelectElement("e")))
+		group.CreateElement("mo").SetText(ommlProperty(node, "groupChrPr", "chr", "⏟"))
+
+	case "phant":
+		appendMathMLChildren(parent.CreateElement("mphantom"), node.SelectElement("e"))
+
+	case "borderBox":
+		enclose := parent.CreateElement("menclose")
+		enclose.CreateAttr("notation", "box")
+		appendMathMLChildren(enclose, node.SelectElement("e"))
+
+	default:
+		// m:box、m:e 等容器
+		appendMathMLChildren(parent, node)
+	}
+}
+
+// mathMLScripts 为 n 元运算符添加上下限，under 为 true 时上下限放在正下方和正上方
+func mathMLScripts(base, sub, sup *etree.Element, under bool) *etree.Element {
+	var tags [3]string
+	if under {
+		tags = [3]string{"munder", "mover", "munderover"}
+	} else {
+		tags = [3]string{"msub", "msup", "msubsup"}
+	}
+
+	var scripts *etree.Element
+	switch {
+	case sub != nil && sup != nil:
+		scripts = etree.NewElement(tags[2])
+		scripts.AddChild(base)
+		scripts.AddChild(sub)
+		scripts.AddChild(sup)
+	case sub != nil:
+		scripts = etree.NewElement(tags[0])
+		scripts.AddChild(base)
+		scripts.AddChild(sub)
+	case sup != nil:
+		scripts = etree.NewElement(tags[1])
+		scripts.AddChild(base)
+		scripts.AddChild(sup)
+	default:
+		return base
+	}
+	return scripts
+}
+
+// appendMathMLFence 添加 \left、\right 形式的伸缩括号
+func appendMathMLFence(parent *etree.Element, chr, form string) {
+	mo := parent.CreateElement("mo")
+	mo.CreateAttr("fence", "true")
+	mo.CreateAttr("form", form)
+	mo.SetText(chr)
+}
+
+// appendMathMLEquationArray 将 m:eqArr 转换为 mtable，& 分隔单元格，# 之后的编号放在 mlabeledtr 的第一个单元格中
+func appendMathMLEquationArray(parent *etree.Element, eqArr *etree.Element) {
+	table := parent.CreateElement("mtable")
+	table.CreateAttr("displaystyle", "true")
+	for _, e := range eqArr.SelectElements("e") {
+		row := etree.NewElement("mtr")
+		var label *etree.Element
+		cell := row.CreateElement("mtd")
+		for _, child := range ommlContent(e) {
+			switch text := runText(child); {
+			case label != nil:
+				appendMathML(label, child)
+			case text == "&":
+				cell = row.CreateElement("mtd")
+			case text == "#":
+				label = etree.NewElement("mtd")
+			default:
+				appendMathML(cell, child)
+			}
+		}
+		if label != nil {
+			row.Tag = "mlabeledtr"
+			row.InsertChildAt(0, label)
+		}
+		table.AddChild(row)
+	}
+}
+
+// appendMathMLRun 将 m:r 转换为 mi、mn、mo 和 mtext，m:nor 转换为 mtext，函数名等单词转换为一个 mi
+func appendMathMLRun(parent *etree.Element, r *etree.Element) {
+	text := runText(r)
+	var rPr *etree.Element
+	for _, child := range r.SelectElements("rPr") {
+		if isMathElement(child) {
+			rPr = child
+		}
+	}
+
+	if rPr != nil && rPr.SelectElement("nor") != nil {
+		parent.CreateElement("mtext").SetText(text)
+		return
+	}
+	variant := mathMLVariant(rPr)
+	if isWord(text) {
+		mi := parent.CreateElement("mi")
+		mi.SetText(text)
+		if variant != "" && variant != "normal" {
+			mi.CreateAttr("mathvariant", variant)
+		}
+		return
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsDigit(r):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' && j+1 < len(runes) && unicode.IsDigit(runes[j+1])) {
+				j++
+			}
+			parent.CreateElement("mn").SetText(string(runes[i:j]))
+			i = j - 1
+		case unicode.IsLetter(r):
+			mi := parent.CreateElement("mi")
+			mi.SetText(string(r))
+			if variant != "" {
+				mi.CreateAttr("mathvariant", variant)
+			}
+		case mathMLSpaces[r] != "":
+			parent.CreateElement("mspace").CreateAttr("width", mathMLSpaces[r])
+		case unicode.IsSpace(r):
+			// 数学模式中的普通空格没有宽度
+		default:
+			parent.CreateElement("mo").SetText(string(r))
+		}
+	}
+}
+
+// mathMLVariant 根据 m:scr 和 m:sty 获取 mathvariant
+func mathMLVariant(rPr *etree.Element) string {
+	if rPr == nil {
+		return ""
+	}
+	scr := ommlValue(rPr.SelectElement("scr"), "")
+	switch sty := ommlValue(rPr.SelectElement("sty"), ""); {
+	case sty == "b" && (scr == "script" || scr == "fraktur" || scr == "sans-serif"):
+		return "bold-" + scr
+	case sty == "b":
+		return "bold"
+	case sty == "bi":
+		return "bold-italic"
+	case scr != "" && scr != "roman":
+		return scr
+	case sty == "p":
+		return "normal"
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package pptx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOMMLToMathML(t *testing.T) {
+	tests := []struct {
+		latex string
+		want  string
+	}{
+		{`\frac{a}{b}`, `<mfrac><mi>a</mi><mi>b</mi></mfrac>`},
+		{`x_{i}^{2}`, `<msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup>`},
+		{`\sum_{i=1}^{n} i`, `<munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover>`},
+		{`\ce{^{14}_{6}C}`, `<mprescripts/>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.latex, func(t *testing.T) {
+			oMath, want := latexOMML(t, tt.latex)
+			mathml, err := OMMLToMathML(oMath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(mathml, tt.want) {
+				t.Errorf("OMMLToMathML() = %s, want it to contain %s", mathml, tt.want)
+			}
+
+			// 转换得到的 MathML 再转换为 OMML 时结果不变
+			again, err := mathMLToOMML(mathml)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ommlString(t, again); got != want {
+				t.Errorf("MathMLToOMML(OMMLToMathML()) = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestOMMLToMathMLRejectsOtherElements(t *testing.T) {
+	slide := newTestSlide(t, testShape(2, "Title 1", "title", 0, testRun("Hello")))
+	if _, err := OMMLToMathML(slide.xml.Root()); err == nil {
+		t.Error("OMMLToMathML accepted a slide element")
+	}
+	if _, err := OMMLToLatex(nil); err == nil {
+		t.Error("OMMLToLatex accepted nil")
+	}
+}
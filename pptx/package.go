@@ -0,0 +1,407 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rootRelsPath 包级关系文件的路径，所有部件都应从这里可达
+const rootRelsPath = "_rels/.rels"
+
+// zipModTime 写入 zip 条目的固定修改时间（DOS 时间的起点），保证相同内容生成相同的字节
+var zipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// compressedExts 本身已压缩的媒体格式，写入 zip 时直接存储不再压缩
+var compressedExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".tif": true, ".tiff": true, ".wdp": true,
+	".mp3": true, ".m4a": true, ".wma": true, ".mp4": true, ".m4v": true, ".mov": true, ".wmv": true,
+	".avi": true, ".zip": true, ".xlsx": true, ".docx": true, ".pptx": true,
+}
+
+// Part 表示 OPC 包中的一个部件
+// 来自原始 zip 且未修改的部件在第一次调用 Data 时才解压，之后使用缓存的内容
+type Part struct {
+	name     string
+	data     []byte    // 修改过或新建的部件内容
+	file     *zip.File // 部件在原始 zip 中的条目，新建的部件为 nil
+	cache    []byte    // 从原始 zip 中解压的内容，首次调用 Data 时读取，写入部件时丢弃
+	modified bool      // 读取后是否被修改过，未修改的部件保存时原样复制压缩数据
+	sum      []byte    // 内容的 SHA-256，首次使用时计算
+}
+
+// Name 返回部件在包中的路径，如 ppt/slides/slide1.xml
+func (pt *Part) Name() string {
+	return pt.name
+}
+
+// Data 返回部件内容，未修改的部件从原始 zip 中读取并缓存，返回的切片不能修改
+func (pt *Part) Data() ([]byte, error) {
+	if pt.modified || pt.file == nil {
+		return pt.data, nil
+	}
+	if pt.cache != nil {
+		return pt.cache, nil
+	}
+	rc, err := pt.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, &PartError{Path: pt.name, Err: err}
+	}
+	pt.cache = data
+	return data, nil
+}
+
+// Open 以流的方式读取部件内容，适合读取较大的媒体文件，已缓存的内容不再从 zip 中解压
+func (pt *Part) Open() (io.ReadCloser, error) {
+	if pt.modified || pt.file == nil {
+		return io.NopCloser(bytes.NewReader(pt.data)), nil
+	}
+	if pt.cache != nil {
+		return io.NopCloser(bytes.NewReader(pt.cache)), nil
+	}
+	rc, err := pt.file.Open()
+	if err != nil {
+		return nil, &PartError{Path: pt.name, Err: err}
+	}
+	return rc, nil
+}
+
+// hash 返回部件内容的 SHA-256
+func (pt *Part) hash() ([]byte, error) {
+	if pt.sum != nil {
+		return pt.sum, nil
+	}
+	rc, err := pt.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return nil, fmt.Errorf("failed to read part %s: %w", pt.name, err)
+	}
+	pt.sum = h.Sum(nil)
+	return pt.sum, nil
+}
+
+// Size 返回部件未压缩时的大小
+func (pt *Part) Size() int64 {
+	if pt.modified || pt.file == nil {
+		return int64(len(pt.data))
+	}
+	return int64(pt.file.UncompressedSize64)
+}
+
+// Package 表示一个 OPC 包，管理部件及其内容类型
+// [Content_Types].xml 由 ContentTypes 维护，不作为普通部件保存
+type Package struct {
+	parts            map[string]*Part
+	order            []string // 部件的添加顺序
+	contentTypes     *ContentTypes
+	contentTypesFile *zip.File // 原始 zip 中的 [Content_Types].xml
+}
+
+// NewPackage 创建空的包
+func NewPackage() *Package {
+	return &Package{
+		parts:        make(map[string]*Part),
+		contentTypes: NewContentTypes(),
+	}
+}
+
+// readPackage 登记 zip 文件中的所有部件，只读取 [Content_Types].xml，其余部件在访问时才读取
+// 部件内容依赖 reader，在包使用完之前不能关闭
+func readPackage(reader *zip.Reader) (*Package, error) {
+	pkg := NewPackage()
+	hasContentTypes := false
+
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue // 跳过目录
+		}
+
+		if file.Name == contentTypesPath {
+			part := &Part{name: file.Name, file: file}
+			content, err := part.Data()
+			if err != nil {
+				return nil, err
+			}
+			ct, err := parseContentTypes(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse content types: %w", err)
+			}
+			pkg.contentTypes = ct
+			pkg.contentTypesFile = file
+			hasContentTypes = true
+			continue
+		}
+		pkg.parts[file.Name] = &Part{name: file.Name, file: file}
+		pkg.order = append(pkg.order, file.Name)
+	}
+
+	if !hasContentTypes {
+		return nil, fmt.Errorf("%s not found", contentTypesPath)
+	}
+	return pkg, nil
+}
+
+// ContentTypes 返回包的内容类型声明
+func (pkg *Package) ContentTypes() *ContentTypes {
+	return pkg.contentTypes
+}
+
+// Part 获取指定路径的部件，不存在时返回 nil
+func (pkg *Package) Part(name string) *Part {
+	return pkg.parts[name]
+}
+
+// Parts 按添加顺序返回所有部件
+func (pkg *Package) Parts() []*Part {
+	parts := make([]*Part, 0, len(pkg.order))
+	for _, name := range pkg.order {
+		parts = append(parts, pkg.parts[name])
+	}
+	return parts
+}
+
+// readPart 读取部件内容，部件不存在时返回错误
+func (pkg *Package) readPart(name string) ([]byte, error) {
+	part := pkg.parts[name]
+	if part == nil {
+		return nil, &PartError{Path: name, Err: ErrPartMissing}
+	}
+	return part.Data()
+}
+
+// SetPart 写入部件内容，部件不存在时创建，不修改内容类型声明
+func (pkg *Package) SetPart(name string, data []byte) *Part {
+	if part := pkg.parts[name]; part != nil {
+		part.data = data
+		part.modified = true
+		part.cache = nil
+		part.sum = nil
+		return part
+	}
+	part := &Part{name: name, data: data, modified: true}
+	pkg.parts[name] = part
+	pkg.order = append(pkg.order, name)
+	return part
+}
+
+// AddPart 写入部件内容并声明其内容类型
+// 扩展名尚无默认声明的非 XML 部件（如图片）添加 Default，其余情况按需添加 Override
+func (pkg *Package) AddPart(name, contentType string, data []byte) *Part {
+	part := pkg.SetPart(name, data)
+
+	if pkg.contentTypes.ContentType(name) == contentType {
+		return part
+	}
+	ext := path.Ext(name)
+	if ext != "" && ext != ".xml" && pkg.contentTypes.Default(ext) == "" {
+		pkg.contentTypes.SetDefault(ext, contentType)
+	} else {
+		pkg.contentTypes.SetOverride(name, contentType)
+	}
+	return part
+}
+
+// RemovePart 删除部件、它的关系文件以及对应的 Override 声明
+func (pkg *Package) RemovePart(name string) {
+	pkg.removePart(name)
+	pkg.removePart(relsPathFor(name))
+}
+
+// removePart 只删除部件本身和它的 Override 声明
+func (pkg *Package) removePart(name string) {
+	if _, ok := pkg.parts[name]; !ok {
+		return
+	}
+	delete(pkg.parts, name)
+	for i, n := range pkg.order {
+		if n == name {
+			pkg.order = append(pkg.order[:i], pkg.order[i+1:]...)
+			break
+		}
+	}
+	pkg.contentTypes.RemoveOverride(name)
+}
+
+// NextPartName 按 format（如 "ppt/slides/slide%d.xml"）生成一个未被使用的部件名
+// 编号取同一前缀下已有部件的最大编号加一，不区分后缀，避免 image1.png 与 image1.jpg 同时出现
+func (pkg *Package) NextPartName(format string) string {
+	prefix := format[:strings.Index(format, "%d")]
+	max := 0
+	for name := range pkg.parts {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		end := 0
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		if n := atoi(rest[:end]); n > max {
+			max = n
+		}
+	}
+	return fmt.Sprintf(format, max+1)
+}
+
+// CollectGarbage 删除无法从包级关系（_rels/.rels）到达的部件，返回被删除的部件名
+// 没有包级关系文件，或者可达的关系文件无法读取、解析时不删除任何部件，避免误删仍被引用的部件
+func (pkg *Package) CollectGarbage() []string {
+	if _, ok := pkg.parts[rootRelsPath]; !ok {
+		return nil
+	}
+
+	reachable := map[string]bool{rootRelsPath: true}
+	queue := []string{""}
+	for len(queue) > 0 {
+		source := queue[0]
+		queue = queue[1:]
+
+		relsPath := rootRelsPath
+		if source != "" {
+			relsPath = relsPathFor(source)
+		}
+		if pkg.parts[relsPath] == nil {
+			continue
+		}
+		reachable[relsPath] = true
+
+		data, err := pkg.readPart(relsPath)
+		if err != nil {
+			return nil
+		}
+		rels, err := parseRelationships(data)
+		if err != nil {
+			return nil
+		}
+		for _, rel := range rels.All() {
+			if rel.TargetMode == "External" {
+				continue
+			}
+			target := resolvePartPath(source, rel.Target)
+			if _, exists := pkg.parts[target]; exists && !reachable[target] {
+				reachable[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	var removed []string
+	for name := range pkg.parts {
+		if !reachable[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		pkg.removePart(name)
+	}
+	return removed
+}
+
+// writeTo 将包写入 zip
+// 输出是确定的：[Content_Types].xml 位于最前，其余部件按读取和添加的顺序排列
+// 未修改的部件直接复制原始的压缩数据和文件头，修改过或新建的部件使用固定的修改时间重新写入
+func (pkg *Package) writeTo(ctx context.Context, w io.Writer) error {
+	writer := zip.NewWriter(w)
+
+	if pkg.contentTypesFile != nil && !pkg.contentTypes.dirty {
+		if err := copyZipEntry(ctx, writer, pkg.contentTypesFile); err != nil {
+			return err
+		}
+	} else {
+		contentTypes, err := pkg.contentTypes.toXML()
+		if err != nil {
+			return fmt.Errorf("failed to serialize content types: %w", err)
+		}
+		if err := writeZipEntry(writer, contentTypesPath, contentTypes); err != nil {
+			return err
+		}
+	}
+
+	for _, part := range pkg.Parts() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if part.file != nil && !part.modified {
+			err = copyZipEntry(ctx, writer, part.file)
+		} else {
+			err = writeZipEntry(writer, part.name, part.data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+	return nil
+}
+
+// writeZipEntry 写入一个 zip 条目，已压缩的媒体使用 zip.Store
+func writeZipEntry(writer *zip.Writer, name string, data []byte) error {
+	method := zip.Deflate
+	if compressedExts[strings.ToLower(path.Ext(name))] {
+		method = zip.Store
+	}
+	w, err := writer.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   method,
+		Modified: zipModTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create zip entry %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyZipEntry 不解压直接复制原始 zip 条目，复制较大的媒体时也会响应 ctx 的取消
+func copyZipEntry(ctx context.Context, writer *zip.Writer, file *zip.File) error {
+	header := file.FileHeader
+	w, err := writer.CreateRaw(&header)
+	if err != nil {
+		return fmt.Errorf("failed to create zip entry %s: %w", file.Name, err)
+	}
+	r, err := file.OpenRaw()
+	if err != nil {
+		return fmt.Errorf("failed to open zip entry %s: %w", file.Name, err)
+	}
+	if _, err := io.Copy(w, &contextReader{ctx: ctx, r: r}); err != nil {
+		return fmt.Errorf("failed to copy zip entry %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// contextReader 在每次读取前检查 ctx 是否已取消
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read 实现 io.Reader
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
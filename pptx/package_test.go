@@ -0,0 +1,203 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRels 生成关系文件内容，targets 依次使用 rId1、rId2……
+func testRels(targets ...string) []byte {
+	xml := `<Relationships xmlns="` + NsRelationships + `">`
+	for i, target := range targets {
+		xml += fmt.Sprintf(`<Relationship Id="rId%d" Type="%s" Target="%s"/>`, i+1, RelTypeImage, target)
+	}
+	return []byte(xml + `</Relationships>`)
+}
+
+func TestCollectGarbage(t *testing.T) {
+	tests := []struct {
+		name       string
+		slideRels  string
+		want       []string
+		wantRemain []string
+	}{
+		{
+			name:       "unreachable parts",
+			slideRels:  string(testRels("../media/image1.png")),
+			want:       []string{"ppt/media/image2.png", "ppt/media/my pic.png"},
+			wantRemain: []string{"ppt/media/image1.png", "ppt/slides/slide1.xml"},
+		},
+		{
+			name:       "percent-encoded target",
+			slideRels:  string(testRels("../media/my%20pic.png")),
+			want:       []string{"ppt/media/image1.png", "ppt/media/image2.png"},
+			wantRemain: []string{"ppt/media/my pic.png"},
+		},
+		{
+			name:       "malformed relationships keep everything",
+			slideRels:  `<Relationships><Relationship`,
+			want:       nil,
+			wantRemain: []string{"ppt/media/image1.png", "ppt/media/image2.png", "ppt/media/my pic.png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := NewPackage()
+			pkg.SetPart(rootRelsPath, testRels("ppt/presentation.xml"))
+			pkg.SetPart(presentationPath, []byte(`<p:presentation/>`))
+			pkg.SetPart("ppt/_rels/presentation.xml.rels", testRels("slides/slide1.xml"))
+			pkg.SetPart("ppt/slides/slide1.xml", []byte(`<p:sld/>`))
+			pkg.SetPart("ppt/slides/_rels/slide1.xml.rels", []byte(tt.slideRels))
+			for _, name := range []string{"ppt/media/image1.png", "ppt/media/image2.png", "ppt/media/my pic.png"} {
+				pkg.SetPart(name, []byte(name))
+			}
+
+			removed := pkg.CollectGarbage()
+			if fmt.Sprint(removed) != fmt.Sprint(tt.want) {
+				t.Errorf("CollectGarbage() = %v, want %v", removed, tt.want)
+			}
+			for _, name := range tt.wantRemain {
+				if pkg.Part(name) == nil {
+					t.Errorf("part %s was removed", name)
+				}
+			}
+		})
+	}
+}
+
+// testZipPackage 将 files 写入 zip 并以 readPackage 读取
+func testZipPackage(t *testing.T, files map[string]string) *Package {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := readPackage(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestPartDataCached(t *testing.T) {
+	pkg := testZipPackage(t, map[string]string{
+		contentTypesPath:        `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"ppt/slides/slide1.xml": "<p:sld/>",
+	})
+	part := pkg.Part("ppt/slides/slide1.xml")
+
+	first, err := part.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := part.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != "<p:sld/>" || &first[0] != &second[0] {
+		t.Errorf("Data() was decompressed again: %q, %q", first, second)
+	}
+
+	pkg.SetPart(part.Name(), []byte("<p:sld>changed</p:sld>"))
+	if data, _ := part.Data(); string(data) != "<p:sld>changed</p:sld>" {
+		t.Errorf("Data() after SetPart = %q", data)
+	}
+	if part.cache != nil {
+		t.Error("cache kept after the part was written")
+	}
+}
+
+// rawZipEntries 读取 zip 中每个条目的文件头和未解压的原始数据
+func rawZipEntries(t *testing.T, data []byte) map[string]*zip.File {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string]*zip.File)
+	for _, file := range reader.File {
+		entries[file.Name] = file
+	}
+	return entries
+}
+
+// rawBytes 读取条目压缩后的原始数据
+func rawBytes(t *testing.T, file *zip.File) []byte {
+	t.Helper()
+	r, err := file.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSaveCopiesUntouchedEntries(t *testing.T) {
+	original, err := os.ReadFile(templatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pres := openTemplate(t)
+	slide, err := pres.GetSlide(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := slide.GetPlaceholder(PlaceholderCtrTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := title.SetText("Hello"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "saved.pptx")
+	if err := pres.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, after := rawZipEntries(t, original), rawZipEntries(t, saved)
+	for name, file := range before {
+		copied, ok := after[name]
+		if !ok {
+			t.Errorf("%s is missing after Save", name)
+			continue
+		}
+		changed := copied.CRC32 != file.CRC32 || !bytes.Equal(rawBytes(t, copied), rawBytes(t, file))
+		if name == slide.path {
+			if !changed {
+				t.Errorf("%s was modified but saved unchanged", name)
+			}
+			continue
+		}
+		if changed {
+			t.Errorf("untouched %s was re-compressed: CRC %08x -> %08x", name, file.CRC32, copied.CRC32)
+		}
+		if copied.Method != file.Method || copied.CompressedSize64 != file.CompressedSize64 {
+			t.Errorf("untouched %s header changed: method %d -> %d, size %d -> %d",
+				name, file.Method, copied.Method, file.CompressedSize64, copied.CompressedSize64)
+		}
+	}
+}
@@ -1,14 +1,13 @@
 package pptx
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/beevik/etree"
+	"github.com/wanglihui/pptx-go/latex2mathml"
 )
 
 // PlaceholderType 定义占位符类型
@@ -22,13 +21,132 @@ const (
 	PlaceholderImage
 	PlaceholderChart
 	PlaceholderTable
-	PlaceholderShape
+	PlaceholderShape // 无法识别的类型，不是 ST_PlaceholderType 的取值
 	PlaceholderFooter
 	PlaceholderHeader
 	PlaceholderSlideNumber
 	PlaceholderDate
+	PlaceholderObject
+	PlaceholderMedia
+	PlaceholderClipArt
+	PlaceholderDiagram
+	PlaceholderSlideImage
 )
 
+// 拼写正确的别名，与 PlaceholderSubTile、PlaceholderCrtTitle 等价
+const (
+	PlaceholderSubTitle = PlaceholderSubTile
+	PlaceholderCtrTitle = PlaceholderCrtTitle
+)
+
+// placeholderTypeNames 占位符类型与 ST_PlaceholderType 取值的对应关系，PlaceholderShape 没有对应的取值
+var placeholderTypeNames = map[PlaceholderType]string{
+	PlaceholderTitle:       "title",
+	PlaceholderSubTile:     "subTitle",
+	PlaceholderCrtTitle:    "ctrTitle",
+	PlaceholderBody:        "body",
+	PlaceholderImage:       "pic",
+	PlaceholderChart:       "chart",
+	PlaceholderTable:       "tbl",
+	PlaceholderFooter:      "ftr",
+	PlaceholderHeader:      "hdr",
+	PlaceholderSlideNumber: "sldNum",
+	PlaceholderDate:        "dt",
+	PlaceholderObject:      "obj",
+	PlaceholderMedia:       "media",
+	PlaceholderClipArt:     "clipArt",
+	PlaceholderDiagram:     "dgm",
+	PlaceholderSlideImage:  "sldImg",
+}
+
+// String 返回占位符类型在 OOXML 中的取值，如 "title"、"subTitle"
+// PlaceholderShape 没有对应的取值，返回常量名
+func (t PlaceholderType) String() string {
+	if name, ok := placeholderTypeNames[t]; ok {
+		return name
+	}
+	if t == PlaceholderShape {
+		return "PlaceholderShape"
+	}
+	return fmt.Sprintf("PlaceholderType(%d)", int(t))
+}
+
+// MarshalText 实现 encoding.TextMarshaler，PlaceholderShape 不能写入 XML，返回错误
+func (t PlaceholderType) MarshalText() ([]byte, error) {
+	name, ok := placeholderTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("invalid placeholder type: %s", t)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (t *PlaceholderType) UnmarshalText(text []byte) error {
+	for pt, name := range placeholderTypeNames {
+		if name == string(text) {
+			*t = pt
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid placeholder type: %q", string(text))
+}
+
+// ContentKind 定义占位符可以承载的内容种类
+type ContentKind int
+
+const (
+	ContentText ContentKind = iota
+	ContentPicture
+	ContentTable
+	ContentChart
+	ContentDiagram
+	ContentMedia
+)
+
+// String 返回内容种类的名称
+func (k ContentKind) String() string {
+	switch k {
+	case ContentText:
+		return "text"
+	case ContentPicture:
+		return "picture"
+	case ContentTable:
+		return "table"
+	case ContentChart:
+		return "chart"
+	case ContentDiagram:
+		return "diagram"
+	case ContentMedia:
+		return "media"
+	default:
+		return fmt.Sprintf("ContentKind(%d)", int(k))
+	}
+}
+
+// Accepts 判断该类型的占位符是否可以承载指定种类的内容
+// obj 类型（以及未知类型）可以承载任意内容，sldImg 只用于备注页，不承载任何内容
+func (t PlaceholderType) Accepts(kind ContentKind) bool {
+	switch t {
+	case PlaceholderObject, PlaceholderShape:
+		return true
+	case PlaceholderImage, PlaceholderClipArt:
+		return kind == ContentPicture
+	case PlaceholderTable:
+		return kind == ContentTable
+	case PlaceholderChart:
+		return kind == ContentChart
+	case PlaceholderDiagram:
+		return kind == ContentDiagram
+	case PlaceholderMedia:
+		return kind == ContentMedia
+	case PlaceholderSlideImage:
+		return false
+	default:
+		// 标题、副标题、正文、页眉页脚、日期、编号等只承载文本
+		return kind == ContentText
+	}
+}
+
 // Placeholder 表示幻灯片中的占位符
 type Placeholder struct {
 	Type  PlaceholderType
@@ -40,9 +158,31 @@ type Placeholder struct {
 
 // SetText 设置占位符的文本内容，支持普通文本、LaTeX 公式和超链接
 func (p *Placeholder) SetText(text string, options ...TextOption) error {
+	return p.SetTextContext(context.Background(), text, options...)
+}
+
+// SetTextContext 与 SetText 相同，ctx 取消时停止 LaTeX 公式的转换并返回 ctx 的错误，占位符内容保持不变
+func (p *Placeholder) SetTextContext(ctx context.Context, text string, options ...TextOption) error {
 	if p.Shape == nil {
 		return fmt.Errorf("shape element is nil")
 	}
+	if err := p.checkAccepts(ContentText); err != nil {
+		return err
+	}
+
+	// 应用选项
+	opts := &TextOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	// 先生成新的段落，全部成功后再替换原有文本，失败时撤销生成段落时添加的超链接关系
+	rollback := p.slide.rels.checkpoint()
+	paras, err := p.buildParagraphs(ctx, text, opts)
+	if err != nil {
+		rollback()
+		return err
+	}
 
 	// 查找或创建 txBody
 	txBody := p.Shape.FindElement("p:txBody")
@@ -50,54 +190,128 @@ func (p *Placeholder) SetText(text string, options ...TextOption) error {
 		txBody = p.Shape.CreateElement("p:txBody")
 	}
 
-	// 清除现有文本
+	// 清除现有文本，原有文本中不再使用的超链接关系一并删除
+	var refs []string
 	for _, a := range txBody.SelectElements("a:p") {
+		refs = append(refs, relationshipRefs(a)...)
 		txBody.RemoveChild(a)
 	}
+	for _, para := range paras {
+		txBody.AddChild(para)
+	}
+	for _, rId := range refs {
+		p.slide.releaseRelationship(rId)
+	}
 
-	// 创建新的段落
-	para := txBody.CreateElement("a:p")
+	return p.slide.SaveChanges()
+}
 
-	// 应用选项
-	opts := &TextOptions{}
-	for _, option := range options {
-		option(opts)
+// buildParagraphs 根据文本和选项生成段落
+// 行内公式放在当前段落中，行间公式独占一段，公式前后的文本分别放在前后的段落中
+func (p *Placeholder) buildParagraphs(ctx context.Context, text string, opts *TextOptions) ([]*etree.Element, error) {
+	para := etree.NewElement("a:p")
+	if !opts.EnableLatex && !opts.EnableMathML {
+		if err := p.addTextRun(para, text, opts); err != nil {
+			return nil, err
+		}
+		return []*etree.Element{para}, nil
 	}
 
+	var converter latex2mathml.Converter
 	if opts.EnableLatex {
-		// LaTeX 处理逻辑
-		segments := parseLatexFormula(text)
-		for _, segment := range segments {
-			if segment.IsLatex {
-				// 转换 LaTeX 为 OMML
-				ommlElements, err := convertLatexToOMML(segment.Text)
-				if err != nil {
-					return fmt.Errorf("failed to convert LaTeX to OMML: %w", err)
-				}
-				for _, elem := range ommlElements {
-					// 添加 a14:m 容器
-					mathContainer := etree.NewElement("a14:m")
-					mathContainer.CreateAttr("xmlns:a14", "http://schemas.microsoft.com/office/drawing/2010/main")
-					mathContainer.AddChild(elem)
-					para.AddChild(mathContainer)
-				}
-			} else {
-				p.addTextRun(para, segment.Text, opts)
+		var err error
+		if converter, err = opts.latexConverter(); err != nil {
+			return nil, err
+		}
+	}
+
+	paras := []*etree.Element{para}
+	segments := newFormulaScanner(opts).scan(text)
+	for i, segment := range segments {
+		if !segment.IsLatex && !segment.IsMathML {
+			// 行间公式所在的段落已经断开，去掉与其相邻的换行
+			if i > 0 && segments[i-1].Display {
+				segment.Text = strings.TrimLeft(segment.Text, "\r\n")
+			}
+			if i+1 < len(segments) && segments[i+1].Display {
+				segment.Text = strings.TrimRight(segment.Text, "\r\n")
+			}
+			if segment.Text == "" {
+				continue
+			}
+			if err := p.addTextRun(para, segment.Text, opts); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if segment.IsMathML {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			oMath, err := MathMLToOMML(segment.Text)
+			if err != nil {
+				return nil, err
+			}
+			para, paras = appendFormula(para, paras, oMath, segment.Display, opts.MathJustification)
+			continue
+		}
+
+		// 转换 LaTeX 为 OMML
+		oMath, err := convertLatexToOMML(ctx, converter, segment.Text, segment.Display)
+		if err != nil && opts.LatexFallback && errors.Is(err, ErrLatexSyntax) {
+			// 公式有语法错误时按原样输出
+			if err := p.addTextRun(para, segment.Source, opts); err != nil {
+				return nil, err
 			}
+			continue
 		}
-	} else {
-		// 普通文本处理
-		p.addTextRun(para, text, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert LaTeX to OMML: %w", err)
+		}
+		para, paras = appendFormula(para, paras, oMath, segment.Display, opts.MathJustification)
 	}
 
-	return p.slide.SaveChanges()
+	// 去掉行间公式之后多余的空段落
+	if len(paras) > 1 && len(para.ChildElements()) == 0 {
+		paras = paras[:len(paras)-1]
+	}
+	return paras, nil
+}
+
+// appendFormula 将公式添加到段落中，行间公式独占一段，返回之后的文本所在的段落
+func appendFormula(para *etree.Element, paras []*etree.Element, oMath *etree.Element, display bool, jc MathJustification) (*etree.Element, []*etree.Element) {
+	if !display {
+		para.AddChild(newMathContainer(oMath))
+		return para, paras
+	}
+
+	if len(para.ChildElements()) > 0 {
+		para = etree.NewElement("a:p")
+		paras = append(paras, para)
+	}
+	para.CreateElement("a:pPr").CreateAttr("algn", jc.paragraphAlign())
+	para.AddChild(newMathContainer(newMathPara(oMath, jc)))
+	para = etree.NewElement("a:p")
+	return para, append(paras, para)
 }
 
 // TextOptions 定义文本设置的选项
 type TextOptions struct {
-	EnableLatex bool
-	Link        string   // 超链接URL
-	LinkType    LinkType // 超链接类型
+	EnableLatex       bool
+	EnableMathML      bool                    // 识别文本中的 <math> 元素
+	MathJustification MathJustification       // 行间公式的对齐方式，默认居中
+	LatexFallback     bool                    // 公式有语法错误时按原样输出为文本，而不是返回错误
+	LatexMacros       latex2mathml.Macros     // 所有公式共用的宏
+	Equations         *latex2mathml.Equations // 公式自动编号的状态，为空时不自动编号
+	LatexDelimiters   []LatexDelimiter        // 识别的公式定界符，为空时使用 DefaultLatexDelimiters
+	PandocDollarRule  bool                    // 单个 $ 使用 Pandoc 规则：开始的 $ 后和结束的 $ 前不能是空白，结束的 $ 后不能是数字
+	LatexPreamble     string                  // 包含 \newcommand、\def、\DeclareMathOperator 等宏定义的导言区，与 LatexMacros 同名时覆盖
+	Link              string                  // 超链接URL
+	LinkType          LinkType                // 超链接类型
+	LinkSlide         *Slide                  // 跳转的目标幻灯片
+	Jump              SlideJump               // 放映时的命名跳转
+	Tooltip           string                  // 超链接提示
 }
 
 // LinkType 定义超链接类型
@@ -111,13 +325,94 @@ const (
 // TextOption 定义文本设置的选项函数
 type TextOption func(*TextOptions)
 
-// WithLatex 启用 LaTeX 支持
+// WithLatex 启用 LaTeX 支持，$…$ 和 \(…\) 为行内公式，$$…$$ 和 \[…\] 为独占一段的行间公式
 func WithLatex() TextOption {
 	return func(o *TextOptions) {
 		o.EnableLatex = true
 	}
 }
 
+// WithMathML 识别文本中的 MathML 公式，如 "面积为 <math><msup><mi>r</mi><mn>2</mn></msup></math>"
+// <math> 元素直接转换为 OMML，不经过 LaTeX，display="block" 的公式独占一段，可以与 WithLatex 同时使用
+func WithMathML() TextOption {
+	return func(o *TextOptions) {
+		o.EnableMathML = true
+	}
+}
+
+// WithLatexDelimiters 设置识别的公式定界符，替换 DefaultLatexDelimiters，如
+//
+//	WithLatexDelimiters(LatexDelimiter{Open: "@@", Close: "@@"}, LatexDelimiter{Open: "[math]", Close: "[/math]", Display: true})
+func WithLatexDelimiters(delimiters ...LatexDelimiter) TextOption {
+	return func(o *TextOptions) {
+		o.LatexDelimiters = delimiters
+	}
+}
+
+// WithPandocDollarRule 单个 $ 使用 Pandoc 的规则识别公式，避免把 "$5 和 $10" 这样的金额识别为公式
+func WithPandocDollarRule() TextOption {
+	return func(o *TextOptions) {
+		o.PandocDollarRule = true
+	}
+}
+
+// WithLatexFallback 公式有语法错误时将其（包括定界符）按原样输出为普通文本，默认返回 ErrLatexSyntax 错误
+func WithLatexFallback() TextOption {
+	return func(o *TextOptions) {
+		o.LatexFallback = true
+	}
+}
+
+// WithLatexMacros 设置所有公式共用的宏，多次调用时合并，可以用 latex2mathml.ParseMacros 从导言区解析
+// 同一组宏可以用于整个演示文稿中的文本
+func WithLatexMacros(macros latex2mathml.Macros) TextOption {
+	return func(o *TextOptions) {
+		if o.LatexMacros == nil {
+			o.LatexMacros = latex2mathml.Macros{}
+		}
+		for name, macro := range macros {
+			o.LatexMacros[name] = macro
+		}
+	}
+}
+
+// WithLatexPreamble 设置包含 \newcommand、\renewcommand、\def、\DeclareMathOperator 等宏定义的导言区，
+// 其中定义的宏可以在所有公式中使用
+func WithLatexPreamble(preamble string) TextOption {
+	return func(o *TextOptions) {
+		o.LatexPreamble = preamble
+	}
+}
+
+// WithEquationNumbering 为 align、gather、multline 和 equation 环境中的公式自动编号，并解析 \label、\eqref 和 \ref
+// 同一张幻灯片或整个演示文稿中的文本使用同一个 equations 时编号连续
+func WithEquationNumbering(equations *latex2mathml.Equations) TextOption {
+	return func(o *TextOptions) {
+		o.Equations = equations
+	}
+}
+
+// latexConverter 根据文本选项创建 LaTeX 转换器，导言区中定义的宏与 LatexMacros 合并
+func (o *TextOptions) latexConverter() (latex2mathml.Converter, error) {
+	converter := latex2mathml.Converter{Macros: o.LatexMacros, Equations: o.Equations}
+	if o.LatexPreamble == "" {
+		return converter, nil
+	}
+	macros, err := o.LatexMacros.Parse(o.LatexPreamble)
+	if err != nil {
+		return converter, fmt.Errorf("failed to parse LaTeX preamble: %w", &LatexError{Latex: o.LatexPreamble, Err: err})
+	}
+	converter.Macros = macros
+	return converter, nil
+}
+
+// WithMathJustification 设置行间公式（$$…$$ 或 \[…\]）的对齐方式
+func WithMathJustification(jc MathJustification) TextOption {
+	return func(o *TextOptions) {
+		o.MathJustification = jc
+	}
+}
+
 // WithLink 添加超链接
 func WithLink(url string, linkType LinkType) TextOption {
 	return func(o *TextOptions) {
@@ -126,8 +421,59 @@ func WithLink(url string, linkType LinkType) TextOption {
 	}
 }
 
+// WithSlideLink 添加跳转到演示文稿中另一张幻灯片的超链接
+func WithSlideLink(slide *Slide) TextOption {
+	return func(o *TextOptions) {
+		o.LinkSlide = slide
+	}
+}
+
+// WithSlideAction 添加放映时的命名跳转，如下一张、上一张、结束放映
+func WithSlideAction(jump SlideJump) TextOption {
+	return func(o *TextOptions) {
+		o.Jump = jump
+	}
+}
+
+// WithTooltip 设置超链接的提示文本
+func WithTooltip(tooltip string) TextOption {
+	return func(o *TextOptions) {
+		o.Tooltip = tooltip
+	}
+}
+
+// action 根据选项生成超链接动作，没有设置超链接时返回 false
+func (p *Placeholder) action(opts *TextOptions) (Action, bool, error) {
+	if opts == nil {
+		return Action{}, false, nil
+	}
+	action := Action{Jump: opts.Jump, Slide: opts.LinkSlide, Tooltip: opts.Tooltip}
+	if opts.Link != "" {
+		if opts.LinkType == LinkTypeSlide {
+			if p.slide == nil || p.slide.pres == nil {
+				return Action{}, false, fmt.Errorf("placeholder is not attached to a presentation, cannot resolve target slide: %s", opts.Link)
+			}
+			// 兼容以部件路径指定目标幻灯片的写法
+			slide := p.slide.pres.getSlideByPath(resolvePartPath(p.slide.path, opts.Link))
+			if slide == nil {
+				slide = p.slide.pres.getSlideByPath(opts.Link)
+			}
+			if slide == nil {
+				return Action{}, false, fmt.Errorf("target slide not found: %s", opts.Link)
+			}
+			action.Slide = slide
+		} else {
+			action.URL = opts.Link
+		}
+	}
+	if action.Jump == JumpNone && action.Slide == nil && action.URL == "" {
+		return Action{}, false, nil
+	}
+	return action, true, nil
+}
+
 // addTextRun 添加文本运行
-func (p *Placeholder) addTextRun(para *etree.Element, text string, opts *TextOptions) {
+func (p *Placeholder) addTextRun(para *etree.Element, text string, opts *TextOptions) error {
 	run := para.CreateElement("a:r")
 
 	// 添加运行属性
@@ -140,110 +486,92 @@ func (p *Placeholder) addTextRun(para *etree.Element, text string, opts *TextOpt
 	t.SetText(text)
 
 	// 如果有超链接，添加超链接
-	if opts != nil && opts.Link != "" {
-		// 获取 slide 引用
-		slide := p.slide
-		if slide != nil {
-			// 创建关系ID
-			rId := fmt.Sprintf("rId%d", len(slide.rels)+1)
-
-			// 添加超链接元素
-			hlinkClick := rPr.CreateElement("a:hlinkClick")
-			hlinkClick.CreateAttr("r:id", rId)
-
-			// 更新关系
-			if err := p.updateHyperlinkRelationship(rId, opts.Link, opts.LinkType); err != nil {
-				// 在际应用中，你可能需要更好的错误处理
-				fmt.Printf("Failed to update hyperlink relationship: %v\n", err)
+	if p.slide != nil {
+		action, ok, err := p.action(opts)
+		if err != nil {
+			return err
+		}
+		if ok {
+			hlinkClick, err := p.slide.newHyperlink("a:hlinkClick", action)
+			if err != nil {
+				return fmt.Errorf("failed to add hyperlink: %w", err)
 			}
+			rPr.AddChild(hlinkClick)
 		}
 	}
+
+	return nil
 }
 
-// updateHyperlinkRelationship 更新超链接关系
-func (p *Placeholder) updateHyperlinkRelationship(rId, target string, linkType LinkType) error {
-	// 获取 slide 引用
-	slide := p.slide
-	if slide == nil {
-		return fmt.Errorf("could not find slide reference")
-	}
+// ImageOptions 定义设置图片时的选项
+type ImageOptions struct {
+	Source ImageSource // 获取图片使用的图片源，为 nil 时使用演示文稿的图片源
+}
 
-	// 创建关系
-	rel := &Relationship{
-		Id:     rId,
-		Target: target,
-	}
+// ImageOption 定义图片选项的函数类型
+type ImageOption func(*ImageOptions)
 
-	if linkType == LinkTypeExternal {
-		rel.Type = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
-		rel.TargetMode = "External"
-	} else {
-		rel.Type = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+// WithImageSource 本次调用使用指定的图片源
+func WithImageSource(source ImageSource) ImageOption {
+	return func(o *ImageOptions) {
+		o.Source = source
 	}
-
-	// 添加到幻灯片关系中
-	slide.rels[rId] = rel
-
-	return nil
 }
 
-// SetImage 设置占位符的图片，支持本地文件路径和网络URL
-func (p *Placeholder) SetImage(imagePath string) error {
-	var imageData []byte
-	var err error
+// SetImage 设置占位符的图片
+// 默认支持网络URL和 data: URI，读取本地文件需要通过 Presentation.SetImageSource 设置带 WithImageFiles 的图片源，也可以用 WithImageSource 替换图片源
+func (p *Placeholder) SetImage(imagePath string, options ...ImageOption) error {
+	return p.SetImageContext(context.Background(), imagePath, options...)
+}
 
-	// 检查是否为网络URL
-	if strings.HasPrefix(imagePath, "http://") || strings.HasPrefix(imagePath, "https://") {
-		// 下载网络图片
-		imageData, err = downloadImage(imagePath)
-		if err != nil {
-			return fmt.Errorf("failed to download image: %w", err)
-		}
-	} else {
-		// 读取本地图片文件
-		imageData, err = ioutil.ReadFile(imagePath)
-		if err != nil {
-			return fmt.Errorf("failed to read image file: %w", err)
-		}
+// SetImageContext 与 SetImage 相同，ctx 会传递给图片源，用于取消下载
+func (p *Placeholder) SetImageContext(ctx context.Context, imagePath string, options ...ImageOption) error {
+	if err := p.checkAccepts(ContentPicture); err != nil {
+		return err
 	}
 
-	// 获取当前演示文稿中的媒体文件数量
-	mediaCount := 0
-	for path := range p.slide.pres.files {
-		if strings.HasPrefix(path, "ppt/media/") {
-			mediaCount++
-		}
+	opts := &ImageOptions{}
+	for _, option := range options {
+		option(opts)
 	}
-
-	// 生成新的图片路径
-	imgExt := strings.ToLower(filepath.Ext(imagePath))
-	if imgExt == "" {
-		imgExt = ".png"
+	source := opts.Source
+	if source == nil {
+		source = p.slide.pres.ImageSource()
 	}
-	imgPath := fmt.Sprintf("ppt/media/image%d%s", mediaCount+1, imgExt)
 
-	// 更新 [Content_Types].xml 添加图片格式声明
-	contentType := getImageContentType(imgExt)
-	if err := p.updateContentTypes(imgExt, contentType); err != nil {
-		return fmt.Errorf("failed to update content types: %w", err)
+	// 解析占位符实际生效的位置和大小
+	frame, err := p.EffectiveFrame()
+	if err != nil {
+		return fmt.Errorf("failed to resolve placeholder frame: %w", err)
 	}
 
-	// 创建关系ID
-	rId := fmt.Sprintf("rId%d", len(p.slide.rels)+1)
+	// 检查父元素
+	if p.Shape.Parent() == nil {
+		return fmt.Errorf("placeholder parent element not found")
+	}
 
-	// 更新关系文件
-	if err := p.updateImageRelationship(rId, filepath.Base(imgPath)); err != nil {
+	img, err := source.Fetch(ctx, imagePath)
+	if err != nil {
+		return fmt.Errorf("failed to fetch image: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+	contentType := img.ContentType
+	if contentType == "" {
+		if contentType, err = sniffImageType(img.Data, ""); err != nil {
+			return fmt.Errorf("failed to fetch image: %w", err)
+		}
+	}
 
-	// 获取父元素
-	parent := p.Shape.Parent()
-	if parent == nil {
-		return fmt.Errorf("placeholder parent element not found")
+	// 保存图片数据，内容相同的图片复用已有的媒体部件
+	imgPath, err := p.slide.pres.addMedia(img.Data, imageExtension(contentType, imagePath), contentType)
+	if err != nil {
+		return fmt.Errorf("failed to add image: %w", err)
 	}
 
-	// 生成唯一ID
-	shapeId := fmt.Sprintf("%d", len(parent.ChildElements())+1)
+	// 沿用原占位符的形状ID
+	shapeId := p.shapeId()
 
 	// 创建新的 p:pic 元素
 	pic := etree.NewElement("p:pic")
@@ -258,290 +586,280 @@ func (p *Placeholder) SetImage(imagePath string) error {
 	cNvPicPr.CreateElement("a:picLocks").CreateAttr("noChangeAspect", "1")
 
 	nvPr := nvPicPr.CreateElement("p:nvPr")
-	ph := nvPr.CreateElement("p:ph")
-	ph.CreateAttr("type", "pic")
+	nvPr.AddChild(p.placeholderRef("pic"))
 
-	// 添加 blipFill
+	// 添加 blipFill，所有检查都已通过，此时才添加图片关系
 	blipFill := pic.CreateElement("p:blipFill")
 	blip := blipFill.CreateElement("a:blip")
-	blip.CreateAttr("r:embed", rId)
+	blip.CreateAttr("r:embed", p.slide.rels.Add(RelTypeImage, relativePartPath(p.slide.path, imgPath), ""))
 
 	stretch := blipFill.CreateElement("a:stretch")
 	stretch.CreateElement("a:fillRect")
 
-	// 复制原占位符的 spPr (shape properties)
+	// 使用解析后的位置和大小
 	spPr := pic.CreateElement("p:spPr")
-	if originalSpPr := p.Shape.FindElement("p:spPr"); originalSpPr != nil {
-		// 复制变换信息
-		if xfrm := originalSpPr.FindElement("a:xfrm"); xfrm != nil {
-			newXfrm := spPr.CreateElement("a:xfrm")
-			// 复制所有属性
-			for _, attr := range xfrm.Attr {
-				newXfrm.CreateAttr(attr.Key, attr.Value)
-			}
-
-			// 复制位置和大小元素，确保使用正确的命名空间
-			if off := xfrm.FindElement("off"); off != nil {
-				newOff := newXfrm.CreateElement("a:off")
-				for _, attr := range off.Attr {
-					newOff.CreateAttr(attr.Key, attr.Value)
-				}
-			}
-			if ext := xfrm.FindElement("ext"); ext != nil {
-				newExt := newXfrm.CreateElement("a:ext")
-				for _, attr := range ext.Attr {
-					newExt.CreateAttr(attr.Key, attr.Value)
-				}
-			}
-		}
-
-		// 添加预设形状
-		prstGeom := spPr.CreateElement("a:prstGeom")
-		prstGeom.CreateAttr("prst", "rect")
-		prstGeom.CreateElement("a:avLst")
-	}
+	writeFrame(spPr, "a:xfrm", frame)
 
-	// 移除原占位符��添加新的 pic 元素
-	parent.RemoveChild(p.Shape)
-	parent.AddChild(pic)
+	// 添加预设形状
+	prstGeom := spPr.CreateElement("a:prstGeom")
+	prstGeom.CreateAttr("prst", "rect")
+	prstGeom.CreateElement("a:avLst")
 
-	// 更新 Shape 引用
-	p.Shape = pic
-
-	// 保存图片数据
-	p.slide.pres.files[imgPath] = imageData
+	// 在原占位符的位置替换为新的 pic 元素，保持层叠顺序
+	p.replaceShape(pic)
 
 	// 保存幻灯片更改
 	return p.slide.SaveChanges()
 }
 
-// getImageContentType 根据文件扩展名返回对应的 Content Type
-func getImageContentType(ext string) string {
-	switch strings.ToLower(ext) {
-	case ".png":
-		return "image/png"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".gif":
-		return "image/gif"
-	case ".bmp":
-		return "image/bmp"
-	case ".tiff":
-		return "image/tiff"
-	case ".wmf":
-		return "image/x-wmf"
-	default:
-		return "image/png"
-	}
-}
-
-// updateContentTypes 更新 [Content_Types].xml 文件
-func (p *Placeholder) updateContentTypes(ext, contentType string) error {
-	// 获取 [Content_Types].xml 文件
-	contentTypesData, exists := p.slide.pres.files["[Content_Types].xml"]
-	if !exists {
-		return fmt.Errorf("content types file not found")
-	}
-
-	// 解析 XML
-	doc := etree.NewDocument()
-	if err := doc.ReadFromBytes(contentTypesData); err != nil {
-		return fmt.Errorf("failed to parse content types: %w", err)
-	}
-
-	// 查找 Types 元素
-	types := doc.FindElement("Types")
-	if types == nil {
-		return fmt.Errorf("types element not found")
-	}
-
-	// 去掉扩展名前面的点号
-	cleanExt := strings.TrimPrefix(ext, ".")
-
-	// 检查是否已存在该扩展名的声明
-	extExists := false
-	for _, default_ := range types.SelectElements("Default") {
-		if extension := default_.SelectAttrValue("Extension", ""); extension == cleanExt {
-			extExists = true
-			break
-		}
+// SetTable 设置占位符的表格内容
+func (p *Placeholder) SetTable(data [][]string) error {
+	if err := p.checkAccepts(ContentTable); err != nil {
+		return err
 	}
-
-	// 如果不存在，添加新的声明
-	if !extExists {
-		default_ := types.CreateElement("Default")
-		default_.CreateAttr("Extension", cleanExt)
-		default_.CreateAttr("ContentType", contentType)
-
-		// 保存更新后的文件
-		data, err := doc.WriteToBytes()
-		if err != nil {
-			return fmt.Errorf("failed to serialize content types: %w", err)
-		}
-		p.slide.pres.files["[Content_Types].xml"] = data
+	if len(data) == 0 || len(data[0]) == 0 {
+		return fmt.Errorf("table data is empty")
 	}
 
-	return nil
-}
-
-// downloadImage 下载网络图片
-func downloadImage(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+	// 解析占位符实际生效的位置和大小
+	frame, err := p.EffectiveFrame()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to resolve placeholder frame: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download image, status code: %d", resp.StatusCode)
+	// 检查父元素
+	if p.Shape.Parent() == nil {
+		return fmt.Errorf("placeholder parent element not found")
 	}
 
-	return ioutil.ReadAll(resp.Body)
-}
-
-// updateImageRelationship 更新图片关系
-func (p *Placeholder) updateImageRelationship(rId, imagePath string) error {
-	// 创建或更新关系文件
-	relsDoc := etree.NewDocument()
-	relationships := relsDoc.CreateElement("Relationships")
-	relationships.CreateAttr("xmlns", NsRelationships)
-
-	rel := relationships.CreateElement("Relationship")
-	rel.CreateAttr("Id", rId)
-	rel.CreateAttr("Type", "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image")
-	rel.CreateAttr("Target", "../media/"+filepath.Base(imagePath))
+	shapeId := p.shapeId()
+	rows := len(data)
+	cols := len(data[0])
 
-	// 保存关系文件
-	data, err := relsDoc.WriteToBytes()
-	if err != nil {
-		return fmt.Errorf("failed to serialize relationships: %w", err)
-	}
+	// 创建 p:graphicFrame 元素
+	graphicFrame := etree.NewElement("p:graphicFrame")
+	nvGraphicFramePr := graphicFrame.CreateElement("p:nvGraphicFramePr")
+	cNvPr := nvGraphicFramePr.CreateElement("p:cNvPr")
+	cNvPr.CreateAttr("id", shapeId)
+	cNvPr.CreateAttr("name", "Table "+shapeId)
+	cNvGraphicFramePr := nvGraphicFramePr.CreateElement("p:cNvGraphicFramePr")
+	cNvGraphicFramePr.CreateElement("a:graphicFrameLocks").CreateAttr("noGrp", "1")
+	nvPr := nvGraphicFramePr.CreateElement("p:nvPr")
+	nvPr.AddChild(p.placeholderRef("tbl"))
 
-	p.slide.pres.files[p.slide.relsPath] = data // 使用presentation的files
-	p.slide.rels[rId] = &Relationship{
-		Id:     rId,
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image",
-		Target: "../media/" + filepath.Base(imagePath),
-	}
+	// 使用解析后的位置和大小
+	writeFrame(graphicFrame, "p:xfrm", frame)
 
-	return nil
-}
-
-// SetTable 设置占位符的表格内容
-func (p *Placeholder) SetTable(data [][]string) error {
-	graphicFrame := p.Shape.FindElement("graphicFrame")
-	if graphicFrame == nil {
-		return fmt.Errorf("graphic frame not found")
-	}
+	graphic := graphicFrame.CreateElement("a:graphic")
+	graphicData := graphic.CreateElement("a:graphicData")
+	graphicData.CreateAttr("uri", "http://schemas.openxmlformats.org/drawingml/2006/table")
 
 	// 创建表格
-	tbl := graphicFrame.CreateElement("a:tbl")
+	tbl := graphicData.CreateElement("a:tbl")
+	tblPr := tbl.CreateElement("a:tblPr")
+	tblPr.CreateAttr("firstRow", "1")
+	tblPr.CreateAttr("bandRow", "1")
 
-	// 设置表格网格
+	// 设置表格网格，列宽和行高按占位符大小均分
 	tblGrid := tbl.CreateElement("a:tblGrid")
-	for range data[0] {
-		tblGrid.CreateElement("a:gridCol")
+	for i := 0; i < cols; i++ {
+		tblGrid.CreateElement("a:gridCol").CreateAttr("w", fmt.Sprintf("%d", frame.Width/int64(cols)))
 	}
 
 	// 添加行和单元格
 	for _, row := range data {
 		tr := tbl.CreateElement("a:tr")
-		for _, cell := range row {
+		tr.CreateAttr("h", fmt.Sprintf("%d", frame.Height/int64(rows)))
+		for i := 0; i < cols; i++ {
 			tc := tr.CreateElement("a:tc")
 			txBody := tc.CreateElement("a:txBody")
-			p := txBody.CreateElement("a:p")
-			r := p.CreateElement("a:r")
-			t := r.CreateElement("a:t")
-			t.SetText(cell)
+			txBody.CreateElement("a:bodyPr")
+			txBody.CreateElement("a:lstStyle")
+			para := txBody.CreateElement("a:p")
+			if i < len(row) {
+				r := para.CreateElement("a:r")
+				t := r.CreateElement("a:t")
+				t.SetText(row[i])
+			}
+			tc.CreateElement("a:tcPr")
 		}
 	}
 
+	// 在原占位符的位置替换为表格
+	p.replaceShape(graphicFrame)
+
 	// 保存更改
 	return p.slide.SaveChanges()
 }
 
+// replaceShape 在原形状的位置插入新形状，保持层叠顺序
+// 原形状引用的关系（如之前设置的图片、文本中的超链接）不再被使用时一并删除
+func (p *Placeholder) replaceShape(shape *etree.Element) {
+	old := p.Shape
+	refs := relationshipRefs(old)
+	parent := old.Parent()
+	index := old.Index()
+	parent.RemoveChild(old)
+	parent.InsertChildAt(index, shape)
+	p.Shape = shape
+
+	for _, rId := range refs {
+		p.slide.releaseRelationship(rId)
+	}
+}
+
+// shapeId 获取占位符形状的 cNvPr id
+func (p *Placeholder) shapeId() string {
+	for _, nv := range []string{"nvSpPr", "nvPicPr", "nvGraphicFramePr"} {
+		if cNvPr := p.Shape.FindElement(nv + "/cNvPr"); cNvPr != nil {
+			if id := cNvPr.SelectAttrValue("id", ""); id != "" {
+				return id
+			}
+		}
+	}
+	if parent := p.Shape.Parent(); parent != nil {
+		return fmt.Sprintf("%d", len(parent.ChildElements())+1)
+	}
+	return "1"
+}
+
+// placeholderRef 复制占位符的 p:ph 元素，使替换后的形状仍能继承布局
+// 原占位符没有 p:ph 时使用 defaultType 创建
+func (p *Placeholder) placeholderRef(defaultType string) *etree.Element {
+	if ph := placeholderProps(p.Shape); ph != nil {
+		return ph.Copy()
+	}
+	ph := etree.NewElement("p:ph")
+	ph.CreateAttr("type", defaultType)
+	return ph
+}
+
 // parsePlaceholderType 解析占位符类型
+// 未指定类型时按 OOXML 的默认值 obj 处理，无法识别的类型归为 PlaceholderShape
 func parsePlaceholderType(typeStr string) PlaceholderType {
-	switch typeStr {
-	case "title":
-		return PlaceholderTitle
-	case "body":
-		return PlaceholderBody
-	case "pic":
-		return PlaceholderImage
-	case "chart":
-		return PlaceholderChart
-	case "tbl":
-		return PlaceholderTable
-	case "ftr":
-		return PlaceholderFooter
-	case "hdr":
-		return PlaceholderHeader
-	case "sldNum":
-		return PlaceholderSlideNumber
-	case "dt":
-		return PlaceholderDate
-	default:
+	if typeStr == "" {
+		return PlaceholderObject
+	}
+	var t PlaceholderType
+	if err := t.UnmarshalText([]byte(typeStr)); err != nil {
 		return PlaceholderShape
 	}
+	return t
+}
+
+// checkAccepts 检查占位符是否可以承载指定种类的内容
+func (p *Placeholder) checkAccepts(kind ContentKind) error {
+	if !p.Type.Accepts(kind) {
+		return fmt.Errorf("placeholder type %s does not accept %s content", p.Type, kind)
+	}
+	return nil
 }
 
 // GetPlaceholders 获取幻灯片中所有占位符
 func (s *Slide) GetPlaceholders() ([]*Placeholder, error) {
-	var placeholders []*Placeholder
+	return s.FindPlaceholders(nil)
+}
 
-	spTree := s.xml.FindElement("//p:spTree")
-	if spTree == nil {
-		return nil, fmt.Errorf("shape tree not found in slide")
+// Text 获取占位符的文本内容，多个段落之间以换行符分隔
+// 公式转换为 LaTeX，行内公式为 $…$，行间公式为 $$…$$，与 WithLatex 的输入格式一致
+// 有公式时文本中的 $ 和 \ 转义为 \$ 和 \\，结果可以再用 WithLatex 设置而不改变内容
+func (p *Placeholder) Text() string {
+	if p.Shape == nil {
+		return ""
+	}
+	txBody := p.Shape.FindElement("txBody")
+	if txBody == nil {
+		return ""
 	}
+	return textBodyText(txBody)
+}
 
-	for _, sp := range spTree.SelectElements("p:sp") {
-		nvSpPr := sp.FindElement("p:nvSpPr")
-		if nvSpPr == nil {
-			continue
+// joinedRunText 返回所有段落中 a:r 的文本直接拼接的结果，不包含换行、字段和公式
+func (p *Placeholder) joinedRunText() string {
+	if p.Shape == nil {
+		return ""
+	}
+	txBody := p.Shape.FindElement("txBody")
+	if txBody == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, para := range txBody.SelectElements("p") {
+		for _, r := range para.SelectElements("r") {
+			if t := r.SelectElement("t"); t != nil {
+				text.WriteString(t.Text())
+			}
 		}
+	}
+	return text.String()
+}
 
-		ph := nvSpPr.FindElement(".//p:ph")
-		if ph == nil {
-			continue
-		}
+// latexTextEscaper 转义文本中的 $ 和 \，使其不会被识别为公式的定界符
+var latexTextEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`)
 
-		placeholder := &Placeholder{
-			Shape: sp,
-			slide: s,
-		}
+// textBodyText 获取 txBody 中的文本，多个段落之间以换行符分隔，有公式时转义文本中的 $ 和 \
+func textBodyText(txBody *etree.Element) string {
+	paras := txBody.SelectElements("p")
+	escape := false
+	for _, para := range paras {
+		escape = escape || hasMath(para.ChildElements())
+	}
 
-		// 设置占位符属性
-		if typeAttr := ph.SelectAttr("type"); typeAttr != nil {
-			placeholder.Type = parsePlaceholderType(typeAttr.Value)
-		}
+	var paragraphs []string
+	for _, para := range paras {
+		var text strings.Builder
+		writeParagraphText(&text, para.ChildElements(), escape)
+		paragraphs = append(paragraphs, text.String())
+	}
+	return strings.Join(paragraphs, "\n")
+}
 
-		if nvPr := nvSpPr.FindElement("p:nvPr"); nvPr != nil {
-			if name := nvPr.SelectAttrValue("name", ""); name != "" {
-				placeholder.Name = name
+// hasMath 判断段落中是否有公式
+func hasMath(children []*etree.Element) bool {
+	for _, child := range children {
+		switch child.Tag {
+		case "m":
+			return true
+		case "AlternateContent":
+			if choice := child.SelectElement("Choice"); choice != nil && choice.SelectElement("m") != nil {
+				return true
 			}
 		}
+	}
+	return false
+}
 
-		if idxAttr := ph.SelectAttr("idx"); idxAttr != nil {
-			placeholder.Index, _ = strconv.Atoi(idxAttr.Value)
+// writeParagraphText 将段落中的文本运行、换行和公式写入 text，escape 为 true 时转义文本中的 $ 和 \
+func writeParagraphText(text *strings.Builder, children []*etree.Element, escape bool) {
+	for _, child := range children {
+		switch child.Tag {
+		case "r", "fld":
+			if t := child.SelectElement("t"); t != nil {
+				value := t.Text()
+				if escape {
+					value = latexTextEscaper.Replace(value)
+				}
+				text.WriteString(value)
+			}
+		case "br":
+			text.WriteString("\n")
+		case "m":
+			text.WriteString(ommlText(child))
+		case "AlternateContent":
+			// PowerPoint 保存的公式放在 mc:Choice 中，mc:Fallback 是不支持公式的程序显示的内容
+			if choice := child.SelectElement("Choice"); choice != nil && choice.SelectElement("m") != nil {
+				writeParagraphText(text, choice.ChildElements(), escape)
+			} else if fallback := child.SelectElement("Fallback"); fallback != nil {
+				writeParagraphText(text, fallback.ChildElements(), escape)
+			}
 		}
-
-		placeholders = append(placeholders, placeholder)
 	}
-
-	return placeholders, nil
 }
 
-// SaveChanges 保存对幻灯片的更改
+// SaveChanges 标记幻灯片已修改，XML 在 Save 时才序列化
+// 直接修改 Placeholder.Shape 等XML元素后需要调用，否则 Save 会原样保留幻灯片原有的内容
 func (s *Slide) SaveChanges() error {
-	if s.xml != nil {
-		data, err := s.xml.WriteToBytes()
-		if err != nil {
-			return fmt.Errorf("failed to serialize slide XML: %w", err)
-		}
-		s.pres.files[s.path] = data
-		// fmt.Println("data===>", string(data))
-	}
+	s.dirty = true
 	return nil
 }
 
@@ -558,11 +876,3 @@ func (p *Placeholder) Debug() {
 		fmt.Printf("Presentation is nil: %v\n", p.slide.pres == nil)
 	}
 }
-
-// Relationship 定义了 PPTX 中的关系
-type Relationship struct {
-	Id         string
-	Type       string
-	Target     string
-	TargetMode string
-}
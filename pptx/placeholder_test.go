@@ -0,0 +1,219 @@
+package pptx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func TestPlaceholderTypeText(t *testing.T) {
+	tests := []struct {
+		typ  PlaceholderType
+		text string
+	}{
+		{PlaceholderTitle, "title"},
+		{PlaceholderSubTitle, "subTitle"},
+		{PlaceholderCtrTitle, "ctrTitle"},
+		{PlaceholderBody, "body"},
+		{PlaceholderImage, "pic"},
+		{PlaceholderChart, "chart"},
+		{PlaceholderTable, "tbl"},
+		{PlaceholderFooter, "ftr"},
+		{PlaceholderHeader, "hdr"},
+		{PlaceholderSlideNumber, "sldNum"},
+		{PlaceholderDate, "dt"},
+		{PlaceholderObject, "obj"},
+		{PlaceholderMedia, "media"},
+		{PlaceholderClipArt, "clipArt"},
+		{PlaceholderDiagram, "dgm"},
+		{PlaceholderSlideImage, "sldImg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			text, err := tt.typ.MarshalText()
+			if err != nil || string(text) != tt.text {
+				t.Errorf("MarshalText() = %q, %v, want %q", text, err, tt.text)
+			}
+			var typ PlaceholderType
+			if err := typ.UnmarshalText([]byte(tt.text)); err != nil || typ != tt.typ {
+				t.Errorf("UnmarshalText(%q) = %v, %v, want %v", tt.text, typ, err, tt.typ)
+			}
+			if got := parsePlaceholderType(tt.text); got != tt.typ {
+				t.Errorf("parsePlaceholderType(%q) = %v, want %v", tt.text, got, tt.typ)
+			}
+		})
+	}
+}
+
+func TestPlaceholderShapeNotMarshalled(t *testing.T) {
+	if text, err := PlaceholderShape.MarshalText(); err == nil {
+		t.Errorf("PlaceholderShape.MarshalText() = %q, want error", text)
+	}
+	var typ PlaceholderType
+	if err := typ.UnmarshalText([]byte("shape")); err == nil {
+		t.Errorf(`UnmarshalText("shape") = %v, want error`, typ)
+	}
+	if got := parsePlaceholderType("shape"); got != PlaceholderShape {
+		t.Errorf(`parsePlaceholderType("shape") = %v, want PlaceholderShape`, got)
+	}
+	if got := parsePlaceholderType(""); got != PlaceholderObject {
+		t.Errorf(`parsePlaceholderType("") = %v, want obj`, got)
+	}
+}
+
+func TestPlaceholderTypeAccepts(t *testing.T) {
+	tests := []struct {
+		typ  PlaceholderType
+		kind ContentKind
+		want bool
+	}{
+		{PlaceholderTitle, ContentText, true},
+		{PlaceholderTitle, ContentPicture, false},
+		{PlaceholderObject, ContentTable, true},
+		{PlaceholderObject, ContentPicture, true},
+		{PlaceholderImage, ContentPicture, true},
+		{PlaceholderImage, ContentText, false},
+		{PlaceholderTable, ContentTable, true},
+		{PlaceholderClipArt, ContentPicture, true},
+		{PlaceholderSlideImage, ContentText, false},
+		{PlaceholderShape, ContentChart, true},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.Accepts(tt.kind); got != tt.want {
+			t.Errorf("%v.Accepts(%v) = %v, want %v", tt.typ, tt.kind, got, tt.want)
+		}
+	}
+}
+
+// testPNG 返回内容不同的 PNG 数据，只有文件头是有效的
+func testPNG(content string) []byte {
+	return append([]byte("\x89PNG\r\n\x1a\n"), content...)
+}
+
+func TestSetImageReplacesRelationship(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("图片与标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	picture, err := slide.GetPlaceholder(PlaceholderImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := WithImageSource(MemorySource{"a.png": testPNG("a"), "b.png": testPNG("b")})
+	before := slide.rels.Len()
+
+	if err := picture.SetImage("a.png", source); err != nil {
+		t.Fatal(err)
+	}
+	first := slide.rels.FindByType(RelTypeImage)
+	if err := picture.SetImage("b.png", source); err != nil {
+		t.Fatal(err)
+	}
+	images := slide.rels.FindByType(RelTypeImage)
+	if len(images) != 1 || slide.rels.Len() != before+1 {
+		t.Fatalf("image relationships = %+v, want only the current image", images)
+	}
+	if len(first) != 1 || first[0].Target == images[0].Target {
+		t.Fatalf("second image reuses the first target %s", images[0].Target)
+	}
+
+	if err := pres.Save(t.TempDir() + "/out.pptx"); err != nil {
+		t.Fatal(err)
+	}
+	if pres.pkg.Part(resolvePartPath(slide.path, first[0].Target)) != nil {
+		t.Error("replaced image was not removed on save")
+	}
+	if pres.pkg.Part(resolvePartPath(slide.path, images[0].Target)) == nil {
+		t.Error("current image was removed on save")
+	}
+}
+
+func TestSetImageFailureKeepsRelationships(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("图片与标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	picture, err := slide.GetPlaceholder(PlaceholderImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := slide.rels.Len()
+
+	// 形状已经不在幻灯片中
+	picture.Shape.Parent().RemoveChild(picture.Shape)
+	if err := picture.SetImage("a.png", WithImageSource(MemorySource{"a.png": testPNG("a")})); err == nil {
+		t.Fatal("SetImage on a detached shape returned no error")
+	}
+	if n := slide.rels.Len(); n != before {
+		t.Errorf("relationships after failed SetImage = %d, want %d", n, before)
+	}
+}
+
+// paragraphStructure 将段落写成紧凑的结构：文本运行写成带引号的文本，a:pPr 写成 algn=对齐方式，
+// 公式写成 oMath 或 oMathPara(jc=对齐方式)
+func paragraphStructure(para *etree.Element) string {
+	var parts []string
+	for _, child := range para.ChildElements() {
+		switch child.FullTag() {
+		case "a:r":
+			parts = append(parts, fmt.Sprintf("%q", child.SelectElement("a:t").Text()))
+		case "a:pPr":
+			parts = append(parts, "algn="+child.SelectAttrValue("algn", ""))
+		case "a14:m":
+			math := child.ChildElements()[0]
+			if jc := math.FindElement("m:oMathParaPr/m:jc"); jc != nil {
+				parts = append(parts, math.Tag+"(jc="+jc.SelectAttrValue("m:val", "")+")")
+			} else {
+				parts = append(parts, math.Tag)
+			}
+		default:
+			parts = append(parts, child.FullTag())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestSetTextFormulaParagraphs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		jc   MathJustification
+		want []string
+	}{
+		{"inline stays in the paragraph", "a $x$ b", "", []string{`"a " oMath " b"`}},
+		{"display gets its own paragraph", "a $$x$$ b", "", []string{`"a "`, `algn=ctr oMathPara(jc=center)`, `" b"`}},
+		{"display only", "$$x$$", "", []string{`algn=ctr oMathPara(jc=center)`}},
+		{"mixed", "a $x$ and $$y$$ then $z$ end", "", []string{`"a " oMath " and "`, `algn=ctr oMathPara(jc=center)`, `" then " oMath " end"`}},
+		{"consecutive display", "$$x$$$$y$$", "", []string{`algn=ctr oMathPara(jc=center)`, `algn=ctr oMathPara(jc=center)`}},
+		{"line breaks around display", "line1\n$$x$$\nline2 $y$", "", []string{`"line1"`, `algn=ctr oMathPara(jc=center)`, `"line2 " oMath`}},
+		{"bracket delimiters", `\(x\) and \[y\] after`, "", []string{`oMath " and "`, `algn=ctr oMathPara(jc=center)`, `" after"`}},
+		{"left justification", "a $$x$$", MathJustifyLeft, []string{`"a "`, `algn=l oMathPara(jc=left)`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slide := newTestSlide(t, testShape(2, "Body 1", "body", 1, testRun("old")))
+			placeholder, err := slide.GetPlaceholder(PlaceholderBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options := []TextOption{WithLatex()}
+			if tt.jc != "" {
+				options = append(options, WithMathJustification(tt.jc))
+			}
+			if err := placeholder.SetText(tt.text, options...); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, para := range placeholder.Shape.FindElements("p:txBody/a:p") {
+				got = append(got, paragraphStructure(para))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("SetText(%q) paragraphs:\n%s\nwant:\n%s", tt.text, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
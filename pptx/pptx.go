@@ -2,11 +2,11 @@ package pptx
 
 import (
 	"archive/zip"
-	"bytes"
-	"errors"
+	"context"
 	"fmt"
-	"io"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -18,24 +18,40 @@ const (
 	NsRelationships  = "http://schemas.openxmlformats.org/package/2006/relationships"
 	NsPresentationML = "http://schemas.openxmlformats.org/presentationml/2006/main"
 	NsDrawingML      = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	NsOfficeRels     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships" // r:id、r:embed 等属性的命名空间
+
+	// 关系类型
+	RelTypeSlide       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	RelTypeSlideLayout = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout"
+	RelTypeSlideMaster = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster"
+	RelTypeImage       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	RelTypeHyperlink   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
 )
 
 // Presentation 表示一个PPTX文件
 type Presentation struct {
 	zipReader *zip.ReadCloser
-	files     map[string][]byte
+	pkg       *Package
 	slides    []*Slide
 	masters   []*Master
-	rels      map[string]string // 关系ID到目标的映射
+	rels      *Relationships  // presentation.xml 的关系
+	xml       *etree.Document // presentation.xml，只在打开时解析一次
+	dirty     bool            // presentation.xml 是否有尚未写入包中的修改
+
+	imageSource ImageSource // SetImage 默认使用的图片源
 }
 
+// presentationPath presentation.xml 的部件路径
+const presentationPath = "ppt/presentation.xml"
+
 // Layout 表示幻灯片布局
 type Layout struct {
 	name     string
 	xml      *etree.Document
 	path     string
 	relsPath string
-	rels     map[string]string
+	rels     *Relationships
+	dirty    bool // XML 是否有尚未写入包中的修改
 }
 
 // Master 表示母版
@@ -44,8 +60,9 @@ type Master struct {
 	xml      *etree.Document
 	path     string
 	relsPath string
-	rels     map[string]string
+	rels     *Relationships
 	layouts  []*Layout
+	dirty    bool // XML 是否有尚未写入包中的修改
 }
 
 // Open 打开PPTX文件
@@ -55,32 +72,21 @@ func Open(filename string) (*Presentation, error) {
 		return nil, fmt.Errorf("failed to open pptx file: %w", err)
 	}
 
-	pptx := &Presentation{
-		zipReader: reader,
-		files:     make(map[string][]byte),
-		rels:      make(map[string]string),
+	pkg, err := readPackage(&reader.Reader)
+	if err != nil {
+		reader.Close()
+		return nil, err
 	}
 
-	// 读取zip文件中的所有内容
-	for _, file := range reader.File {
-		rc, err := file.Open()
-		if err != nil {
-			return nil, fmt.Errorf("failed to open zip file entry %s: %w", file.Name, err)
-		}
-
-		content, err := io.ReadAll(rc)
-		if err != nil {
-			rc.Close()
-			return nil, fmt.Errorf("failed to read zip file entry %s: %w", file.Name, err)
-		}
-		rc.Close()
-		// fmt.Println("file.Name=====>", file.Name)
-		pptx.files[file.Name] = content
+	pptx := &Presentation{
+		zipReader: reader,
+		pkg:       pkg,
 	}
 
 	// 初始化presentation
 	err = pptx.initialize()
 	if err != nil {
+		reader.Close()
 		return nil, fmt.Errorf("failed to initialize presentation: %w", err)
 	}
 
@@ -90,33 +96,28 @@ func Open(filename string) (*Presentation, error) {
 // initialize 初始化presentation
 func (p *Presentation) initialize() error {
 	// 首先解析presentation.xml.rels
-	relsContent, ok := p.files["ppt/_rels/presentation.xml.rels"]
-	if !ok {
-		return errors.New("presentation.xml.rels not found")
+	relsContent, err := p.pkg.readPart(relsPathFor(presentationPath))
+	if err != nil {
+		return fmt.Errorf("failed to read presentation.xml.rels: %w", err)
 	}
 
-	relsDoc := etree.NewDocument()
-	if err := relsDoc.ReadFromBytes(relsContent); err != nil {
+	rels, err := parseRelationships(relsContent)
+	if err != nil {
 		return fmt.Errorf("failed to parse presentation.xml.rels: %w", err)
 	}
-
-	// 解析关系
-	for _, rel := range relsDoc.FindElements("//Relationship") {
-		id := rel.SelectAttr("Id").Value
-		target := rel.SelectAttr("Target").Value
-		p.rels[id] = target
-	}
+	p.rels = rels
 
 	// 解析presentation.xml
-	presContent, ok := p.files["ppt/presentation.xml"]
-	if !ok {
-		return errors.New("presentation.xml not found")
+	presContent, err := p.pkg.readPart(presentationPath)
+	if err != nil {
+		return fmt.Errorf("failed to read presentation.xml: %w", err)
 	}
 
 	presDoc := etree.NewDocument()
 	if err := presDoc.ReadFromBytes(presContent); err != nil {
 		return fmt.Errorf("failed to parse presentation.xml: %w", err)
 	}
+	p.xml = presDoc
 
 	// 初始化masters
 	if err := p.initMasters(presDoc); err != nil {
@@ -137,18 +138,21 @@ func (p *Presentation) initMasters(presDoc *etree.Document) error {
 
 	// 查找所有sldMasterId元素
 	for _, masterEl := range presDoc.FindElements("//p:sldMasterId") {
-		rId := masterEl.SelectAttr("r:id").Value
-		masterPath := "ppt/" + p.rels[rId]
+		masterPath, err := p.resolvePresentationRel(masterEl.SelectAttrValue("r:id", ""))
+		if err != nil {
+			return err
+		}
 
-		masterContent, ok := p.files[masterPath]
-		if !ok {
-			return fmt.Errorf("master file not found: %s", masterPath)
+		masterContent, err := p.pkg.readPart(masterPath)
+		if err != nil {
+			return fmt.Errorf("failed to read master file: %w", err)
 		}
 
 		master := &Master{
-			path:    masterPath,
-			rels:    make(map[string]string),
-			layouts: make([]*Layout, 0),
+			path:     masterPath,
+			relsPath: relsPathFor(masterPath),
+			rels:     NewRelationships(),
+			layouts:  make([]*Layout, 0),
 		}
 
 		// 解析master XML
@@ -159,30 +163,19 @@ func (p *Presentation) initMasters(presDoc *etree.Document) error {
 		master.xml = masterDoc
 
 		// 解析master关系文件
-		masterRelsPath := filepath.Join("ppt/slideMasters/_rels", filepath.Base(masterPath)+".rels")
-		if relsContent, ok := p.files[masterRelsPath]; ok {
-			master.relsPath = masterRelsPath
-			relsDoc := etree.NewDocument()
-			if err := relsDoc.ReadFromBytes(relsContent); err != nil {
-				return fmt.Errorf("failed to parse master rels: %w", err)
-			}
+		rels, err := p.readRelationships(master.relsPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse master rels: %w", err)
+		}
+		master.rels = rels
 
-			// 解析布局关系
-			for _, rel := range relsDoc.FindElements("//Relationship") {
-				id := rel.SelectAttr("Id").Value
-				target := rel.SelectAttr("Target").Value
-				master.rels[id] = target
-
-				// 如果是布局，则加载布局
-				if strings.Contains(target, "slideLayout") {
-					target = strings.Replace(target, "../", "", 1)
-					layout, err := p.loadLayout(filepath.Join("ppt", target))
-					if err != nil {
-						return fmt.Errorf("failed to load layout: %w", err)
-					}
-					master.layouts = append(master.layouts, layout)
-				}
+		// 加载母版中的布局
+		for _, rel := range rels.FindByType(RelTypeSlideLayout) {
+			layout, err := p.loadLayout(resolvePartPath(masterPath, rel.Target))
+			if err != nil {
+				return fmt.Errorf("failed to load layout: %w", err)
 			}
+			master.layouts = append(master.layouts, layout)
 		}
 
 		p.masters = append(p.masters, master)
@@ -193,14 +186,15 @@ func (p *Presentation) initMasters(presDoc *etree.Document) error {
 
 // loadLayout 加载布局文件
 func (p *Presentation) loadLayout(layoutPath string) (*Layout, error) {
-	layoutContent, ok := p.files[layoutPath]
-	if !ok {
-		return nil, fmt.Errorf("layout file not found: %s", layoutPath)
+	layoutContent, err := p.pkg.readPart(layoutPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layout file: %w", err)
 	}
 
 	layout := &Layout{
-		path: layoutPath,
-		rels: make(map[string]string),
+		path:     layoutPath,
+		relsPath: relsPathFor(layoutPath),
+		rels:     NewRelationships(),
 	}
 
 	// 解析layout XML
@@ -218,23 +212,11 @@ func (p *Presentation) loadLayout(layoutPath string) (*Layout, error) {
 	}
 
 	// 解析layout关系文件
-	layoutRelsPath := filepath.Join("ppt/slideLayouts/_rels", filepath.Base(layoutPath)+".rels")
-	// fmt.Println("layoutRelsPath1=====>", layoutRelsPath)
-	// fmt.Println(p.files[layoutRelsPath])
-	if relsContent, ok := p.files[layoutRelsPath]; ok {
-		layout.relsPath = layoutRelsPath
-		// fmt.Println("layoutRelsPath=====>", layoutRelsPath)
-		relsDoc := etree.NewDocument()
-		if err := relsDoc.ReadFromBytes(relsContent); err != nil {
-			return nil, fmt.Errorf("failed to parse layout rels: %w", err)
-		}
-
-		for _, rel := range relsDoc.FindElements("//Relationship") {
-			id := rel.SelectAttr("Id").Value
-			target := rel.SelectAttr("Target").Value
-			layout.rels[id] = target
-		}
+	rels, err := p.readRelationships(layout.relsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse layout rels: %w", err)
 	}
+	layout.rels = rels
 
 	return layout, nil
 }
@@ -245,17 +227,21 @@ func (p *Presentation) initSlides(presDoc *etree.Document) error {
 
 	// 查找所有sldId元素
 	for _, slideEl := range presDoc.FindElements("//p:sldId") {
-		rId := slideEl.SelectAttr("r:id").Value
-		slidePath := "ppt/" + p.rels[rId]
+		slidePath, err := p.resolvePresentationRel(slideEl.SelectAttrValue("r:id", ""))
+		if err != nil {
+			return err
+		}
 
-		slideContent, ok := p.files[slidePath]
-		if !ok {
-			return fmt.Errorf("slide file not found: %s", slidePath)
+		slideContent, err := p.pkg.readPart(slidePath)
+		if err != nil {
+			return fmt.Errorf("failed to read slide file: %w", err)
 		}
 
 		slide := &Slide{
-			path: slidePath,
-			rels: make(map[string]*Relationship),
+			path:     slidePath,
+			relsPath: relsPathFor(slidePath),
+			rels:     NewRelationships(),
+			pres:     p,
 		}
 
 		// 解析slide XML
@@ -266,25 +252,16 @@ func (p *Presentation) initSlides(presDoc *etree.Document) error {
 		slide.xml = slideDoc
 
 		// 解析slide关系文件
-		slideRelsPath := filepath.Join("ppt/_rels", filepath.Base(slidePath)+".rels")
-		if relsContent, ok := p.files[slideRelsPath]; ok {
-			slide.relsPath = slideRelsPath
-			relsDoc := etree.NewDocument()
-			if err := relsDoc.ReadFromBytes(relsContent); err != nil {
-				return fmt.Errorf("failed to parse slide rels: %w", err)
-			}
+		rels, err := p.readRelationships(slide.relsPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse slide rels: %w", err)
+		}
+		slide.rels = rels
 
-			for _, rel := range relsDoc.FindElements("//Relationship") {
-				id := rel.SelectAttr("Id").Value
-				target := rel.SelectAttr("Target").Value
-				rel := &Relationship{
-					Id:         id,
-					Target:     target,
-					Type:       rel.SelectAttr("Type").Value,
-					TargetMode: rel.SelectAttr("TargetMode").Value,
-				}
-				slide.rels[id] = rel
-			}
+		// 关联幻灯片使用的布局和母版
+		if rel := rels.FirstByType(RelTypeSlideLayout); rel != nil {
+			slide.layout = p.getLayoutByPath(resolvePartPath(slidePath, rel.Target))
+			slide.master = p.findMasterForLayout(slide.layout)
 		}
 
 		p.slides = append(p.slides, slide)
@@ -293,104 +270,220 @@ func (p *Presentation) initSlides(presDoc *etree.Document) error {
 	return nil
 }
 
+// readRelationships 读取并解析部件的关系文件，关系文件不存在时返回空的关系集合
+func (p *Presentation) readRelationships(relsPath string) (*Relationships, error) {
+	if p.pkg.Part(relsPath) == nil {
+		return NewRelationships(), nil
+	}
+	data, err := p.pkg.readPart(relsPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseRelationships(data)
+}
+
+// resolvePresentationRel 将 presentation.xml 中的关系ID解析为部件路径
+func (p *Presentation) resolvePresentationRel(rId string) (string, error) {
+	rel := p.rels.Get(rId)
+	if rel == nil {
+		return "", fmt.Errorf("presentation relationship not found: %s", rId)
+	}
+	return resolvePartPath(presentationPath, rel.Target), nil
+}
+
+// SaveOptions 定义保存时的选项
+type SaveOptions struct {
+	Strict bool // 保存前执行 Validate，存在问题时返回 *ValidationError 且不写入文件
+}
+
+// SaveOption 定义保存选项的函数类型
+type SaveOption func(*SaveOptions)
+
+// WithStrictValidation 保存前校验演示文稿，发现问题时拒绝保存
+func WithStrictValidation() SaveOption {
+	return func(o *SaveOptions) {
+		o.Strict = true
+	}
+}
+
 // Save 保存PPTX文件
-func (p *Presentation) Save(filename string) error {
-	// 更新所有已修改的XML文件到files map中
+// 保存前会删除无法从包级关系到达的部件，如删除幻灯片后不再被引用的图片
+func (p *Presentation) Save(filename string, options ...SaveOption) error {
+	return p.SaveContext(context.Background(), filename, options...)
+}
+
+// SaveContext 与 Save 相同，ctx 取消时停止写入并返回 ctx 的错误，原文件保持不变
+func (p *Presentation) SaveContext(ctx context.Context, filename string, options ...SaveOption) error {
+	opts := &SaveOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	// 更新所有已修改的XML文件到包中
 	if err := p.updateFiles(); err != nil {
 		return fmt.Errorf("failed to update files: %w", err)
 	}
+	p.pkg.CollectGarbage()
 
-	buf := new(bytes.Buffer)
-	writer := zip.NewWriter(buf)
-
-	// 写入所有文件
-	for name, content := range p.files {
-		w, err := writer.Create(name)
-		if err != nil {
-			return fmt.Errorf("failed to create zip entry %s: %w", name, err)
-		}
-
-		_, err = w.Write(content)
-		if err != nil {
-			return fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	if opts.Strict {
+		if issues := p.Validate(); len(issues) > 0 {
+			return &ValidationError{Issues: issues}
 		}
 	}
 
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to close zip writer: %w", err)
+	// 先写入同目录下的临时文件再替换，未修改的部件直接从原文件流式复制，
+	// 保存到原文件时也不会破坏仍在读取的内容
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".pptx-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
 	}
+	defer os.Remove(tmp.Name())
 
-	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+	if err := p.pkg.writeTo(ctx, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return nil
 }
 
-// updateFiles 更新所有已修改的XML文件到files map中
+// SetImageSource 设置 SetImage 默认使用的图片源，为 nil 时恢复为 NewImageSource(nil)
+func (p *Presentation) SetImageSource(source ImageSource) {
+	p.imageSource = source
+}
+
+// ImageSource 返回 SetImage 默认使用的图片源
+func (p *Presentation) ImageSource() ImageSource {
+	if p.imageSource == nil {
+		p.imageSource = NewImageSource(nil)
+	}
+	return p.imageSource
+}
+
+// Package 返回演示文稿底层的 OPC 包
+func (p *Presentation) Package() *Package {
+	return p.pkg
+}
+
+// updateFiles 将所有已修改的XML文档序列化到包中，未修改的部件保持原样
 func (p *Presentation) updateFiles() error {
+	pending, err := p.pendingParts()
+	if err != nil {
+		return err
+	}
+	for _, part := range pending {
+		p.pkg.SetPart(part.name, part.data)
+		part.written()
+	}
+	return nil
+}
+
+// pendingPart 表示内存中尚未写入包的部件内容
+type pendingPart struct {
+	name    string
+	data    []byte
+	written func() // 写入包之后清除修改标记
+}
+
+// pendingParts 序列化所有已修改的XML文档和关系集合，不修改包，也不清除修改标记
+func (p *Presentation) pendingParts() ([]pendingPart, error) {
+	var pending []pendingPart
+	addDocument := func(partPath string, doc *etree.Document, dirty *bool) error {
+		if !*dirty || doc == nil {
+			return nil
+		}
+		data, err := doc.WriteToBytes()
+		if err != nil {
+			return err
+		}
+		pending = append(pending, pendingPart{name: partPath, data: data, written: func() { *dirty = false }})
+		return nil
+	}
+	addRelationships := func(relsPath string, rels *Relationships) error {
+		data, err := p.serializeRelationships(relsPath, rels)
+		if err != nil || data == nil {
+			return err
+		}
+		pending = append(pending, pendingPart{name: relsPath, data: data, written: func() { rels.dirty = false }})
+		return nil
+	}
+
+	// 更新presentation.xml
+	if err := addDocument(presentationPath, p.xml, &p.dirty); err != nil {
+		return nil, fmt.Errorf("failed to serialize presentation.xml: %w", err)
+	}
+
 	// 更新slides
 	for _, slide := range p.slides {
-		if slide.xml != nil {
-			data, err := slide.xml.WriteToBytes()
-			if err != nil {
-				return fmt.Errorf("failed to serialize slide XML: %w", err)
-			}
-			p.files[slide.path] = data
+		if err := addDocument(slide.path, slide.xml, &slide.dirty); err != nil {
+			return nil, fmt.Errorf("failed to serialize slide XML: %w", err)
 		}
 
 		// 保存关系文件
-		if len(slide.rels) > 0 {
-			relsDoc := etree.NewDocument()
-			relationships := relsDoc.CreateElement("Relationships")
-			relationships.CreateAttr("xmlns", NsRelationships)
-
-			for id, r := range slide.rels {
-				rel := relationships.CreateElement("Relationship")
-				rel.CreateAttr("Id", id)
-				rel.CreateAttr("Type", r.Type)
-				rel.CreateAttr("Target", r.Target)
-				if r.TargetMode == "External" {
-					rel.CreateAttr("TargetMode", r.TargetMode)
-				}
-			}
-
-			data, err := relsDoc.WriteToBytes()
-			if err != nil {
-				return fmt.Errorf("failed to serialize slide relationships: %w", err)
-			}
-			p.files[slide.relsPath] = data
+		if err := addRelationships(slide.relsPath, slide.rels); err != nil {
+			return nil, fmt.Errorf("failed to serialize slide relationships: %w", err)
 		}
 	}
 
-	// 更新layouts
+	// 更新layouts和masters
 	for _, master := range p.masters {
 		for _, layout := range master.layouts {
-			if layout.xml != nil {
-				data, err := layout.xml.WriteToBytes()
-				if err != nil {
-					return fmt.Errorf("failed to serialize layout XML: %w", err)
-				}
-				p.files[layout.path] = data
+			if err := addDocument(layout.path, layout.xml, &layout.dirty); err != nil {
+				return nil, fmt.Errorf("failed to serialize layout XML: %w", err)
 			}
+			if err := addRelationships(layout.relsPath, layout.rels); err != nil {
+				return nil, fmt.Errorf("failed to serialize layout relationships: %w", err)
+			}
+		}
+		if err := addDocument(master.path, master.xml, &master.dirty); err != nil {
+			return nil, fmt.Errorf("failed to serialize master XML: %w", err)
+		}
+		if err := addRelationships(master.relsPath, master.rels); err != nil {
+			return nil, fmt.Errorf("failed to serialize master relationships: %w", err)
 		}
 	}
 
-	// 更新masters
-	for _, master := range p.masters {
-		if master.xml != nil {
-			data, err := master.xml.WriteToBytes()
-			if err != nil {
-				return fmt.Errorf("failed to serialize master XML: %w", err)
-			}
-			p.files[master.path] = data
-		}
+	// 更新presentation.xml.rels
+	if err := addRelationships(relsPathFor(presentationPath), p.rels); err != nil {
+		return nil, fmt.Errorf("failed to serialize presentation relationships: %w", err)
 	}
 
+	return pending, nil
+}
+
+// writeRelationships 将修改过的关系集合写入包中，关系为空且原来没有关系文件时不写入
+func (p *Presentation) writeRelationships(relsPath string, rels *Relationships) error {
+	data, err := p.serializeRelationships(relsPath, rels)
+	if err != nil || data == nil {
+		return err
+	}
+	p.pkg.SetPart(relsPath, data)
+	rels.dirty = false
 	return nil
 }
 
+// serializeRelationships 序列化修改过的关系集合，不需要写入时返回 nil
+func (p *Presentation) serializeRelationships(relsPath string, rels *Relationships) ([]byte, error) {
+	if rels == nil || !rels.dirty {
+		return nil, nil
+	}
+	if p.pkg.Part(relsPath) == nil && rels.Len() == 0 {
+		return nil, nil
+	}
+	return rels.toXML()
+}
+
 // Close 关闭PPTX文件
+// 未修改的部件在访问和保存时才从文件中读取，关闭后不能再调用 Save
 func (p *Presentation) Close() error {
 	if p.zipReader != nil {
 		return p.zipReader.Close()
@@ -425,12 +518,23 @@ func (p *Presentation) GetLayoutByName(name string) *Layout {
 	return nil
 }
 
+// layoutNames 返回所有布局的名称
+func (p *Presentation) layoutNames() []string {
+	var names []string
+	for _, master := range p.masters {
+		for _, layout := range master.layouts {
+			names = append(names, layout.name)
+		}
+	}
+	return names
+}
+
 // AddSlide 添加新的幻灯片
 func (p *Presentation) AddSlide(layoutName string) (*Slide, error) {
 	// 查找布局
 	layout := p.GetLayoutByName(layoutName)
 	if layout == nil {
-		return nil, fmt.Errorf("layout not found: %s", layoutName)
+		return nil, &LayoutError{Name: layoutName, Available: p.layoutNames()}
 	}
 
 	// 创建新的slide XML，从layout复制
@@ -446,87 +550,48 @@ func (p *Presentation) AddSlide(layoutName string) (*Slide, error) {
 		return nil, fmt.Errorf("invalid layout XML")
 	}
 
-	// 设置slide路径
-	slideIndex := len(p.slides) + 1
-	slidePath := fmt.Sprintf("ppt/slides/slide%d.xml", slideIndex)
-	slideRelsPath := fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", slideIndex)
-
-	// 首先复制布局的关系
-	layoutRels := make(map[string]*Relationship)
-	// fmt.Println("layoutRels=====>", layout.relsPath)
-	if layoutRelsData, exists := p.files[layout.relsPath]; exists {
-		// 解析布局关系文件
-		layoutRelsDoc := etree.NewDocument()
-		if err := layoutRelsDoc.ReadFromBytes(layoutRelsData); err != nil {
-			return nil, fmt.Errorf("failed to parse layout relationships: %w", err)
-		}
-
-		// 复制所有关系
-		for _, rel := range layoutRelsDoc.FindElements("//Relationship") {
-			layoutRels[rel.SelectAttrValue("Id", "")] = &Relationship{
-				Id:         rel.SelectAttrValue("Id", ""),
-				Type:       rel.SelectAttrValue("Type", ""),
-				Target:     rel.SelectAttrValue("Target", ""),
-				TargetMode: rel.SelectAttrValue("TargetMode", ""),
-			}
-		}
-	}
+	// 设置slide路径，编号不与已有部件冲突
+	slidePath := p.pkg.NextPartName("ppt/slides/slide%d.xml")
 
-	// 创建新的slide对象，使用复制的关系
+	// 创建新的slide对象
 	slide := &Slide{
 		xml:      slideDoc,
 		path:     slidePath,
-		relsPath: slideRelsPath,
-		rels:     layoutRels, // 使用复制的布局关系
+		relsPath: relsPathFor(slidePath),
+		rels:     NewRelationships(),
 		layout:   layout,
 		master:   p.findMasterForLayout(layout),
 		pres:     p,
 	}
 
-	// 生成新的rId
-	newRid := fmt.Sprintf("rId%d", len(slide.rels)+1)
+	// 复制布局的关系，保留原有ID以匹配从布局复制来的XML中的引用
+	for _, rel := range layout.rels.All() {
+		if rel.Type == RelTypeSlideMaster {
+			continue
+		}
+		target := rel.Target
+		if rel.TargetMode != "External" {
+			target = relativePartPath(slidePath, resolvePartPath(layout.path, rel.Target))
+		}
+		slide.rels.add(&Relationship{
+			Id:         rel.Id,
+			Type:       rel.Type,
+			Target:     target,
+			TargetMode: rel.TargetMode,
+		})
+	}
 
 	// 添加对layout的基础引用关系
-	layoutRel := &Relationship{
-		Id:     newRid,
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout",
-		Target: "../slideLayouts/" + filepath.Base(layout.path),
-	}
-	slide.rels[newRid] = layoutRel
+	slide.rels.Add(RelTypeSlideLayout, relativePartPath(slidePath, layout.path), "")
 
 	// 更新presentation.xml中的幻灯片列表
 	if err := p.updatePresentationSlideList(slide); err != nil {
 		return nil, fmt.Errorf("failed to update presentation slide list: %w", err)
 	}
 
-	// 序列化并保存slide XML
-	slideData, err := slideDoc.WriteToBytes()
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize slide XML: %w", err)
-	}
-	p.files[slidePath] = slideData
-
-	// 创建slide关系文件
-	relsDoc := etree.NewDocument()
-	relationships := relsDoc.CreateElement("Relationships")
-	relationships.CreateAttr("xmlns", NsRelationships)
-
-	// 添加所有关系（包括从布局复制的和layout引用）
-	for _, rel := range slide.rels {
-		relationship := relationships.CreateElement("Relationship")
-		relationship.CreateAttr("Id", rel.Id)
-		relationship.CreateAttr("Type", rel.Type)
-		relationship.CreateAttr("Target", rel.Target)
-		if rel.TargetMode != "" {
-			relationship.CreateAttr("TargetMode", rel.TargetMode)
-		}
-	}
-
-	relsData, err := relsDoc.WriteToBytes()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create slide relationships: %w", err)
-	}
-	p.files[slideRelsPath] = relsData
+	// 登记slide部件和内容类型，XML和关系文件在保存时写入
+	p.pkg.AddPart(slidePath, ContentTypeSlide, nil)
+	slide.dirty = true
 
 	// 添加到幻灯片集合
 	p.slides = append(p.slides, slide)
@@ -537,7 +602,7 @@ func (p *Presentation) AddSlide(layoutName string) (*Slide, error) {
 // DeleteSlide 删除指定索引的幻灯片
 func (p *Presentation) DeleteSlide(index int) error {
 	if index < 0 || index >= len(p.slides) {
-		return fmt.Errorf("invalid slide index: %d", index)
+		return &SlideIndexError{Index: index, Count: len(p.slides)}
 	}
 
 	// 获取要删除的幻灯片
@@ -548,9 +613,15 @@ func (p *Presentation) DeleteSlide(index int) error {
 		return fmt.Errorf("failed to remove slide reference: %w", err)
 	}
 
-	// 删除幻灯片文件和关系文件
-	delete(p.files, slide.path)
-	delete(p.files, slide.relsPath)
+	// 删除其它幻灯片中跳转到该幻灯片的链接
+	for _, other := range p.slides {
+		if other != slide {
+			other.removeLinksTo(slide)
+		}
+	}
+
+	// 删除幻灯片部件、关系文件和内容类型声明，仅被该幻灯片引用的部件在保存时清理
+	p.pkg.RemovePart(slide.path)
 
 	// 从幻灯片集合中删除
 	p.slides = append(p.slides[:index], p.slides[index+1:]...)
@@ -560,35 +631,20 @@ func (p *Presentation) DeleteSlide(index int) error {
 
 // removeSlideReference 从presentation.xml中删除幻灯片引用
 func (p *Presentation) removeSlideReference(slide *Slide) error {
-	presContent, ok := p.files["ppt/presentation.xml"]
-	if !ok {
-		return fmt.Errorf("presentation.xml not found")
-	}
-
-	presDoc := etree.NewDocument()
-	if err := presDoc.ReadFromBytes(presContent); err != nil {
-		return fmt.Errorf("failed to parse presentation.xml: %w", err)
-	}
-
-	// 查找并删除sldId元素
-	sldIdLst := presDoc.FindElement("//p:sldIdLst")
+	// 查找并删除sldId元素及对应的关系
+	sldIdLst := p.xml.FindElement("//p:sldIdLst")
 	if sldIdLst != nil {
 		for _, sldId := range sldIdLst.SelectElements("p:sldId") {
 			rId := sldId.SelectAttrValue("r:id", "")
-			if target, ok := p.rels[rId]; ok && target == strings.TrimPrefix(slide.path, "ppt/") {
+			if rel := p.rels.Get(rId); rel != nil && resolvePartPath(presentationPath, rel.Target) == slide.path {
 				sldIdLst.RemoveChild(sldId)
+				p.rels.Remove(rId)
+				p.dirty = true
 				break
 			}
 		}
 	}
 
-	// 更新presentation.xml
-	data, err := presDoc.WriteToBytes()
-	if err != nil {
-		return fmt.Errorf("failed to serialize presentation.xml: %w", err)
-	}
-	p.files["ppt/presentation.xml"] = data
-
 	return nil
 }
 
@@ -599,74 +655,83 @@ func (p *Presentation) GetSlides() []*Slide {
 // GetSlide 获取指定索引的幻灯片
 func (p *Presentation) GetSlide(index int) (*Slide, error) {
 	if index < 0 || index >= len(p.slides) {
-		return nil, fmt.Errorf("invalid slide index: %d", index)
+		return nil, &SlideIndexError{Index: index, Count: len(p.slides)}
 	}
 	return p.slides[index], nil
 }
 
-// findMasterForLayout 查找布局对应的母版
-func (p *Presentation) findMasterForLayout(layout *Layout) *Master {
+// getLayoutByPath 通过部件路径获取布局
+func (p *Presentation) getLayoutByPath(layoutPath string) *Layout {
 	for _, master := range p.masters {
-		for _, l := range master.layouts {
-			if l == layout {
-				return master
+		for _, layout := range master.layouts {
+			if layout.path == layoutPath {
+				return layout
 			}
 		}
 	}
 	return nil
 }
 
-// updatePresentationRels 更新presentation.xml.rels
-func (p *Presentation) updatePresentationRels(slide *Slide, rId string) error {
-	relsPath := "ppt/_rels/presentation.xml.rels"
-	relsContent, ok := p.files[relsPath]
-	if !ok {
-		return fmt.Errorf("presentation.xml.rels not found")
+// getSlideByPath 通过部件路径获取幻灯片
+func (p *Presentation) getSlideByPath(slidePath string) *Slide {
+	for _, slide := range p.slides {
+		if slide.path == slidePath {
+			return slide
+		}
 	}
+	return nil
+}
 
-	relsDoc := etree.NewDocument()
-	if err := relsDoc.ReadFromBytes(relsContent); err != nil {
-		return fmt.Errorf("failed to parse presentation.xml.rels: %w", err)
+// relativePartPath 计算从源部件指向目标部件的相对路径，用作关系的 Target
+func relativePartPath(sourcePath, targetPath string) string {
+	var from []string
+	if dir := path.Dir(sourcePath); dir != "." {
+		from = strings.Split(dir, "/")
 	}
+	to := strings.Split(targetPath, "/")
 
-	// 添加新的关系
-	relationships := relsDoc.FindElement("//Relationships")
-	if relationships == nil {
-		return fmt.Errorf("relationships element not found")
+	common := 0
+	for common < len(from) && common < len(to)-1 && from[common] == to[common] {
+		common++
 	}
 
-	rel := relationships.CreateElement("Relationship")
-	rel.CreateAttr("Id", rId)
-	rel.CreateAttr("Type", "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide")
-	rel.CreateAttr("Target", strings.TrimPrefix(slide.path, "ppt/"))
+	parts := make([]string, 0, len(from)-common+len(to)-common)
+	for i := common; i < len(from); i++ {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, to[common:]...)
+	return strings.Join(parts, "/")
+}
 
-	// 更新关系文件
-	data, err := relsDoc.WriteToBytes()
-	if err != nil {
-		return fmt.Errorf("failed to serialize presentation.xml.rels: %w", err)
+// resolvePartPath 将关系中的相对目标解析为包内的部件路径，目标中的百分号编码（如 my%20pic.png）会被解码
+func resolvePartPath(sourcePath, target string) string {
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
 	}
-	p.files[relsPath] = data
+	return path.Join(path.Dir(sourcePath), target)
+}
 
+// findMasterForLayout 查找布局对应的母版
+func (p *Presentation) findMasterForLayout(layout *Layout) *Master {
+	for _, master := range p.masters {
+		for _, l := range master.layouts {
+			if l == layout {
+				return master
+			}
+		}
+	}
 	return nil
 }
 
 // updatePresentationSlideList 更新presentation.xml中的幻灯片列表
 func (p *Presentation) updatePresentationSlideList(slide *Slide) error {
-	// 获取presentation.xml
-	presContent, ok := p.files["ppt/presentation.xml"]
-	if !ok {
-		return fmt.Errorf("presentation.xml not found")
-	}
-
-	presDoc := etree.NewDocument()
-	if err := presDoc.ReadFromBytes(presContent); err != nil {
-		return fmt.Errorf("failed to parse presentation.xml: %w", err)
-	}
-
 	// 查找或创建sldIdLst元素
-	sldIdLst := presDoc.FindElement("//p:sldIdLst")
+	sldIdLst := p.xml.FindElement("//p:sldIdLst")
 	if sldIdLst == nil {
-		presentation := presDoc.FindElement("//p:presentation")
+		presentation := p.xml.FindElement("//p:presentation")
 		if presentation == nil {
 			return fmt.Errorf("presentation element not found")
 		}
@@ -683,44 +748,15 @@ func (p *Presentation) updatePresentationSlideList(slide *Slide) error {
 		}
 	}
 	newId := maxId + 1
-	// 从presentation.xml.rels获取最大的rId
-	relsPath := "ppt/_rels/presentation.xml.rels"
-	relsContent, ok := p.files[relsPath]
-	if !ok {
-		return fmt.Errorf("presentation.xml.rels not found")
-	}
 
-	relsDoc := etree.NewDocument()
-	if err := relsDoc.ReadFromBytes(relsContent); err != nil {
-		return fmt.Errorf("failed to parse presentation.xml.rels: %w", err)
-	}
+	// 在presentation.xml.rels中添加幻灯片关系
+	newRid := p.rels.Add(RelTypeSlide, relativePartPath(presentationPath, slide.path), "")
 
-	// 找到最大的rId
-	maxRid := 0
-	for _, rel := range relsDoc.FindElements("//Relationship") {
-		if rid := rel.SelectAttr("Id"); rid != nil {
-			if num := getRidNumber(rid.Value); num > maxRid {
-				maxRid = num
-			}
-		}
-	}
-	newRid := fmt.Sprintf("rId%d", maxRid+1)
 	// 创建新的sldId元素
 	sldId := sldIdLst.CreateElement("p:sldId")
 	sldId.CreateAttr("id", fmt.Sprintf("%d", newId))
 	sldId.CreateAttr("r:id", newRid)
-
-	// 更新关系文件
-	if err := p.updatePresentationRels(slide, newRid); err != nil {
-		return fmt.Errorf("failed to update presentation rels: %w", err)
-	}
-
-	// 更新presentation.xml
-	data, err := presDoc.WriteToBytes()
-	if err != nil {
-		return fmt.Errorf("failed to serialize presentation.xml: %w", err)
-	}
-	p.files["ppt/presentation.xml"] = data
+	p.dirty = true
 
 	return nil
 }
@@ -0,0 +1,161 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTestPresentation 打开示例模板并添加一张带标题的幻灯片，保存到 dir 中的 name
+func buildTestPresentation(t *testing.T, dir, name string) []byte {
+	t.Helper()
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("图片与标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := title.SetText("Hello $x^2$", WithLatex()); err != nil {
+		t.Fatal(err)
+	}
+	picture, err := slide.GetPlaceholder(PlaceholderImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := picture.SetImage("a.png", WithImageSource(MemorySource{"a.png": testPNG("a")})); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := pres.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSaveDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	first := buildTestPresentation(t, dir, "first.pptx")
+	second := buildTestPresentation(t, dir, "second.pptx")
+	if !bytes.Equal(first, second) {
+		t.Fatal("saving the same presentation twice produced different bytes")
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range reader.File {
+		if !file.Modified.Equal(zipModTime) {
+			t.Errorf("%s modified at %v, want %v", file.Name, file.Modified, zipModTime)
+		}
+	}
+
+	// 未修改的演示文稿再次保存时内容不变
+	pres, err := Open(filepath.Join(dir, "first.pptx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pres.Close()
+	if err := pres.Save(filepath.Join(dir, "again.pptx")); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(filepath.Join(dir, "again.pptx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, again) {
+		t.Error("saving an unmodified presentation changed its bytes")
+	}
+}
+
+// dirtyParts 列出仍有未写入修改的 XML 文档和关系集合
+func dirtyParts(pres *Presentation) []string {
+	var dirty []string
+	check := func(name string, docDirty bool, rels *Relationships) {
+		if docDirty {
+			dirty = append(dirty, name)
+		}
+		if rels != nil && rels.dirty {
+			dirty = append(dirty, relsPathFor(name))
+		}
+	}
+	check(presentationPath, pres.dirty, pres.rels)
+	for _, slide := range pres.slides {
+		check(slide.path, slide.dirty, slide.rels)
+	}
+	for _, master := range pres.masters {
+		check(master.path, master.dirty, master.rels)
+		for _, layout := range master.layouts {
+			check(layout.path, layout.dirty, layout.rels)
+		}
+	}
+	return dirty
+}
+
+func TestSaveWritesOnlyDirtyParts(t *testing.T) {
+	pres := openTemplate(t)
+	existing := pres.slides[0]
+	untouched := []string{existing.path, existing.relsPath}
+	for _, master := range pres.masters {
+		untouched = append(untouched, master.path, master.relsPath)
+		for _, layout := range master.layouts {
+			untouched = append(untouched, layout.path, layout.relsPath)
+		}
+	}
+	original := make(map[string][]byte)
+	for _, name := range untouched {
+		data, err := pres.pkg.readPart(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original[name] = data
+	}
+
+	slide, err := pres.AddSlide("仅标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := title.SetText("Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(dirtyParts(pres)) == 0 {
+		t.Fatal("adding a slide left no modification flags")
+	}
+
+	if err := pres.Save(filepath.Join(t.TempDir(), "saved.pptx")); err != nil {
+		t.Fatal(err)
+	}
+	if dirty := dirtyParts(pres); len(dirty) != 0 {
+		t.Errorf("modification flags still set after Save: %v", dirty)
+	}
+	for _, name := range untouched {
+		part := pres.pkg.Part(name)
+		if part == nil {
+			t.Errorf("%s is missing after Save", name)
+			continue
+		}
+		if part.modified {
+			t.Errorf("untouched %s was re-serialised", name)
+		}
+		if data, err := pres.pkg.readPart(name); err != nil || !bytes.Equal(data, original[name]) {
+			t.Errorf("untouched %s changed after Save (err %v)", name, err)
+		}
+	}
+	if pending, err := pres.pendingParts(); err != nil || len(pending) != 0 {
+		t.Errorf("pendingParts() after Save = %d parts, %v, want none", len(pending), err)
+	}
+}
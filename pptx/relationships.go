@@ -0,0 +1,222 @@
+package pptx
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/beevik/etree"
+)
+
+// Relationship 定义了 PPTX 中的关系
+type Relationship struct {
+	Id         string
+	Type       string
+	Target     string
+	TargetMode string
+}
+
+// Relationships 表示一个部件（幻灯片、布局、母版、演示文稿等）的关系集合
+// 关系按添加顺序保存，新关系的ID总是大于已有的最大ID，不会与已有关系冲突
+type Relationships struct {
+	items []*Relationship
+	maxId int
+	dirty bool // 解析后是否被修改过，未修改的关系文件保存时原样保留
+}
+
+// NewRelationships 创建空的关系集合
+func NewRelationships() *Relationships {
+	return &Relationships{}
+}
+
+// parseRelationships 解析 .rels 文件内容
+func parseRelationships(data []byte) (*Relationships, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+
+	rels := NewRelationships()
+	for _, rel := range doc.FindElements("//Relationship") {
+		rels.add(&Relationship{
+			Id:         rel.SelectAttrValue("Id", ""),
+			Type:       rel.SelectAttrValue("Type", ""),
+			Target:     rel.SelectAttrValue("Target", ""),
+			TargetMode: rel.SelectAttrValue("TargetMode", ""),
+		})
+	}
+	rels.dirty = false
+	return rels, nil
+}
+
+// Add 添加关系并返回分配的关系ID
+// targetMode 为空表示内部关系，外部链接使用 "External"
+func (r *Relationships) Add(relType, target, targetMode string) string {
+	id := fmt.Sprintf("rId%d", r.maxId+1)
+	r.add(&Relationship{
+		Id:         id,
+		Type:       relType,
+		Target:     target,
+		TargetMode: targetMode,
+	})
+	return id
+}
+
+// add 按原有ID添加关系，同ID的关系会被替换
+func (r *Relationships) add(rel *Relationship) {
+	if num := getRidNumber(rel.Id); num > r.maxId {
+		r.maxId = num
+	}
+	r.dirty = true
+	for i, existing := range r.items {
+		if existing.Id == rel.Id {
+			r.items[i] = rel
+			return
+		}
+	}
+	r.items = append(r.items, rel)
+}
+
+// Get 通过ID获取关系，不存在时返回 nil
+func (r *Relationships) Get(id string) *Relationship {
+	for _, rel := range r.items {
+		if rel.Id == id {
+			return rel
+		}
+	}
+	return nil
+}
+
+// FindByType 获取指定类型的所有关系
+func (r *Relationships) FindByType(relType string) []*Relationship {
+	var result []*Relationship
+	for _, rel := range r.items {
+		if rel.Type == relType {
+			result = append(result, rel)
+		}
+	}
+	return result
+}
+
+// FirstByType 获取指定类型的第一个关系，不存在时返回 nil
+func (r *Relationships) FirstByType(relType string) *Relationship {
+	for _, rel := range r.items {
+		if rel.Type == relType {
+			return rel
+		}
+	}
+	return nil
+}
+
+// FindByTarget 查找指向 target 的内部关系，不存在时返回 nil
+func (r *Relationships) FindByTarget(relType, target string) *Relationship {
+	for _, rel := range r.items {
+		if rel.Type == relType && rel.Target == target && rel.TargetMode != "External" {
+			return rel
+		}
+	}
+	return nil
+}
+
+// Remove 删除指定ID的关系，返回是否删除成功
+// 已删除的ID不会被重新分配，避免仍引用旧ID的XML指向新的目标
+func (r *Relationships) Remove(id string) bool {
+	for i, rel := range r.items {
+		if rel.Id == id {
+			r.items = append(r.items[:i], r.items[i+1:]...)
+			r.dirty = true
+			return true
+		}
+	}
+	return false
+}
+
+// checkpoint 记录关系集合当前的状态，返回的函数撤销此后通过 Add 添加的关系
+func (r *Relationships) checkpoint() (rollback func()) {
+	n, maxId, dirty := len(r.items), r.maxId, r.dirty
+	return func() {
+		r.items = r.items[:n]
+		r.maxId = maxId
+		r.dirty = dirty
+	}
+}
+
+// All 返回所有关系
+func (r *Relationships) All() []*Relationship {
+	return append([]*Relationship(nil), r.items...)
+}
+
+// Len 返回关系数量
+func (r *Relationships) Len() int {
+	return len(r.items)
+}
+
+// toXML 序列化为 .rels 文件内容，关系按ID的数字顺序输出
+func (r *Relationships) toXML() ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8" standalone="yes"`)
+	relationships := doc.CreateElement("Relationships")
+	relationships.CreateAttr("xmlns", NsRelationships)
+
+	for _, r := range r.sorted() {
+		rel := relationships.CreateElement("Relationship")
+		rel.CreateAttr("Id", r.Id)
+		rel.CreateAttr("Type", r.Type)
+		rel.CreateAttr("Target", r.Target)
+		if r.TargetMode != "" {
+			rel.CreateAttr("TargetMode", r.TargetMode)
+		}
+	}
+	return doc.WriteToBytes()
+}
+
+// sorted 返回按ID数字顺序排列的关系，数字相同时按ID字符串排序
+func (r *Relationships) sorted() []*Relationship {
+	items := r.All()
+	sort.SliceStable(items, func(i, j int) bool {
+		ni, nj := getRidNumber(items[i].Id), getRidNumber(items[j].Id)
+		if ni != nj {
+			return ni < nj
+		}
+		return items[i].Id < items[j].Id
+	})
+	return items
+}
+
+// isRelationshipAttr 判断属性是否为关系引用（r:id、r:embed、r:link 等），按命名空间 URI 识别前缀
+// 没有声明命名空间的 r: 前缀也视为关系引用
+func isRelationshipAttr(attr *etree.Attr) bool {
+	if attr.Space == "" || attr.Space == "xmlns" {
+		return false
+	}
+	uri := attr.NamespaceURI()
+	return uri == NsOfficeRels || (uri == "" && attr.Space == "r")
+}
+
+// relationshipAttrValue 返回元素上名为 key 的关系引用属性的值，不存在时返回空字符串
+func relationshipAttrValue(el *etree.Element, key string) string {
+	for i := range el.Attr {
+		if el.Attr[i].Key == key && isRelationshipAttr(&el.Attr[i]) {
+			return el.Attr[i].Value
+		}
+	}
+	return ""
+}
+
+// relationshipRefs 返回元素及其子元素中引用的所有关系ID，不包含空值
+func relationshipRefs(el *etree.Element) []string {
+	var refs []string
+	for _, e := range append([]*etree.Element{el}, el.FindElements(".//*")...) {
+		for i := range e.Attr {
+			if e.Attr[i].Value != "" && isRelationshipAttr(&e.Attr[i]) {
+				refs = append(refs, e.Attr[i].Value)
+			}
+		}
+	}
+	return refs
+}
+
+// relsPathFor 获取部件对应的关系文件路径，如 ppt/slides/slide1.xml -> ppt/slides/_rels/slide1.xml.rels
+func relsPathFor(partPath string) string {
+	return path.Join(path.Dir(partPath), "_rels", path.Base(partPath)+".rels")
+}
@@ -0,0 +1,66 @@
+package pptx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRelationshipsAdd(t *testing.T) {
+	tests := []struct {
+		name   string
+		rels   string
+		remove []string
+		want   []string
+	}{
+		{"empty", ``, nil, []string{"rId1", "rId2"}},
+		{"after highest id", `<Relationship Id="rId1"/><Relationship Id="rId7"/>`, nil, []string{"rId8", "rId9"}},
+		{"non-numeric ids", `<Relationship Id="rIdImage"/><Relationship Id="rId2"/>`, nil, []string{"rId3", "rId4"}},
+		{"removed ids not reused", `<Relationship Id="rId1"/><Relationship Id="rId2"/>`, []string{"rId2"}, []string{"rId3", "rId4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rels, err := parseRelationships([]byte(`<Relationships xmlns="` + NsRelationships + `">` + tt.rels + `</Relationships>`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range tt.remove {
+				rels.Remove(id)
+			}
+			for _, want := range tt.want {
+				if got := rels.Add(RelTypeImage, "../media/image1.png", ""); got != want {
+					t.Errorf("Add() = %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRelationshipsToXMLSorted(t *testing.T) {
+	rels, err := parseRelationships([]byte(`<Relationships xmlns="` + NsRelationships + `">` +
+		`<Relationship Id="rId10" Type="t" Target="c"/><Relationship Id="rId2" Type="t" Target="a"/></Relationships>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rels.dirty {
+		t.Error("parsed relationships are marked dirty")
+	}
+	rels.Add(RelTypeHyperlink, "https://example.com", "External")
+
+	data, err := rels.toXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml := string(data)
+	ids := []string{`Id="rId2"`, `Id="rId10"`, `Id="rId11"`}
+	last := -1
+	for _, id := range ids {
+		i := strings.Index(xml, id)
+		if i < 0 || i < last {
+			t.Fatalf("relationships not in numeric order: %s", xml)
+		}
+		last = i
+	}
+	if !strings.Contains(xml, `TargetMode="External"`) {
+		t.Errorf("external target mode missing: %s", xml)
+	}
+}
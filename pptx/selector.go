@@ -0,0 +1,74 @@
+package pptx
+
+import (
+	"regexp"
+)
+
+// Selector 定义占位符选择器，返回 true 表示匹配
+type Selector func(p *Placeholder) bool
+
+// ByType 按占位符类型匹配
+func ByType(t PlaceholderType) Selector {
+	return func(p *Placeholder) bool {
+		return p.Type == t
+	}
+}
+
+// ByIdx 按占位符索引（p:ph 的 idx 属性）匹配
+func ByIdx(idx int) Selector {
+	return func(p *Placeholder) bool {
+		return p.Index == idx
+	}
+}
+
+// ByName 按形状名称（p:cNvPr 的 name 属性）匹配
+func ByName(name string) Selector {
+	return func(p *Placeholder) bool {
+		return p.Name == name
+	}
+}
+
+// ByText 按占位符的文本内容完全匹配
+func ByText(text string) Selector {
+	return func(p *Placeholder) bool {
+		return p.Text() == text
+	}
+}
+
+// byRunText 按所有文本运行直接拼接的结果完全匹配，GetPlaceholder 的 string 参数使用这种比较方式
+func byRunText(text string) Selector {
+	return func(p *Placeholder) bool {
+		return p.joinedRunText() == text
+	}
+}
+
+// ByTextPattern 按正则表达式匹配占位符的文本内容
+func ByTextPattern(re *regexp.Regexp) Selector {
+	return func(p *Placeholder) bool {
+		return re.MatchString(p.Text())
+	}
+}
+
+// And 所有选择器都匹配时才匹配
+func And(selectors ...Selector) Selector {
+	return func(p *Placeholder) bool {
+		for _, sel := range selectors {
+			if !sel(p) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or 任意一个选择器匹配即匹配
+func Or(selectors ...Selector) Selector {
+	return func(p *Placeholder) bool {
+		for _, sel := range selectors {
+			if sel(p) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -3,6 +3,7 @@ package pptx
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/beevik/etree"
 )
@@ -15,7 +16,8 @@ type Slide struct {
 	layout   *Layout
 	master   *Master
 	pres     *Presentation
-	rels     map[string]*Relationship
+	rels     *Relationships
+	dirty    bool // XML 是否有尚未写入包中的修改
 }
 
 // SlideSize 表示幻灯片大小
@@ -84,180 +86,169 @@ func atoi(s string) int {
 }
 
 // GetPlaceholder 通过类型、名称、索引或文本内容获取占位符
+// 参数可以是 PlaceholderType、int（索引）、string（名称或文本）或 Selector，
+// 多个参数之间为“或”的关系，返回第一个匹配的占位符
+// 为了兼容以前的行为，string 参数比较的文本是所有 a:r 文本直接拼接的结果，不包含段落之间的换行和公式，
+// 与 ByText 使用的 Placeholder.Text 不同；新代码建议直接使用 FindPlaceholders 和 Selector
 func (s *Slide) GetPlaceholder(params ...interface{}) (*Placeholder, error) {
 	if len(params) == 0 {
 		return nil, fmt.Errorf("no parameters provided")
 	}
-	// fmt.Println(s.xml.WriteToString())
-	// 查找 spTree，不使用命名空间前缀
-	spTree := s.xml.FindElement("//sld/cSld/spTree")
-	if spTree == nil {
-		// 尝试带命名空间的路径
-		spTree = s.xml.FindElement("//p:sld/p:cSld/p:spTree")
-		if spTree == nil {
-			return nil, fmt.Errorf("shape tree not found in slide")
+
+	selectors := make([]Selector, 0, len(params))
+	for _, param := range params {
+		sel, err := selectorFromParam(param)
+		if err != nil {
+			return nil, err
 		}
+		selectors = append(selectors, sel)
 	}
 
-	// 查找所有 sp 元素，不使用命名空间前缀
-	for _, sp := range spTree.SelectElements("sp") {
-		nvSpPr := sp.FindElement("nvSpPr")
-		if nvSpPr == nil {
-			continue
+	placeholders, err := s.FindPlaceholders(Or(selectors...))
+	if err != nil {
+		return nil, err
+	}
+	if len(placeholders) == 0 {
+		return nil, &PlaceholderError{
+			SlideIndex: s.Index(),
+			SlidePath:  s.path,
+			Selector:   describeParams(params),
 		}
+	}
+	return placeholders[0], nil
+}
 
-		// 获取占位符信息，尝试不带命名空间的路径
-		ph := nvSpPr.FindElement("nvPr/ph")
-		if ph == nil {
-			// 尝试带命名空间的路径
-			ph = nvSpPr.FindElement("p:nvPr/p:ph")
-			if ph == nil {
-				continue
-			}
+// describeParams 生成 GetPlaceholder 参数的描述，用于错误信息
+func describeParams(params []interface{}) string {
+	descriptions := make([]string, 0, len(params))
+	for _, param := range params {
+		switch v := param.(type) {
+		case Selector, func(*Placeholder) bool:
+			descriptions = append(descriptions, "selector")
+		case PlaceholderType:
+			descriptions = append(descriptions, "type="+v.String())
+		case string:
+			descriptions = append(descriptions, fmt.Sprintf("name or text=%q", v))
+		case int:
+			descriptions = append(descriptions, fmt.Sprintf("idx=%d", v))
+		default:
+			descriptions = append(descriptions, fmt.Sprintf("%+v", v))
 		}
+	}
+	return strings.Join(descriptions, " or ")
+}
 
-		placeholder := &Placeholder{
-			Shape: sp,
-			slide: s,
+// Index 返回幻灯片在演示文稿中的索引，幻灯片已被删除时返回 -1
+func (s *Slide) Index() int {
+	if s.pres != nil {
+		for i, slide := range s.pres.slides {
+			if slide == s {
+				return i
+			}
 		}
+	}
+	return -1
+}
 
-		// 获取占位符类型
-		if typeAttr := ph.SelectAttr("type"); typeAttr != nil {
-			placeholder.Type = parsePlaceholderType(typeAttr.Value)
-		}
+// selectorFromParam 将 GetPlaceholder 的参数转换为选择器
+func selectorFromParam(param interface{}) (Selector, error) {
+	switch v := param.(type) {
+	case Selector:
+		return v, nil
+	case func(*Placeholder) bool:
+		return v, nil
+	case PlaceholderType:
+		return ByType(v), nil
+	case string:
+		// 匹配名称或文本内容
+		return Or(ByName(v), byRunText(v)), nil
+	case int:
+		return ByIdx(v), nil
+	case struct {
+		Type PlaceholderType
+		Text string
+	}:
+		// 同时匹配类型和文本
+		return And(ByType(v.Type), byRunText(v.Text)), nil
+	case struct {
+		Name string
+		Text string
+	}:
+		// 同时匹配名称和文本
+		return And(ByName(v.Name), byRunText(v.Text)), nil
+	default:
+		return nil, fmt.Errorf("unsupported placeholder parameter type: %T", param)
+	}
+}
 
-		// 获取占位符名称
-		nvPr := nvSpPr.FindElement("nvPr")
-		if nvPr == nil {
-			nvPr = nvSpPr.FindElement("p:nvPr")
-		}
-		if nvPr != nil {
-			if userDrawn := nvPr.SelectAttr("userDrawn"); userDrawn != nil && userDrawn.Value == "1" {
-				continue // 跳过用户绘制的形状
-			}
-			if name := nvPr.SelectAttrValue("name", ""); name != "" {
-				placeholder.Name = name
-			}
-		}
+// FindPlaceholders 返回幻灯片中所有与选择器匹配的占位符，按形状树中的顺序排列
+// sel 为 nil 时返回全部占位符
+func (s *Slide) FindPlaceholders(sel Selector) ([]*Placeholder, error) {
+	spTree := s.xml.FindElement("//p:cSld/p:spTree")
+	if spTree == nil {
+		return nil, fmt.Errorf("shape tree not found in slide")
+	}
 
-		// 获取占位符索引
-		if idxAttr := ph.SelectAttr("idx"); idxAttr != nil {
-			placeholder.Index, _ = strconv.Atoi(idxAttr.Value)
+	var placeholders []*Placeholder
+	for _, shape := range spTree.ChildElements() {
+		placeholder := newPlaceholder(s, shape)
+		if placeholder == nil {
+			continue
 		}
-
-		// 获取占位符文本内容
-		var placeholderText string
-		if txBody := sp.FindElement("txBody"); txBody != nil {
-			for _, p := range txBody.SelectElements("a:p") {
-				for _, r := range p.SelectElements("a:r") {
-					if t := r.SelectElement("a:t"); t != nil {
-						placeholderText += t.Text()
-					}
-				}
-			}
+		if sel == nil || sel(placeholder) {
+			placeholders = append(placeholders, placeholder)
 		}
+	}
+	return placeholders, nil
+}
 
-		// 根据参数匹配占位符
-		for _, param := range params {
-			switch v := param.(type) {
-			case PlaceholderType:
-				if placeholder.Type == v {
-					return placeholder, nil
-				}
-			case string:
-				// 匹配名称或文本内容
-				if placeholder.Name == v || placeholderText == v {
-					return placeholder, nil
-				}
-			case int:
-				if placeholder.Index == v {
-					return placeholder, nil
-				}
-			case struct {
-				Type PlaceholderType
-				Text string
-			}:
-				// 同时匹配类型和文本
-				if placeholder.Type == v.Type && placeholderText == v.Text {
-					return placeholder, nil
-				}
-			case struct {
-				Name string
-				Text string
-			}:
-				// 同时匹配名称和文本
-				if placeholder.Name == v.Name && placeholderText == v.Text {
-					return placeholder, nil
-				}
-			}
+// Text 获取幻灯片中所有形状（包括组合中的形状和表格单元格）的文本，按形状在幻灯片中的顺序以换行符分隔
+// 公式转换为 $…$ 或 $$…$$ 形式的 LaTeX，与 Placeholder.Text 相同
+func (s *Slide) Text() string {
+	spTree := s.xml.FindElement("//p:cSld/p:spTree")
+	if spTree == nil {
+		return ""
+	}
+	var texts []string
+	for _, txBody := range spTree.FindElements(".//txBody") {
+		if text := textBodyText(txBody); text != "" {
+			texts = append(texts, text)
 		}
 	}
-
-	return nil, fmt.Errorf("placeholder not found")
+	return strings.Join(texts, "\n")
 }
 
-// copyLayoutRelationships 复制布局中的关系到新幻灯片
-func (s *Slide) copyLayoutRelationships(layout *Layout) error {
-	// 读取布局的关系文件
-	layoutRelsData, exists := s.pres.files[layout.relsPath]
-	if !exists {
-		return nil // 如果布局没有关系文件，直接返回
+// newPlaceholder 从形状元素创建占位符，形状不是占位符时返回 nil
+func newPlaceholder(s *Slide, shape *etree.Element) *Placeholder {
+	ph := placeholderProps(shape)
+	if ph == nil {
+		return nil
 	}
 
-	// 解析布局关系文件
-	doc := etree.NewDocument()
-	if err := doc.ReadFromBytes(layoutRelsData); err != nil {
-		return fmt.Errorf("failed to parse layout relationships: %w", err)
+	// 跳过用户绘制的形状
+	if nvPr := ph.Parent(); nvPr != nil && nvPr.SelectAttrValue("userDrawn", "") == "1" {
+		return nil
 	}
 
-	// 遍历所有关系
-	for _, rel := range doc.FindElements("//Relationship") {
-		rId := rel.SelectAttrValue("Id", "")
-		target := rel.SelectAttrValue("Target", "")
-		relType := rel.SelectAttrValue("Type", "")
-		targetMode := rel.SelectAttrValue("TargetMode", "")
-
-		// 创建新的关系ID
-		newRId := fmt.Sprintf("rId%d", len(s.rels)+1)
-
-		// 添加到幻灯片的关系中
-		s.rels[newRId] = &Relationship{
-			Id:         newRId,
-			Type:       relType,
-			Target:     target,
-			TargetMode: targetMode,
-		}
-
-		// 更新幻灯片XML中的rId引用
-		s.updateReferenceIds(rId, newRId)
+	placeholder := &Placeholder{
+		Shape: shape,
+		slide: s,
 	}
 
-	return nil
-}
+	// 获取占位符类型
+	placeholder.Type = parsePlaceholderType(ph.SelectAttrValue("type", ""))
 
-// updateReferenceIds 更新幻灯片XML中的rId引用
-func (s *Slide) updateReferenceIds(oldRId, newRId string) {
-	// 查找所有包含 r:id 属性的元素
-	for _, elem := range s.xml.FindElements("//*[@r:id]") {
-		if id := elem.SelectAttrValue("r:id", ""); id == oldRId {
-			elem.RemoveAttr("r:id")
-			elem.CreateAttr("r:id", newRId)
+	// 获取占位符名称
+	if nvPr := ph.Parent(); nvPr != nil {
+		if cNvPr := nvPr.Parent().SelectElement("cNvPr"); cNvPr != nil {
+			placeholder.Name = cNvPr.SelectAttrValue("name", "")
 		}
 	}
 
-	// 查找所有包含 r:embed 属性的元素
-	for _, elem := range s.xml.FindElements("//*[@r:embed]") {
-		if id := elem.SelectAttrValue("r:embed", ""); id == oldRId {
-			elem.RemoveAttr("r:embed")
-			elem.CreateAttr("r:embed", newRId)
-		}
+	// 获取占位符索引
+	if idxAttr := ph.SelectAttr("idx"); idxAttr != nil {
+		placeholder.Index, _ = strconv.Atoi(idxAttr.Value)
 	}
 
-	// 查找所有包含 r:link 属性的元素
-	for _, elem := range s.xml.FindElements("//*[@r:link]") {
-		if id := elem.SelectAttrValue("r:link", ""); id == oldRId {
-			elem.RemoveAttr("r:link")
-			elem.CreateAttr("r:link", newRId)
-		}
-	}
+	return placeholder
 }
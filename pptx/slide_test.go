@@ -0,0 +1,126 @@
+package pptx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+// newTestSlide 创建只包含给定形状的幻灯片，不属于任何演示文稿
+func newTestSlide(t *testing.T, shapes ...string) *Slide {
+	t.Helper()
+	doc := etree.NewDocument()
+	err := doc.ReadFromString(`<p:sld xmlns:a="` + NsDrawingML + `" xmlns:p="` + NsPresentationML +
+		`" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:cSld><p:spTree>` +
+		strings.Join(shapes, "") + `</p:spTree></p:cSld></p:sld>`)
+	if err != nil {
+		t.Fatalf("failed to parse slide: %v", err)
+	}
+	return &Slide{xml: doc, path: "ppt/slides/slide1.xml", relsPath: "ppt/slides/_rels/slide1.xml.rels", rels: NewRelationships()}
+}
+
+// testShape 生成一个占位符形状，每个段落中的文本为一个 a:r
+func testShape(id int, name, phType string, idx int, paragraphs ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr/><p:nvPr><p:ph`, id, name)
+	if phType != "" {
+		fmt.Fprintf(&b, ` type="%s"`, phType)
+	}
+	fmt.Fprintf(&b, ` idx="%d"/></p:nvPr></p:nvSpPr><p:spPr/><p:txBody><a:bodyPr/>`, idx)
+	for _, para := range paragraphs {
+		b.WriteString("<a:p>" + para + "</a:p>")
+	}
+	b.WriteString("</p:txBody></p:sp>")
+	return b.String()
+}
+
+// testRun 生成一个文本运行
+func testRun(text string) string {
+	return "<a:r><a:t>" + text + "</a:t></a:r>"
+}
+
+func TestGetPlaceholder(t *testing.T) {
+	slide := newTestSlide(t,
+		testShape(2, "Title 1", "title", 0, testRun("Hello")),
+		testShape(3, "Content 2", "", 1, testRun("first"), testRun("second")),
+		testShape(4, "Content 3", "body", 2, testRun("area ")+`<a14:m xmlns:a14="http://schemas.microsoft.com/office/drawing/2010/main">`+
+			`<m:oMath xmlns:m="`+NsMath+`"><m:r><m:t>x</m:t></m:r></m:oMath></a14:m>`),
+	)
+
+	tests := []struct {
+		name    string
+		params  []interface{}
+		wantIdx int
+	}{
+		{"by type", []interface{}{PlaceholderTitle}, 0},
+		{"by index", []interface{}{2}, 2},
+		{"by name", []interface{}{"Content 2"}, 1},
+		{"paragraphs joined without newline", []interface{}{"firstsecond"}, 1},
+		{"equation not part of the text", []interface{}{"area "}, 2},
+		{"type and text", []interface{}{struct {
+			Type PlaceholderType
+			Text string
+		}{PlaceholderObject, "firstsecond"}}, 1},
+		{"first shape in tree order", []interface{}{"missing", PlaceholderBody, PlaceholderTitle}, 0},
+		{"selector", []interface{}{ByTextPattern(regexp.MustCompile(`^Hel`))}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := slide.GetPlaceholder(tt.params...)
+			if err != nil {
+				t.Fatalf("GetPlaceholder(%v) error: %v", tt.params, err)
+			}
+			if got.Index != tt.wantIdx {
+				t.Errorf("GetPlaceholder(%v) = idx %d, want %d", tt.params, got.Index, tt.wantIdx)
+			}
+		})
+	}
+
+	_, err := slide.GetPlaceholder("first\nsecond")
+	if !errors.Is(err, ErrPlaceholderNotFound) {
+		t.Errorf("GetPlaceholder with Text() form = %v, want ErrPlaceholderNotFound", err)
+	}
+	if _, err := slide.GetPlaceholder(1.5); err == nil {
+		t.Error("GetPlaceholder with unsupported parameter type returned no error")
+	}
+}
+
+func TestFindPlaceholders(t *testing.T) {
+	slide := newTestSlide(t,
+		testShape(2, "Title 1", "title", 0, testRun("Hello")),
+		testShape(3, "Content 2", "body", 1, testRun("first"), testRun("second")),
+		testShape(4, "Content 3", "body", 2),
+	)
+
+	tests := []struct {
+		name string
+		sel  Selector
+		want []int
+	}{
+		{"all", nil, []int{0, 1, 2}},
+		{"by type", ByType(PlaceholderBody), []int{1, 2}},
+		{"by text", ByText("first\nsecond"), []int{1}},
+		{"and", And(ByType(PlaceholderBody), ByName("Content 3")), []int{2}},
+		{"or", Or(ByIdx(0), ByIdx(2)), []int{0, 2}},
+		{"none", ByName("missing"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placeholders, err := slide.FindPlaceholders(tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, p := range placeholders {
+				got = append(got, p.Index)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("FindPlaceholders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
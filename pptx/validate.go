@@ -0,0 +1,315 @@
+package pptx
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// IssueKind 定义校验问题的类别
+type IssueKind string
+
+const (
+	IssueMissingTarget       IssueKind = "missing-target"       // 关系指向的部件不存在
+	IssueMissingContentType  IssueKind = "missing-content-type" // 部件没有内容类型
+	IssueDanglingOverride    IssueKind = "dangling-override"    // Override 声明的部件不存在
+	IssueMissingRelationship IssueKind = "missing-relationship" // XML 引用的关系ID不存在
+	IssueDuplicateShapeId    IssueKind = "duplicate-shape-id"   // 同一幻灯片中 cNvPr id 重复
+	IssueInvalidSlideId      IssueKind = "invalid-slide-id"     // p:sldId 的 id 超出范围
+	IssueDuplicateSlideId    IssueKind = "duplicate-slide-id"   // p:sldId 的 id 重复
+	IssueMissingElement      IssueKind = "missing-element"      // 缺少必需的元素
+	IssueMalformedPart       IssueKind = "malformed-part"       // 部件内容无法解析
+)
+
+const (
+	// p:sldId 的 id 取值范围
+	minSlideId = 256
+	maxSlideId = 2147483647
+)
+
+// Issue 表示一个校验问题
+type Issue struct {
+	Kind    IssueKind
+	Part    string // 出现问题的部件路径
+	Message string
+}
+
+// String 返回问题描述
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Part, i.Kind, i.Message)
+}
+
+// ValidationError 表示严格模式下保存前校验失败
+type ValidationError struct {
+	Issues []Issue
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return "validation failed: " + e.Issues[0].String()
+	}
+	return fmt.Sprintf("validation failed with %d issues, first: %s", len(e.Issues), e.Issues[0].String())
+}
+
+// requiredElements 各类部件必须包含的元素
+var requiredElements = map[string][]string{
+	ContentTypeSlide:       {"p:cSld", "p:cSld/p:spTree", "p:cSld/p:spTree/p:nvGrpSpPr", "p:cSld/p:spTree/p:grpSpPr"},
+	ContentTypeSlideLayout: {"p:cSld", "p:cSld/p:spTree", "p:cSld/p:spTree/p:nvGrpSpPr", "p:cSld/p:spTree/p:grpSpPr"},
+	ContentTypeSlideMaster: {"p:cSld", "p:cSld/p:spTree", "p:cSld/p:spTree/p:nvGrpSpPr", "p:cSld/p:spTree/p:grpSpPr", "p:clrMap"},
+}
+
+// requiredShapeElements 形状必须包含的子元素
+var requiredShapeElements = map[string][]string{
+	"sp":           {"nvSpPr", "spPr"},
+	"pic":          {"nvPicPr", "blipFill", "spPr"},
+	"graphicFrame": {"nvGraphicFramePr", "xfrm", "graphic"},
+	"grpSp":        {"nvGrpSpPr", "grpSpPr"},
+	"cxnSp":        {"nvCxnSpPr", "spPr"},
+}
+
+// Validate 检查演示文稿中可能导致 PowerPoint 报告内容错误的问题
+// 校验的是包含内存中修改的快照，不会修改包，返回的问题按部件顺序排列
+func (p *Presentation) Validate() []Issue {
+	var issues []Issue
+	snapshot, err := p.snapshot()
+	if err != nil {
+		return append(issues, Issue{Kind: IssueMalformedPart, Message: err.Error()})
+	}
+
+	ct := p.pkg.ContentTypes()
+	for _, entry := range ct.overrides {
+		if !snapshot.exists(entry.key) {
+			issues = append(issues, Issue{IssueDanglingOverride, entry.key, "override declared for missing part"})
+		}
+	}
+
+	for _, name := range snapshot.names {
+		contentType := ct.ContentType(name)
+		if contentType == "" {
+			issues = append(issues, Issue{IssueMissingContentType, name, "part has no content type"})
+		}
+
+		// 关系文件：检查每个内部关系的目标是否存在
+		if path.Base(path.Dir(name)) == "_rels" && strings.HasSuffix(name, ".rels") {
+			issues = append(issues, snapshot.validateRelationships(name)...)
+			continue
+		}
+
+		if !isXMLContentType(contentType) {
+			continue
+		}
+
+		data, err := snapshot.read(name)
+		if err != nil {
+			issues = append(issues, Issue{IssueMalformedPart, name, err.Error()})
+			continue
+		}
+		doc := etree.NewDocument()
+		if err := doc.ReadFromBytes(data); err != nil {
+			issues = append(issues, Issue{IssueMalformedPart, name, err.Error()})
+			continue
+		}
+		issues = append(issues, snapshot.validateReferences(name, doc)...)
+		issues = append(issues, validateRequiredElements(name, contentType, doc)...)
+		if contentType == ContentTypeSlide {
+			issues = append(issues, validateShapeIds(name, doc)...)
+		}
+		if name == presentationPath {
+			issues = append(issues, validateSlideIds(doc)...)
+		}
+	}
+	return issues
+}
+
+// packageSnapshot 包与内存中尚未写入的修改合并后的只读视图
+type packageSnapshot struct {
+	pkg     *Package
+	pending map[string][]byte // 尚未写入包的部件内容
+	names   []string          // 所有部件名，包中的部件在前，新部件按生成顺序排在后面
+}
+
+// snapshot 创建包含内存中修改的快照，不修改包和修改标记
+func (p *Presentation) snapshot() (*packageSnapshot, error) {
+	pending, err := p.pendingParts()
+	if err != nil {
+		return nil, err
+	}
+	s := &packageSnapshot{pkg: p.pkg, pending: make(map[string][]byte, len(pending))}
+	for _, part := range p.pkg.Parts() {
+		s.names = append(s.names, part.Name())
+	}
+	for _, part := range pending {
+		if !s.exists(part.name) {
+			s.names = append(s.names, part.name)
+		}
+		s.pending[part.name] = part.data
+	}
+	return s, nil
+}
+
+// exists 判断部件是否存在
+func (s *packageSnapshot) exists(name string) bool {
+	_, ok := s.pending[name]
+	return ok || s.pkg.Part(name) != nil
+}
+
+// read 读取部件内容
+func (s *packageSnapshot) read(name string) ([]byte, error) {
+	if data, ok := s.pending[name]; ok {
+		return data, nil
+	}
+	return s.pkg.readPart(name)
+}
+
+// relationships 读取并解析关系文件，关系文件不存在时返回空的关系集合
+func (s *packageSnapshot) relationships(relsPath string) (*Relationships, error) {
+	if !s.exists(relsPath) {
+		return NewRelationships(), nil
+	}
+	data, err := s.read(relsPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseRelationships(data)
+}
+
+// validateRelationships 检查关系文件中的内部关系目标是否存在
+func (s *packageSnapshot) validateRelationships(relsPath string) []Issue {
+	rels, err := s.relationships(relsPath)
+	if err != nil {
+		return []Issue{{IssueMalformedPart, relsPath, err.Error()}}
+	}
+
+	source := sourcePartFor(relsPath)
+	var issues []Issue
+	for _, rel := range rels.All() {
+		if rel.TargetMode == "External" {
+			continue
+		}
+		target := resolvePartPath(source, rel.Target)
+		if !s.exists(target) {
+			issues = append(issues, Issue{IssueMissingTarget, relsPath,
+				fmt.Sprintf("relationship %s targets missing part %s", rel.Id, target)})
+		}
+	}
+	return issues
+}
+
+// validateReferences 检查 XML 中 r:id、r:embed、r:link 等关系引用是否存在，关系命名空间按 URI 识别
+func (s *packageSnapshot) validateReferences(name string, doc *etree.Document) []Issue {
+	rels, err := s.relationships(relsPathFor(name))
+	if err != nil {
+		// 关系文件本身的问题由 validateRelationships 报告
+		rels = NewRelationships()
+	}
+
+	var issues []Issue
+	for _, el := range doc.FindElements("//*") {
+		for i := range el.Attr {
+			attr := &el.Attr[i]
+			if !isRelationshipAttr(attr) {
+				continue
+			}
+			// 命名跳转等动作使用空的 r:id
+			if attr.Value == "" || rels.Get(attr.Value) != nil {
+				continue
+			}
+			issues = append(issues, Issue{IssueMissingRelationship, name,
+				fmt.Sprintf("%s references missing relationship %s=%q", el.FullTag(), attr.FullKey(), attr.Value)})
+		}
+	}
+	return issues
+}
+
+// validateRequiredElements 检查部件和其中的形状是否包含必需的元素
+func validateRequiredElements(name, contentType string, doc *etree.Document) []Issue {
+	root := doc.Root()
+	if root == nil {
+		return []Issue{{IssueMissingElement, name, "document has no root element"}}
+	}
+
+	var issues []Issue
+	for _, required := range requiredElements[contentType] {
+		if root.FindElement(required) == nil {
+			issues = append(issues, Issue{IssueMissingElement, name, "missing required element " + required})
+		}
+	}
+	if name == presentationPath {
+		for _, required := range []string{"p:sldMasterIdLst", "p:notesSz"} {
+			if root.SelectElement(required) == nil {
+				issues = append(issues, Issue{IssueMissingElement, name, "missing required element " + required})
+			}
+		}
+	}
+
+	for _, spTree := range doc.FindElements("//p:spTree") {
+		for _, shape := range spTree.FindElements(".//*") {
+			for _, child := range requiredShapeElements[shape.Tag] {
+				if shape.Space == "p" && shape.SelectElement(child) == nil {
+					issues = append(issues, Issue{IssueMissingElement, name,
+						fmt.Sprintf("%s is missing required element %s", shape.FullTag(), child)})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// validateShapeIds 检查幻灯片中 cNvPr 的 id 是否唯一
+func validateShapeIds(name string, doc *etree.Document) []Issue {
+	var issues []Issue
+	seen := make(map[string]bool)
+	for _, cNvPr := range doc.FindElements("//cNvPr") {
+		id := cNvPr.SelectAttrValue("id", "")
+		if seen[id] {
+			issues = append(issues, Issue{IssueDuplicateShapeId, name, "duplicate shape id " + id})
+		}
+		seen[id] = true
+	}
+	return issues
+}
+
+// validateSlideIds 检查 p:sldId 的 id 是否在有效范围内且唯一
+func validateSlideIds(doc *etree.Document) []Issue {
+	var issues []Issue
+	seen := make(map[int64]bool)
+	for _, sldId := range doc.FindElements("//p:sldIdLst/p:sldId") {
+		value := sldId.SelectAttrValue("id", "")
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			issues = append(issues, Issue{IssueInvalidSlideId, presentationPath, fmt.Sprintf("slide id %q is not an integer", value)})
+			continue
+		}
+		if id < minSlideId || id > maxSlideId {
+			issues = append(issues, Issue{IssueInvalidSlideId, presentationPath,
+				fmt.Sprintf("slide id %q is out of range [%d, %d]", value, minSlideId, maxSlideId)})
+		}
+		if seen[id] {
+			issues = append(issues, Issue{IssueDuplicateSlideId, presentationPath, "duplicate slide id " + value})
+		}
+		seen[id] = true
+	}
+	return issues
+}
+
+// sourcePartFor 获取关系文件所属的部件路径，包级关系返回空字符串
+func sourcePartFor(relsPath string) string {
+	if relsPath == rootRelsPath {
+		return ""
+	}
+	dir := path.Dir(path.Dir(relsPath))
+	base := strings.TrimSuffix(path.Base(relsPath), ".rels")
+	if dir == "." {
+		return base
+	}
+	return path.Join(dir, base)
+}
+
+// isXMLContentType 判断内容类型是否为 XML
+func isXMLContentType(contentType string) bool {
+	return contentType == ContentTypeXML || strings.HasSuffix(contentType, "+xml")
+}
@@ -0,0 +1,101 @@
+package pptx
+
+import (
+	"testing"
+
+	"github.com/beevik/etree"
+)
+
+func TestValidateDoesNotModifyPackage(t *testing.T) {
+	pres := openTemplate(t)
+	slide, err := pres.AddSlide("仅标题")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := slide.GetPlaceholder(PlaceholderTitle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := title.SetText("Hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if issues := pres.Validate(); len(issues) != 0 {
+		t.Fatalf("Validate() = %v, want no issues", issues)
+	}
+	if !slide.dirty || !slide.rels.dirty || !pres.dirty {
+		t.Error("Validate cleared the modification flags")
+	}
+	if data, _ := pres.pkg.readPart(slide.path); len(data) != 0 || pres.pkg.Part(slide.relsPath) != nil {
+		t.Error("Validate wrote the new slide into the package")
+	}
+	if part := pres.pkg.Part(presentationPath); part.modified {
+		t.Error("Validate re-serialised presentation.xml")
+	}
+}
+
+func TestValidateReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want int
+	}{
+		{"existing relationship", `<p:pic xmlns:p="` + NsPresentationML + `" xmlns:r="` + NsOfficeRels + `"><a:blip xmlns:a="` + NsDrawingML + `" r:embed="rId1"/></p:pic>`, 0},
+		{"missing r:embed", `<p:pic xmlns:p="` + NsPresentationML + `" xmlns:r="` + NsOfficeRels + `"><a:blip xmlns:a="` + NsDrawingML + `" r:embed="rId9"/></p:pic>`, 1},
+		{"other prefix", `<p:pic xmlns:p="` + NsPresentationML + `" xmlns:rel="` + NsOfficeRels + `"><a:blip xmlns:a="` + NsDrawingML + `" rel:embed="rId9"/></p:pic>`, 1},
+		{"r prefix bound to another namespace", `<p:pic xmlns:p="` + NsPresentationML + `" xmlns:r="urn:other"><a:blip xmlns:a="` + NsDrawingML + `" r:embed="rId9"/></p:pic>`, 0},
+		{"named show jump", `<p:sp xmlns:p="` + NsPresentationML + `" xmlns:r="` + NsOfficeRels + `"><a:hlinkClick xmlns:a="` + NsDrawingML + `" r:id="" action="ppaction://hlinkshowjump?jump=nextslide"/></p:sp>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := NewPackage()
+			pkg.SetPart("ppt/slides/_rels/slide1.xml.rels", testRels("../media/image1.png"))
+			snapshot := &packageSnapshot{pkg: pkg}
+
+			doc := etree.NewDocument()
+			if err := doc.ReadFromString(tt.xml); err != nil {
+				t.Fatal(err)
+			}
+			issues := snapshot.validateReferences("ppt/slides/slide1.xml", doc)
+			if len(issues) != tt.want {
+				t.Errorf("validateReferences() = %v, want %d issues", issues, tt.want)
+			}
+			for _, issue := range issues {
+				if issue.Kind != IssueMissingRelationship {
+					t.Errorf("issue kind = %s, want %s", issue.Kind, IssueMissingRelationship)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSlideIds(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want []IssueKind
+	}{
+		{"valid", []string{"256", "257"}, nil},
+		{"out of range", []string{"255"}, []IssueKind{IssueInvalidSlideId}},
+		{"not an integer", []string{"256x"}, []IssueKind{IssueInvalidSlideId}},
+		{"empty", []string{""}, []IssueKind{IssueInvalidSlideId}},
+		{"duplicate", []string{"256", "256"}, []IssueKind{IssueDuplicateSlideId}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := etree.NewDocument()
+			sldIdLst := doc.CreateElement("p:presentation").CreateElement("p:sldIdLst")
+			for _, id := range tt.ids {
+				sldIdLst.CreateElement("p:sldId").CreateAttr("id", id)
+			}
+			issues := validateSlideIds(doc)
+			var kinds []IssueKind
+			for _, issue := range issues {
+				kinds = append(kinds, issue.Kind)
+			}
+			if len(kinds) != len(tt.want) || len(kinds) > 0 && kinds[0] != tt.want[0] {
+				t.Errorf("validateSlideIds(%q) = %v, want %v", tt.ids, issues, tt.want)
+			}
+		})
+	}
+}